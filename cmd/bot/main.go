@@ -10,7 +10,7 @@ import (
 	"github.com/crocxdued/currency-telegram-bot/internal/app"
 	"github.com/crocxdued/currency-telegram-bot/internal/config"
 	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
-	_ "github.com/lib/pq"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
 )
 
@@ -22,9 +22,15 @@ func main() {
 		log.Fatalf("Config error: %v", err)
 	}
 
-	if err := logger.InitGlobal(cfg.LogLevel); err != nil {
+	secrets := []string{cfg.BotToken, cfg.LogLevelToken}
+	for _, provider := range cfg.Providers {
+		secrets = append(secrets, provider.APIKey)
+	}
+
+	if err := logger.InitGlobal(cfg.LogLevel, logger.WithSecrets(secrets...)); err != nil {
 		log.Fatalf("Logger error: %v", err)
 	}
+	logger.SetRedactUserText(cfg.LogRedactUserText)
 
 	logger.S.Info("Checking and running migrations...")
 	if err := runMigrations(cfg); err != nil {
@@ -42,7 +48,7 @@ func main() {
 }
 
 func runMigrations(cfg *config.Config) error {
-	db, err := sql.Open("postgres", cfg.DBURL)
+	db, err := sql.Open("pgx", cfg.DBURL)
 	if err != nil {
 		return fmt.Errorf("failed to open database for migrations: %w", err)
 	}
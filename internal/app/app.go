@@ -3,25 +3,30 @@ package app
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/crocxdued/currency-telegram-bot/internal/config"
 	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
-	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/exchanger/cbr"
-	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/exchanger/exchangeratehost"
 	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/handlers"
-	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
 	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/postgres"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/rediscache"
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	"github.com/crocxdued/currency-telegram-bot/internal/tracing"
 	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"github.com/jmoiron/sqlx"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 )
 
 type App struct {
 	config *config.Config
-	db     *sqlx.DB
+	db     *pgxpool.Pool
 	bot    *tgbotapi.BotAPI
+	redis  *redis.Client
+
+	providers *services.ProviderChain
 }
 
 func New(cfg *config.Config) *App {
@@ -31,16 +36,34 @@ func New(cfg *config.Config) *App {
 }
 
 func (a *App) initDB(ctx context.Context) error {
-	db, err := sqlx.ConnectContext(ctx, "postgres", a.config.DBURL)
+	poolConfig, err := pgxpool.ParseConfig(a.config.DBURL)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return fmt.Errorf("invalid database URL: %w", err)
 	}
 
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	db := a.config.DB
+	poolConfig.MaxConns = db.MaxConns
+	poolConfig.MinConns = db.MinConns
+	poolConfig.MaxConnLifetime = db.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = db.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = db.HealthCheckPeriod
+	poolConfig.ConnConfig.ConnectTimeout = db.ConnectTimeout
+	if db.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(db.StatementTimeout.Milliseconds(), 10)
+	}
+	poolConfig.AfterConnect = postgres.PrepareStatements
 
-	a.db = db
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	// Пул подключается лениво — проверяем соединение сразу, как раньше при старте
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	a.db = pool
 	logger.S.Info("Database connection established")
 	return nil
 }
@@ -54,34 +77,126 @@ func (a *App) initBot() error {
 	bot.Debug = false
 	a.bot = bot
 
+	// Ошибки библиотеки содержат URL с токеном — пишем их через zap с маскированием
+	_ = tgbotapi.SetLogger(logger.NewPrintfLogger("telegram"))
+
 	logger.S.Infof("Authorized on account %s", bot.Self.UserName)
 	return nil
 }
 
-func (a *App) initServices() (*handlers.BotHandler, error) {
+func (a *App) initServices(ctx context.Context) (*handlers.BotHandler, error) {
 
-	ratesCache := cache.NewRatesCache(a.config.CacheTTLMinutes)
+	ratesStore, err := a.initRatesStore(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	providers := []services.ExchangeProvider{
-		exchangeratehost.New(),
-		cbr.New(),
+	chain, err := buildProviderChain(a.config.Providers)
+	if err != nil {
+		return nil, err
 	}
+	a.providers = chain
+
+	exchangeService := services.NewRoutedExchangeService(chain, ratesStore)
 
-	exchangeService := services.NewExchangeService(providers, ratesCache)
+	go exchangeService.RunRefresher(ctx, a.config.CacheRefreshInterval, a.config.CacheRefreshTopPairs)
 
 	favoritesRepo := postgres.NewFavoritesRepository(a.db)
 
-	botHandler := handlers.NewBotHandler(a.bot, exchangeService, favoritesRepo)
+	conversionsRepo := postgres.NewConversionsRepository(a.db)
+	if a.config.HistoryRetention > 0 {
+		go services.RunConversionRetention(ctx, conversionsRepo, a.config.HistoryRetention, time.Hour)
+	}
+
+	usersRepo := postgres.NewUsersRepository(a.db)
+	broadcastRepo := postgres.NewBroadcastRepository(a.db)
+
+	sender := telegram.NewSender(a.bot,
+		telegram.WithGlobalRate(a.config.Sender.GlobalRate),
+		telegram.WithChatRate(a.config.Sender.ChatRate, a.config.Sender.GroupRate),
+		telegram.WithRetries(a.config.Sender.MaxRetries, time.Second),
+		telegram.WithRetryHook(func(method, reason string) {
+			metrics.TelegramRetriesTotal.WithLabelValues(method, reason).Inc()
+		}),
+	)
+	go sender.Run(ctx)
+
+	// Блокировка хранилища курсов общая для реплик при CACHE_BACKEND=redis
+	broadcaster := handlers.NewBroadcaster(sender, usersRepo, broadcastRepo, ratesStore)
+	go broadcaster.Run(ctx)
+
+	var throttler *services.Throttler
+	if a.config.Throttle.Rate > 0 {
+		throttler = services.NewThrottler(services.ThrottleConfig{
+			Rate:            a.config.Throttle.Rate,
+			Burst:           a.config.Throttle.Burst,
+			MaxViolations:   a.config.Throttle.MaxViolations,
+			ViolationWindow: a.config.Throttle.ViolationWindow,
+			BanDuration:     a.config.Throttle.BanDuration,
+		}, postgres.NewBanRepository(a.db))
+		go throttler.Run(ctx, time.Minute)
+	}
+
+	cacheAdmin, _ := ratesStore.(services.CacheAdmin)
+	adminDeps := handlers.AdminDeps{
+		Stats:       postgres.NewStatsRepository(a.db),
+		Audit:       postgres.NewAuditRepository(a.db),
+		Cache:       cacheAdmin,
+		Providers:   chain.All(),
+		Broadcasts:  broadcastRepo,
+		Broadcaster: broadcaster,
+	}
+
+	// Длинные данные кнопок хранятся там же, где кэш курсов, чтобы их понимала любая реплика
+	var callbackOpts []telegram.CallbackOption
+	if a.redis != nil {
+		callbackOpts = append(callbackOpts,
+			telegram.WithPayloadStore(rediscache.NewPayloadStore(a.redis, a.config.RedisKeyPrefix), 0))
+	}
+	callbacks := telegram.NewCallbackCodec([]byte(a.config.CallbackSecret), callbackOpts...)
+
+	botHandler := handlers.NewBotHandler(a.bot, exchangeService, favoritesRepo,
+		handlers.WithSender(sender),
+		handlers.WithCallbackCodec(callbacks),
+		handlers.WithChatSettings(postgres.NewChatSettingsRepository(a.db)),
+		handlers.WithExpenses(postgres.NewExpensesRepository(a.db)),
+		handlers.WithDebts(postgres.NewDebtsRepository(a.db)),
+		handlers.WithHoldings(postgres.NewHoldingsRepository(a.db)),
+		handlers.WithConversions(conversionsRepo, a.config.HistoryRetention),
+		handlers.WithUserData(services.NewUserDataRegistry(postgres.UserDataSources(a.db)...)),
+		handlers.WithUsers(usersRepo),
+		handlers.WithThrottler(throttler),
+		handlers.WithAdmin(a.config.AdminIDs, adminDeps),
+	)
 
 	return botHandler, nil
 }
 
 // Run запускает приложение
 func (a *App) Run() error {
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	logger.S.Info("Starting application...")
 
+	shutdownTracing, err := tracing.Init(ctx, tracing.Config{
+		Exporter:    a.config.Tracing.Exporter,
+		Endpoint:    a.config.Tracing.OTLPEndpoint,
+		Insecure:    a.config.Tracing.OTLPInsecure,
+		SampleRatio: a.config.Tracing.SampleRatio,
+		ServiceName: a.config.Tracing.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("tracing initialization failed: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.S.Warnf("Failed to flush traces: %v", err)
+		}
+	}()
+
 	if err := a.initDB(ctx); err != nil {
 		return fmt.Errorf("database initialization failed: %w", err)
 	}
@@ -91,11 +206,16 @@ func (a *App) Run() error {
 		return fmt.Errorf("bot initialization failed: %w", err)
 	}
 
-	botHandler, err := a.initServices()
+	botHandler, err := a.initServices(ctx)
+	if a.redis != nil {
+		defer a.redis.Close()
+	}
 	if err != nil {
 		return fmt.Errorf("services initialization failed: %w", err)
 	}
 
+	a.startHTTPServer(ctx, a.newHealthChecker())
+
 	// Настраиваем webhook
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -104,8 +224,14 @@ func (a *App) Run() error {
 
 	logger.S.Info("Bot is now running. Press Ctrl+C to exit.")
 
+	// Ответ одному чату может ждать лимита Telegram, поэтому чаты обрабатываются параллельно
+	dispatcher := telegram.NewDispatcher(botHandler.HandleUpdate)
+	defer dispatcher.Wait()
+
 	for update := range updates {
-		botHandler.HandleUpdate(update)
+		if !dispatcher.Dispatch(ctx, update) {
+			logger.S.Warnf("Dropped update %d: chat queue is full", update.UpdateID)
+		}
 	}
 
 	return nil
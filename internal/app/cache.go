@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/config"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/rediscache"
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// initRatesStore создает хранилище курсов согласно CACHE_BACKEND
+func (a *App) initRatesStore(ctx context.Context) (services.RatesStore, error) {
+	maxStale := time.Duration(a.config.CacheMaxStaleMinutes) * time.Minute
+	ttlPolicy := services.NewCacheTTLPolicy(
+		time.Duration(a.config.CacheTTLMinutes)*time.Minute,
+		a.config.CacheProviderTTL,
+		a.config.CacheCurrencyTTL,
+	)
+
+	switch a.config.CacheBackend {
+	case config.CacheBackendRedis:
+		opts, err := redis.ParseURL(a.config.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+		}
+
+		client := redis.NewClient(opts)
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		a.redis = client
+
+		store := rediscache.NewRatesStore(
+			client,
+			a.config.CacheTTLMinutes,
+			rediscache.WithKeyPrefix(a.config.RedisKeyPrefix),
+			rediscache.WithMaxStale(maxStale),
+			rediscache.WithTTLPolicy(ttlPolicy),
+		)
+		if err := metrics.RegisterCache(store.Stats); err != nil {
+			return nil, fmt.Errorf("failed to register cache metrics: %w", err)
+		}
+
+		logger.S.Info("Using redis rates cache")
+		return store, nil
+	default:
+		ratesCache := cache.NewRatesCache(
+			a.config.CacheTTLMinutes,
+			cache.WithMaxStale(maxStale),
+			cache.WithCapacity(a.config.CacheMaxEntries),
+			cache.WithTTLPolicy(ttlPolicy),
+		)
+		go runCacheCleanup(ctx, ratesCache, a.config.CacheRefreshInterval)
+
+		if err := metrics.RegisterCache(ratesCache.Stats); err != nil {
+			return nil, fmt.Errorf("failed to register cache metrics: %w", err)
+		}
+
+		logger.S.Info("Using in-memory rates cache")
+		return ratesCache, nil
+	}
+}
+
+// runCacheCleanup периодически удаляет записи старше допустимого возраста
+func runCacheCleanup(ctx context.Context, ratesCache *cache.RatesCache, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ratesCache.Cleanup()
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/health"
+)
+
+// newHealthChecker регистрирует проверки готовности бота
+func (a *App) newHealthChecker() *health.Checker {
+	checker := health.NewChecker(a.config.HealthCacheTTL, a.config.HealthCheckTimeout)
+
+	checker.Add("database", func(ctx context.Context) error {
+		return a.db.Ping(ctx)
+	})
+
+	checker.Add("telegram", func(ctx context.Context) error {
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := a.bot.GetMe()
+			errCh <- err
+		}()
+
+		select {
+		case err := <-errCh:
+			return err
+		case <-ctx.Done():
+			return fmt.Errorf("getMe: %w", ctx.Err())
+		}
+	})
+
+	checker.Add("providers", func(ctx context.Context) error {
+		return checkProviders(ctx, a.providers.All())
+	})
+
+	return checker
+}
+
+// checkProviders проходит, если хотя бы один провайдер вернул курс по канареечной паре
+func checkProviders(ctx context.Context, providers []services.ExchangeProvider) error {
+	var failures []string
+
+	for _, status := range services.ProbeProviders(ctx, providers) {
+		if status.Healthy() {
+			return nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", status.Name, status.Err))
+	}
+
+	if len(failures) == 0 {
+		return errors.New("no providers configured")
+	}
+	return errors.New(strings.Join(failures, "; "))
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/health"
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+)
+
+// startHTTPServer запускает служебный HTTP-сервер и останавливает его при отмене контекста
+func (a *App) startHTTPServer(ctx context.Context, checker *health.Checker) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", checker.ReadinessHandler())
+	mux.Handle("/loglevel", logger.LevelHandler(a.config.LogLevelToken))
+
+	server := &http.Server{
+		Addr:              a.config.HTTPAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		logger.S.Infof("HTTP server listening on %s", a.config.HTTPAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.S.Errorf("HTTP server failed: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/config"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/exchanger/cbr"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/exchanger/exchangeratehost"
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+)
+
+// buildProviderChain собирает цепочку включенных провайдеров из конфигурации
+func buildProviderChain(cfgs []config.ProviderConfig) (*services.ProviderChain, error) {
+	var routes []services.ProviderRoute
+
+	for _, cfg := range cfgs {
+		if !cfg.Enabled {
+			continue
+		}
+
+		provider, err := newProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		routes = append(routes, services.ProviderRoute{
+			Provider:   metrics.InstrumentProvider(provider),
+			Priority:   cfg.Priority,
+			Currencies: cfg.Currencies,
+		})
+	}
+
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no exchange providers enabled")
+	}
+
+	return services.NewProviderChain(routes), nil
+}
+
+func newProvider(cfg config.ProviderConfig) (services.ExchangeProvider, error) {
+	switch cfg.Name {
+	case config.ProviderFrankfurter:
+		return exchangeratehost.New(
+			exchangeratehost.WithBaseURL(cfg.BaseURL),
+			exchangeratehost.WithTimeout(cfg.Timeout),
+			exchangeratehost.WithAPIKey(cfg.APIKey),
+		), nil
+	case config.ProviderCBR:
+		return cbr.New(
+			cbr.WithBaseURL(cfg.BaseURL),
+			cbr.WithTimeout(cfg.Timeout),
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown exchange provider %q", cfg.Name)
+	}
+}
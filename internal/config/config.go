@@ -2,16 +2,128 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// Поддерживаемые хранилища кэша курсов
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// Имена поддерживаемых провайдеров курсов
+const (
+	ProviderFrankfurter = "frankfurter"
+	ProviderCBR         = "cbr"
+)
+
 type Config struct {
-	BotToken        string `mapstructure:"BOT_TOKEN"`
-	DBURL           string
-	LogLevel        string `mapstructure:"LOG_LEVEL"`
-	CacheTTLMinutes int    `mapstructure:"CACHE_TTL_MINUTES"`
+	BotToken string `mapstructure:"BOT_TOKEN"`
+	DBURL    string
+	// DB настраивает пул соединений с Postgres
+	DB       DBConfig
+	LogLevel string `mapstructure:"LOG_LEVEL"`
+	// LogLevelToken — Bearer-токен для смены уровня логирования через /loglevel; пустой отключает /loglevel
+	LogLevelToken string `mapstructure:"LOG_LEVEL_TOKEN"`
+	// CallbackSecret — ключ подписи данных инлайн-кнопок, по умолчанию токен бота
+	CallbackSecret string `mapstructure:"CALLBACK_SECRET"`
+	// AdminIDs — Telegram ID пользователей, которым доступны команды администратора
+	AdminIDs []int64
+	// LogRedactUserText скрывает текст сообщений пользователей в логах
+	LogRedactUserText bool `mapstructure:"LOG_REDACT_USER_TEXT"`
+	CacheTTLMinutes   int  `mapstructure:"CACHE_TTL_MINUTES"`
+	// HTTPAddr — адрес служебного HTTP-сервера с метриками
+	HTTPAddr string `mapstructure:"HTTP_ADDR"`
+	// Tracing настраивает экспорт трасс OpenTelemetry
+	Tracing TracingConfig
+	// Sender ограничивает исходящие запросы к Bot API
+	Sender SenderConfig
+	// Throttle ограничивает входящие запросы одного пользователя
+	Throttle ThrottleConfig
+	// HealthCacheTTL — сколько переиспользуется результат /readyz
+	HealthCacheTTL time.Duration `mapstructure:"HEALTH_CACHE_TTL"`
+	// HealthCheckTimeout ограничивает каждую проверку готовности
+	HealthCheckTimeout time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT"`
+	// CacheMaxStaleMinutes — сколько истекший курс отдается как резервный при недоступности провайдеров
+	CacheMaxStaleMinutes int `mapstructure:"CACHE_MAX_STALE_MINUTES"`
+	// CacheRefreshInterval — период фонового обновления популярных пар
+	CacheRefreshInterval time.Duration `mapstructure:"CACHE_REFRESH_INTERVAL"`
+	// CacheRefreshTopPairs — сколько самых запрашиваемых пар обновлять фоном
+	CacheRefreshTopPairs int `mapstructure:"CACHE_REFRESH_TOP_PAIRS"`
+	// CacheMaxEntries ограничивает число пар в кэше в памяти
+	CacheMaxEntries int `mapstructure:"CACHE_MAX_ENTRIES"`
+	// CacheProviderTTL и CacheCurrencyTTL переопределяют срок жизни курсов,
+	// формат: "CBR=12h" и "BTC=1m,ETH=1m"
+	CacheProviderTTL map[string]time.Duration
+	CacheCurrencyTTL map[string]time.Duration
+	// CacheBackend — memory для одной реплики или redis для общего кэша нескольких реплик
+	CacheBackend   string `mapstructure:"CACHE_BACKEND"`
+	RedisURL       string `mapstructure:"REDIS_URL"`
+	RedisKeyPrefix string `mapstructure:"REDIS_KEY_PREFIX"`
+	Providers      []ProviderConfig
+	// HistoryRetention — сколько хранится история конвертаций пользователей; 0 — без ограничения
+	HistoryRetention time.Duration `mapstructure:"HISTORY_RETENTION"`
+}
+
+// TracingConfig описывает экспорт трасс: none, otlp (OTLP/HTTP) или stdout для локальной отладки
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string // host:port коллектора
+	OTLPInsecure bool
+	SampleRatio  float64
+	ServiceName  string
+}
+
+// DBConfig задает размер пула соединений с Postgres, время жизни соединений и таймауты
+type DBConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+	// StatementTimeout ограничивает выполнение одного запроса на стороне сервера; 0 — без ограничения
+	StatementTimeout time.Duration
+}
+
+// SenderConfig задает лимиты отправки в сообщениях в секунду и число повторов
+type SenderConfig struct {
+	GlobalRate float64
+	ChatRate   float64
+	GroupRate  float64
+	MaxRetries int
+}
+
+// ThrottleConfig задает лимиты входящих запросов пользователя; Rate 0 отключает ограничение
+type ThrottleConfig struct {
+	Rate            float64 // запросов в секунду
+	Burst           int
+	MaxViolations   int // превышений за ViolationWindow до временной блокировки
+	ViolationWindow time.Duration
+	BanDuration     time.Duration
+}
+
+// ProviderConfig описывает настройки одного источника курсов
+type ProviderConfig struct {
+	Name     string
+	Enabled  bool
+	Priority int
+	BaseURL  string // пустая строка — адрес по умолчанию из клиента
+	Timeout  time.Duration
+	APIKey   string
+	// Currencies — валюты, для пар с которыми провайдер опрашивается первым
+	Currencies []string
+}
+
+// providerDefaults задает порядок и настройки провайдеров по умолчанию
+var providerDefaults = []ProviderConfig{
+	{Name: ProviderFrankfurter, Enabled: true, Priority: 10, Timeout: 10 * time.Second},
+	{Name: ProviderCBR, Enabled: true, Priority: 20, Timeout: 10 * time.Second},
 }
 
 func Load() (*Config, error) {
@@ -20,14 +132,47 @@ func Load() (*Config, error) {
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
 	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_REDACT_USER_TEXT", true)
 	viper.SetDefault("CACHE_TTL_MINUTES", 5)
+	viper.SetDefault("HTTP_ADDR", ":8080")
+	viper.SetDefault("HEALTH_CACHE_TTL", 15*time.Second)
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	viper.SetDefault("TRACING_OTLP_INSECURE", true)
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1.0)
+	viper.SetDefault("TRACING_SERVICE_NAME", "currency-bot")
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT", 5*time.Second)
+	viper.SetDefault("TELEGRAM_SEND_GLOBAL_RATE", 30.0)
+	viper.SetDefault("TELEGRAM_SEND_CHAT_RATE", 1.0)
+	viper.SetDefault("TELEGRAM_SEND_GROUP_RATE", 20.0/60)
+	viper.SetDefault("TELEGRAM_SEND_MAX_RETRIES", 3)
+	viper.SetDefault("THROTTLE_RATE", 1.0)
+	viper.SetDefault("THROTTLE_BURST", 5)
+	viper.SetDefault("THROTTLE_MAX_VIOLATIONS", 20)
+	viper.SetDefault("THROTTLE_VIOLATION_WINDOW", time.Minute)
+	viper.SetDefault("THROTTLE_BAN_DURATION", time.Hour)
+	viper.SetDefault("CACHE_MAX_STALE_MINUTES", 24*60)
+	viper.SetDefault("CACHE_REFRESH_INTERVAL", time.Minute)
+	viper.SetDefault("CACHE_REFRESH_TOP_PAIRS", 20)
+	viper.SetDefault("CACHE_MAX_ENTRIES", 10000)
+	viper.SetDefault("CACHE_BACKEND", CacheBackendMemory)
+	viper.SetDefault("REDIS_KEY_PREFIX", "currency-bot:")
+	viper.SetDefault("HISTORY_RETENTION", 90*24*time.Hour)
 	viper.SetDefault("POSTGRES_PORT", "5432")
 	viper.SetDefault("POSTGRES_SSLMODE", "disable")
 	viper.SetDefault("POSTGRES_USER", "postgres")
 	viper.SetDefault("POSTGRES_PASSWORD", "postgres")
 	viper.SetDefault("POSTGRES_DB", "currency_bot")
+	viper.SetDefault("DB_MAX_CONNS", 25)
+	viper.SetDefault("DB_MIN_CONNS", 2)
+	viper.SetDefault("DB_MAX_CONN_LIFETIME", time.Hour)
+	viper.SetDefault("DB_MAX_CONN_IDLE_TIME", 30*time.Minute)
+	viper.SetDefault("DB_HEALTH_CHECK_PERIOD", time.Minute)
+	viper.SetDefault("DB_CONNECT_TIMEOUT", 5*time.Second)
+	viper.SetDefault("DB_STATEMENT_TIMEOUT", 30*time.Second)
 
 	var c Config
+	var err error
 
 	c.DBURL = fmt.Sprintf(
 		"postgres://%s:%s@%s:%s/%s?sslmode=%s",
@@ -38,14 +183,219 @@ func Load() (*Config, error) {
 		viper.GetString("POSTGRES_DB"),
 		viper.GetString("POSTGRES_SSLMODE"),
 	)
+	// Сертификаты для sslmode=verify-ca/verify-full и клиентской аутентификации по TLS
+	for _, param := range []string{"sslrootcert", "sslcert", "sslkey"} {
+		if value := viper.GetString("POSTGRES_" + strings.ToUpper(param)); value != "" {
+			c.DBURL += "&" + param + "=" + url.QueryEscape(value)
+		}
+	}
+	c.DB = DBConfig{
+		MaxConns:          viper.GetInt32("DB_MAX_CONNS"),
+		MinConns:          viper.GetInt32("DB_MIN_CONNS"),
+		MaxConnLifetime:   viper.GetDuration("DB_MAX_CONN_LIFETIME"),
+		MaxConnIdleTime:   viper.GetDuration("DB_MAX_CONN_IDLE_TIME"),
+		HealthCheckPeriod: viper.GetDuration("DB_HEALTH_CHECK_PERIOD"),
+		ConnectTimeout:    viper.GetDuration("DB_CONNECT_TIMEOUT"),
+		StatementTimeout:  viper.GetDuration("DB_STATEMENT_TIMEOUT"),
+	}
 
 	c.BotToken = viper.GetString("BOT_TOKEN")
+	c.CallbackSecret = viper.GetString("CALLBACK_SECRET")
 	c.LogLevel = viper.GetString("LOG_LEVEL")
+	c.LogLevelToken = viper.GetString("LOG_LEVEL_TOKEN")
+	c.LogRedactUserText = viper.GetBool("LOG_REDACT_USER_TEXT")
 	c.CacheTTLMinutes = viper.GetInt("CACHE_TTL_MINUTES")
+	c.HTTPAddr = viper.GetString("HTTP_ADDR")
+	c.HealthCacheTTL = viper.GetDuration("HEALTH_CACHE_TTL")
+	c.Tracing = TracingConfig{
+		Exporter:     strings.ToLower(viper.GetString("TRACING_EXPORTER")),
+		OTLPEndpoint: viper.GetString("TRACING_OTLP_ENDPOINT"),
+		OTLPInsecure: viper.GetBool("TRACING_OTLP_INSECURE"),
+		SampleRatio:  viper.GetFloat64("TRACING_SAMPLE_RATIO"),
+		ServiceName:  viper.GetString("TRACING_SERVICE_NAME"),
+	}
+	c.HealthCheckTimeout = viper.GetDuration("HEALTH_CHECK_TIMEOUT")
+	c.Sender = SenderConfig{
+		GlobalRate: viper.GetFloat64("TELEGRAM_SEND_GLOBAL_RATE"),
+		ChatRate:   viper.GetFloat64("TELEGRAM_SEND_CHAT_RATE"),
+		GroupRate:  viper.GetFloat64("TELEGRAM_SEND_GROUP_RATE"),
+		MaxRetries: viper.GetInt("TELEGRAM_SEND_MAX_RETRIES"),
+	}
+	c.Throttle = ThrottleConfig{
+		Rate:            viper.GetFloat64("THROTTLE_RATE"),
+		Burst:           viper.GetInt("THROTTLE_BURST"),
+		MaxViolations:   viper.GetInt("THROTTLE_MAX_VIOLATIONS"),
+		ViolationWindow: viper.GetDuration("THROTTLE_VIOLATION_WINDOW"),
+		BanDuration:     viper.GetDuration("THROTTLE_BAN_DURATION"),
+	}
+	c.CacheMaxStaleMinutes = viper.GetInt("CACHE_MAX_STALE_MINUTES")
+	c.CacheRefreshInterval = viper.GetDuration("CACHE_REFRESH_INTERVAL")
+	c.CacheRefreshTopPairs = viper.GetInt("CACHE_REFRESH_TOP_PAIRS")
+	c.CacheMaxEntries = viper.GetInt("CACHE_MAX_ENTRIES")
+	c.CacheBackend = strings.ToLower(viper.GetString("CACHE_BACKEND"))
+	c.RedisURL = viper.GetString("REDIS_URL")
+	c.RedisKeyPrefix = viper.GetString("REDIS_KEY_PREFIX")
+	c.HistoryRetention = viper.GetDuration("HISTORY_RETENTION")
+
+	if c.AdminIDs, err = parseIDList(viper.GetString("ADMIN_IDS")); err != nil {
+		return nil, fmt.Errorf("invalid ADMIN_IDS: %w", err)
+	}
 
 	if c.BotToken == "" {
 		return nil, fmt.Errorf("BOT_TOKEN is required")
 	}
+	if c.CallbackSecret == "" {
+		c.CallbackSecret = c.BotToken
+	}
+
+	if c.CacheRefreshInterval <= 0 {
+		return nil, fmt.Errorf("CACHE_REFRESH_INTERVAL must be positive")
+	}
+
+	if c.Sender.GlobalRate <= 0 || c.Sender.ChatRate <= 0 || c.Sender.GroupRate <= 0 {
+		return nil, fmt.Errorf("TELEGRAM_SEND_*_RATE must be positive")
+	}
+
+	if c.Throttle.Rate < 0 || (c.Throttle.Rate > 0 && c.Throttle.Burst < 1) {
+		return nil, fmt.Errorf("THROTTLE_RATE must not be negative and THROTTLE_BURST must be at least 1")
+	}
+
+	if c.DB.MaxConns < 1 || c.DB.MinConns < 0 || c.DB.MinConns > c.DB.MaxConns {
+		return nil, fmt.Errorf("DB_MAX_CONNS must be positive and DB_MIN_CONNS between 0 and DB_MAX_CONNS")
+	}
+	// В pgxpool нулевые время жизни и простоя означают «закрыть сразу», а не «без ограничения»
+	if c.DB.ConnectTimeout <= 0 || c.DB.HealthCheckPeriod <= 0 || c.DB.MaxConnLifetime <= 0 || c.DB.MaxConnIdleTime <= 0 {
+		return nil, fmt.Errorf("DB_CONNECT_TIMEOUT, DB_HEALTH_CHECK_PERIOD, DB_MAX_CONN_LIFETIME and DB_MAX_CONN_IDLE_TIME must be positive")
+	}
+	if c.DB.StatementTimeout < 0 {
+		return nil, fmt.Errorf("DB_STATEMENT_TIMEOUT must not be negative")
+	}
+
+	if c.HistoryRetention < 0 {
+		return nil, fmt.Errorf("HISTORY_RETENTION must not be negative")
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		return nil, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if c.CacheProviderTTL, err = parseDurationMap(viper.GetString("CACHE_PROVIDER_TTL")); err != nil {
+		return nil, fmt.Errorf("invalid CACHE_PROVIDER_TTL: %w", err)
+	}
+	if c.CacheCurrencyTTL, err = parseDurationMap(viper.GetString("CACHE_CURRENCY_TTL")); err != nil {
+		return nil, fmt.Errorf("invalid CACHE_CURRENCY_TTL: %w", err)
+	}
+
+	switch c.CacheBackend {
+	case CacheBackendMemory:
+	case CacheBackendRedis:
+		if c.RedisURL == "" {
+			return nil, fmt.Errorf("REDIS_URL is required for redis cache backend")
+		}
+	default:
+		return nil, fmt.Errorf("unknown CACHE_BACKEND %q", c.CacheBackend)
+	}
+
+	providers, err := loadProviders()
+	if err != nil {
+		return nil, err
+	}
+	c.Providers = providers
 
 	return &c, nil
 }
+
+// apiKeyProviders — провайдеры, клиенты которых умеют передавать ключ доступа
+var apiKeyProviders = map[string]bool{ProviderFrankfurter: true}
+
+// loadProviders читает настройки провайдеров из переменных вида
+// PROVIDER_CBR_ENABLED, PROVIDER_CBR_PRIORITY, PROVIDER_CBR_BASE_URL,
+// PROVIDER_CBR_TIMEOUT, PROVIDER_FRANKFURTER_API_KEY и PROVIDER_CBR_CURRENCIES
+func loadProviders() ([]ProviderConfig, error) {
+	var providers []ProviderConfig
+	enabled := 0
+
+	for _, def := range providerDefaults {
+		prefix := "PROVIDER_" + strings.ToUpper(def.Name) + "_"
+
+		viper.SetDefault(prefix+"ENABLED", def.Enabled)
+		viper.SetDefault(prefix+"PRIORITY", def.Priority)
+		viper.SetDefault(prefix+"TIMEOUT", def.Timeout)
+
+		p := ProviderConfig{
+			Name:       def.Name,
+			Enabled:    viper.GetBool(prefix + "ENABLED"),
+			Priority:   viper.GetInt(prefix + "PRIORITY"),
+			BaseURL:    viper.GetString(prefix + "BASE_URL"),
+			Timeout:    viper.GetDuration(prefix + "TIMEOUT"),
+			APIKey:     viper.GetString(prefix + "API_KEY"),
+			Currencies: splitList(viper.GetString(prefix + "CURRENCIES")),
+		}
+
+		if p.Timeout <= 0 {
+			return nil, fmt.Errorf("%sTIMEOUT must be positive", prefix)
+		}
+		if p.APIKey != "" && !apiKeyProviders[p.Name] {
+			return nil, fmt.Errorf("%sAPI_KEY is not supported by this provider", prefix)
+		}
+		if p.Enabled {
+			enabled++
+		}
+
+		providers = append(providers, p)
+	}
+
+	if enabled == 0 {
+		return nil, fmt.Errorf("at least one exchange provider must be enabled")
+	}
+
+	return providers, nil
+}
+
+// splitList разбирает список вида "RUB, KZT" в коды в верхнем регистре
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseIDList разбирает список Telegram ID вида "123, 456"
+func parseIDList(value string) ([]int64, error) {
+	var ids []int64
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", item)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseDurationMap разбирает список вида "CBR=12h,BTC=1m"
+func parseDurationMap(value string) (map[string]time.Duration, error) {
+	result := make(map[string]time.Duration)
+	for _, item := range splitList(value) {
+		name, raw, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected NAME=DURATION, got %q", item)
+		}
+
+		d, err := time.ParseDuration(strings.ToLower(strings.TrimSpace(raw)))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration for %s: %q", name, raw)
+		}
+		result[strings.TrimSpace(name)] = d
+	}
+	return result, nil
+}
@@ -0,0 +1,26 @@
+package entities
+
+import "time"
+
+// PairCount — пара валют и число пользователей, добавивших ее в избранное
+type PairCount struct {
+	FromCurrency string `db:"from_currency"`
+	ToCurrency   string `db:"to_currency"`
+	Count        int    `db:"count"`
+}
+
+// FavoritesStats содержит сводку по избранному для администраторов
+type FavoritesStats struct {
+	Users     int
+	Favorites int
+	TopPairs  []PairCount
+}
+
+// AuditEntry — запись журнала действий администратора
+type AuditEntry struct {
+	ID        int64     `db:"id"`
+	AdminID   int64     `db:"admin_id"`
+	Action    string    `db:"action"`
+	Details   string    `db:"details"`
+	CreatedAt time.Time `db:"created_at"`
+}
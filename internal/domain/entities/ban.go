@@ -0,0 +1,11 @@
+package entities
+
+import "time"
+
+// Ban — временная блокировка пользователя за превышение лимита запросов
+type Ban struct {
+	UserID      int64     `db:"user_id"`
+	Reason      string    `db:"reason"`
+	BannedUntil time.Time `db:"banned_until"`
+	CreatedAt   time.Time `db:"created_at"`
+}
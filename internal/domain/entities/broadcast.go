@@ -0,0 +1,25 @@
+package entities
+
+import "time"
+
+// Статусы рассылки
+const (
+	BroadcastDraft     = "draft"   // ожидает подтверждения администратора
+	BroadcastSending   = "sending" // доставляется; продолжается после перезапуска
+	BroadcastDone      = "done"
+	BroadcastCancelled = "cancelled"
+)
+
+// Broadcast — объявление для всех пользователей бота
+type Broadcast struct {
+	ID         int64      `db:"id"`
+	AdminID    int64      `db:"admin_id"`
+	Text       string     `db:"text"`
+	Status     string     `db:"status"`
+	LastUserID int64      `db:"last_user_id"`
+	Sent       int        `db:"sent"`
+	Failed     int        `db:"failed"`
+	CreatedAt  time.Time  `db:"created_at"`
+	StartedAt  *time.Time `db:"started_at"`
+	FinishedAt *time.Time `db:"finished_at"`
+}
@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// ChatSettings — настройки чата. В личном чате это настройки пользователя,
+// в группе их меняют администраторы группы.
+type ChatSettings struct {
+	ChatID int64 `db:"chat_id"`
+	// DefaultFrom и DefaultTo подставляются, если в запросе указано меньше двух валют
+	DefaultFrom string `db:"default_from"`
+	DefaultTo   string `db:"default_to"`
+	// TriggerPattern — регулярное выражение, на совпадение с которым бот отвечает в группе
+	// без упоминания; пустое — только команды, упоминания и ответы боту
+	TriggerPattern string `db:"trigger_pattern"`
+	// AutoConvert включает пересчет сумм, упомянутых в обычных сообщениях группы,
+	// в валюты AutoCurrencies (коды через запятую)
+	AutoConvert    bool      `db:"auto_convert"`
+	AutoCurrencies string    `db:"auto_currencies"`
+	UpdatedBy      int64     `db:"updated_by"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+// HasDefaults сообщает, задана ли пара по умолчанию
+func (s ChatSettings) HasDefaults() bool {
+	return s.DefaultFrom != "" && s.DefaultTo != ""
+}
+
+// AutoTargets возвращает валюты автоматического пересчета; если они не заданы — пару по умолчанию
+func (s ChatSettings) AutoTargets() []string {
+	if s.AutoCurrencies != "" {
+		return strings.Split(s.AutoCurrencies, ",")
+	}
+	if s.HasDefaults() {
+		return []string{s.DefaultTo, s.DefaultFrom}
+	}
+	return nil
+}
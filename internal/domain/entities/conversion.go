@@ -0,0 +1,23 @@
+package entities
+
+import "time"
+
+// ConversionRecord — выполненная пользователем конвертация в истории
+type ConversionRecord struct {
+	ID        int64     `db:"id"`
+	UserID    int64     `db:"user_id"`
+	ChatID    int64     `db:"chat_id"`
+	Input     string    `db:"input_text"` // текст запроса, как его ввел пользователь
+	Amount    float64   `db:"amount"`
+	From      string    `db:"from_currency"`
+	To        string    `db:"to_currency"`
+	Rate      float64   `db:"rate"`
+	Result    float64   `db:"result"`
+	Provider  string    `db:"provider"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Pair возвращает пару в виде "USD/RUB"
+func (c ConversionRecord) Pair() string {
+	return c.From + "/" + c.To
+}
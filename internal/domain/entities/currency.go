@@ -15,14 +15,66 @@ type ExchangeRate struct {
 	From        Currency
 	To          Currency
 	Rate        float64
-	LastUpdated time.Time
+	LastUpdated time.Time // дата публикации курса источником
+	Provider    string    // имя провайдера, вернувшего курс
+	FromCache   bool      // курс взят из кэша, а не запрошен у провайдера
+	Stale       bool      // срок жизни в кэше истек, а провайдеры недоступны
+}
+
+// NewExchangeRate создает курс пары по кодам валют
+func NewExchangeRate(from, to string, rate float64, provider string, published time.Time) ExchangeRate {
+	return ExchangeRate{
+		From:        Currency{Code: from},
+		To:          Currency{Code: to},
+		Rate:        rate,
+		LastUpdated: published,
+		Provider:    provider,
+	}
+}
+
+// Conversion представляет результат пересчета суммы по курсу
+type Conversion struct {
+	Amount float64
+	Result float64
+	Rate   ExchangeRate
+}
+
+// RateChange — текущий курс и курс предыдущего дня
+type RateChange struct {
+	Current  ExchangeRate
+	Previous ExchangeRate // нулевой, если история курса недоступна
+}
+
+// Percent возвращает изменение курса в процентах; false — предыдущий курс неизвестен
+func (c RateChange) Percent() (float64, bool) {
+	if c.Previous.Rate == 0 {
+		return 0, false
+	}
+	return (c.Current.Rate - c.Previous.Rate) / c.Previous.Rate * 100, true
 }
 
 // UserFavorite представляет избранную пару валют пользователя
 type UserFavorite struct {
-	ID           int64     `db:"id"`
-	UserID       int64     `db:"user_id"`
-	FromCurrency string    `db:"from_currency"`
-	ToCurrency   string    `db:"to_currency"`
-	CreatedAt    time.Time `db:"created_at"`
+	ID            int64     `db:"id"`
+	UserID        int64     `db:"user_id"`
+	FromCurrency  string    `db:"from_currency"`
+	ToCurrency    string    `db:"to_currency"`
+	Position      int       `db:"position"`
+	Label         string    `db:"label"`          // подпись пользователя, например "Аренда в Алматы"
+	DefaultAmount float64   `db:"default_amount"` // сумма для конвертации в одно нажатие
+	Pinned        bool      `db:"pinned"`         // закрепленные пары показываются первыми
+	CreatedAt     time.Time `db:"created_at"`
+}
+
+// Pair возвращает пару в виде "USD/RUB"
+func (f UserFavorite) Pair() string {
+	return f.FromCurrency + "/" + f.ToCurrency
+}
+
+// Title возвращает подпись пары, а без нее — саму пару
+func (f UserFavorite) Title() string {
+	if f.Label != "" {
+		return f.Label
+	}
+	return f.Pair()
 }
@@ -0,0 +1,51 @@
+package entities
+
+import "time"
+
+// Статусы общего счета
+const (
+	SplitPending   = "pending" // ждет подтверждения заплатившего
+	SplitConfirmed = "confirmed"
+	SplitCancelled = "cancelled"
+)
+
+// Split — общий счет, который один участник оплатил за нескольких
+type Split struct {
+	ID        int64        `db:"id"`
+	ChatID    int64        `db:"chat_id"`
+	PaidBy    int64        `db:"paid_by"`
+	Amount    float64      `db:"amount"`
+	Currency  string       `db:"currency"`
+	Note      string       `db:"note"`
+	Status    string       `db:"status"`
+	CreatedAt time.Time    `db:"created_at"`
+	Shares    []SplitShare `db:"-"`
+}
+
+// SplitShare — доля участника в счете в валюте счета
+type SplitShare struct {
+	SplitID int64   `db:"split_id"`
+	UserID  int64   `db:"user_id"`
+	Name    string  `db:"name"`
+	Amount  float64 `db:"amount"`
+}
+
+// Settlement — перевод в счет долга, подтвержденный получателем
+type Settlement struct {
+	ID        int64     `db:"id"`
+	ChatID    int64     `db:"chat_id"`
+	FromUser  int64     `db:"from_user"`
+	ToUser    int64     `db:"to_user"`
+	Amount    float64   `db:"amount"`
+	Currency  string    `db:"currency"`
+	CreatedAt time.Time `db:"created_at"`
+	// Key — ключ нажатия кнопки, по которому перевод учитывается один раз; пустой — без проверки
+	Key string `db:"settle_key"`
+}
+
+// Transfer — перевод, который гасит долги; Amount в копейках валюты расчета
+type Transfer struct {
+	From   int64
+	To     int64
+	Amount int64
+}
@@ -0,0 +1,71 @@
+package entities
+
+import "time"
+
+// Категории расходов
+const (
+	CategoryFood          = "food"
+	CategoryTransport     = "transport"
+	CategoryLodging       = "lodging"
+	CategoryEntertainment = "entertainment"
+	CategoryShopping      = "shopping"
+	CategoryOther         = "other"
+)
+
+// Trip — поездка, в которой участники записывают расходы в местных валютах
+type Trip struct {
+	ID     int64  `db:"id"`
+	ChatID int64  `db:"chat_id"`
+	Name   string `db:"name"`
+	// BaseCurrency — валюта итогов по умолчанию
+	BaseCurrency string    `db:"base_currency"`
+	CreatedBy    int64     `db:"created_by"`
+	Active       bool      `db:"active"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// TripMember — участник поездки; Name запоминается при вступлении для отчетов
+type TripMember struct {
+	TripID   int64     `db:"trip_id"`
+	UserID   int64     `db:"user_id"`
+	Name     string    `db:"name"`
+	JoinedAt time.Time `db:"joined_at"`
+}
+
+// Expense — расход в валюте, в которой он оплачен
+type Expense struct {
+	ID       int64   `db:"id"`
+	TripID   int64   `db:"trip_id"`
+	PaidBy   int64   `db:"paid_by"`
+	Amount   float64 `db:"amount"`
+	Currency string  `db:"currency"`
+	Category string  `db:"category"`
+	Note     string  `db:"note"`
+	// SpentOn — дата расхода, по ее курсу сумма пересчитывается в отчетах
+	SpentOn   time.Time `db:"spent_on"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// CategoryTotal — сумма расходов категории
+type CategoryTotal struct {
+	Category string
+	Amount   float64
+}
+
+// PayerTotal — сколько заплатил участник
+type PayerTotal struct {
+	UserID int64
+	Amount float64
+}
+
+// ExpenseReport — расходы поездки, пересчитанные в одну валюту
+type ExpenseReport struct {
+	Currency string
+	Total    float64
+	Count    int
+	// ByCategory и ByPayer отсортированы по убыванию суммы
+	ByCategory []CategoryTotal
+	ByPayer    []PayerTotal
+	// Approximate — расходы, пересчитанные по текущему курсу, потому что курса на их дату нет
+	Approximate int
+}
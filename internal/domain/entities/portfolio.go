@@ -0,0 +1,49 @@
+package entities
+
+import "time"
+
+// Holding — сумма в одной валюте в портфеле пользователя
+type Holding struct {
+	UserID    int64     `db:"user_id"`
+	Currency  string    `db:"currency"`
+	Amount    float64   `db:"amount"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// PositionValue — оценка одной позиции портфеля
+type PositionValue struct {
+	Currency string
+	Amount   float64
+	Value    float64 // в валюте оценки
+	Share    float64 // доля в портфеле, %
+	// Previous — стоимость по курсу предыдущего дня; 0, если история курса недоступна
+	Previous float64
+}
+
+// PortfolioValuation — портфель, пересчитанный в одну валюту
+type PortfolioValuation struct {
+	Currency  string
+	Total     float64
+	Positions []PositionValue // по убыванию стоимости
+	// Previous — стоимость портфеля днем раньше; 0, если хотя бы для одной позиции нет истории
+	Previous float64
+	// Unpriced — валюты, курс которых сейчас недоступен; в итог они не входят
+	Unpriced []string
+}
+
+// Change возвращает изменение стоимости за день и в процентах; false — история недоступна
+func (v PortfolioValuation) Change() (float64, float64, bool) {
+	if v.Previous == 0 {
+		return 0, 0, false
+	}
+	diff := v.Total - v.Previous
+	return diff, diff / v.Previous * 100, true
+}
+
+// Change возвращает изменение стоимости позиции за день в процентах; false — история недоступна
+func (p PositionValue) Change() (float64, bool) {
+	if p.Previous == 0 {
+		return 0, false
+	}
+	return (p.Value - p.Previous) / p.Previous * 100, true
+}
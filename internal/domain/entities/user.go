@@ -0,0 +1,22 @@
+package entities
+
+import "time"
+
+// User — пользователь, хотя бы раз писавший боту
+type User struct {
+	ID          int64      `db:"user_id"`
+	Username    string     `db:"username"`
+	FirstSeenAt time.Time  `db:"first_seen_at"`
+	LastSeenAt  time.Time  `db:"last_seen_at"`
+	BlockedAt   *time.Time `db:"blocked_at"` // пользователь заблокировал бота
+	// PreferredCurrency — валюта взаиморасчетов; пустая, если не выбрана
+	PreferredCurrency string `db:"preferred_currency"`
+}
+
+// UserCounts — сводка по пользователям для администраторов
+type UserCounts struct {
+	Total   int `db:"total"`
+	Blocked int `db:"blocked"`
+	// Active — писавшие боту за последние сутки
+	Active int `db:"active"`
+}
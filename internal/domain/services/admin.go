@@ -0,0 +1,17 @@
+package services
+
+import (
+	"context"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+)
+
+// StatsRepository возвращает сводные данные для команд администратора
+type StatsRepository interface {
+	FavoritesStats(ctx context.Context, topPairs int) (entities.FavoritesStats, error)
+}
+
+// AuditRepository сохраняет журнал действий администраторов
+type AuditRepository interface {
+	Record(ctx context.Context, entry entities.AuditEntry) error
+}
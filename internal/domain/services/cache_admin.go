@@ -0,0 +1,29 @@
+package services
+
+import "context"
+
+// CacheStats содержит счетчики работы кэша курсов
+type CacheStats struct {
+	Hits      uint64
+	StaleHits uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int // -1, если размер хранилища неизвестен
+	Capacity  int // 0 — без ограничения
+}
+
+// HitRatio возвращает долю попаданий среди всех обращений к свежим записям
+func (s CacheStats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// CacheAdmin — операции обслуживания кэша курсов для администраторов
+type CacheAdmin interface {
+	Stats() CacheStats
+	// Flush удаляет все курсы, чтобы следующие запросы ушли к провайдерам
+	Flush(ctx context.Context) error
+}
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"context"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+)
+
+// ChatSettingsRepository хранит настройки чатов
+type ChatSettingsRepository interface {
+	// GetChatSettings возвращает пустые настройки с ChatID, если чат ничего не настраивал
+	GetChatSettings(ctx context.Context, chatID int64) (entities.ChatSettings, error)
+	SaveChatSettings(ctx context.Context, settings entities.ChatSettings) error
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// retentionBatch — сколько записей удаляется одним запросом, чтобы не держать долгую блокировку
+const retentionBatch = 5000
+
+// ConversionsRepository хранит историю конвертаций пользователей
+type ConversionsRepository interface {
+	AddConversion(ctx context.Context, record entities.ConversionRecord) error
+	// UserConversions возвращает страницу истории от новых записей к старым
+	UserConversions(ctx context.Context, userID int64, offset, limit int) ([]entities.ConversionRecord, error)
+	CountConversions(ctx context.Context, userID int64) (int, error)
+	// GetConversion возвращает запись пользователя; sql.ErrNoRows — записи нет или она чужая
+	GetConversion(ctx context.Context, userID, id int64) (entities.ConversionRecord, error)
+	// EachConversion передает fn записи пользователя по одной, от старых к новым, не загружая их все в память
+	EachConversion(ctx context.Context, userID int64, fn func(entities.ConversionRecord) error) error
+	DeleteConversions(ctx context.Context, userID int64) (int64, error)
+	// DeleteConversionsBefore удаляет не больше limit записей старше before
+	DeleteConversionsBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// RunConversionRetention каждые interval удаляет записи истории старше retention
+func RunConversionRetention(ctx context.Context, repo ConversionsRepository, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pruneConversions(ctx, repo, time.Now().Add(-retention))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneConversions удаляет старые записи пачками, пока они не закончатся
+func pruneConversions(ctx context.Context, repo ConversionsRepository, before time.Time) {
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := repo.DeleteConversionsBefore(ctx, before, retentionBatch)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to prune conversion history", zap.Error(err))
+			return
+		}
+		total += deleted
+		if deleted < retentionBatch {
+			break
+		}
+	}
+	if total > 0 {
+		logger.FromContext(ctx).Info("Conversion history pruned", zap.Int64("deleted", total), zap.Time("before", before))
+	}
+}
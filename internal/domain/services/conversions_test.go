@@ -0,0 +1,39 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeConversions хранит только число старых записей; когда они заканчиваются, останавливает очистку
+type fakeConversions struct {
+	services.ConversionsRepository
+	old    int
+	limits []int
+	stop   context.CancelFunc
+}
+
+func (f *fakeConversions) DeleteConversionsBefore(_ context.Context, _ time.Time, limit int) (int64, error) {
+	f.limits = append(f.limits, limit)
+	deleted := min(f.old, limit)
+	f.old -= deleted
+	if f.old == 0 {
+		f.stop()
+	}
+	return int64(deleted), nil
+}
+
+func TestRunConversionRetention_DeletesInBatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	repo := &fakeConversions{old: 12000, stop: cancel}
+
+	services.RunConversionRetention(ctx, repo, 24*time.Hour, time.Hour)
+
+	assert.Equal(t, 0, repo.old)
+	assert.Len(t, repo.limits, 3)
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"math/bits"
+	"slices"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+)
+
+// maxExactDebtors — до такого числа участников с ненулевым балансом переводы
+// подбираются точным перебором подмножеств, больше — жадно
+const maxExactDebtors = 16
+
+// DebtsRepository хранит общие счета и переводы в счет долгов
+type DebtsRepository interface {
+	// CreateSplit сохраняет счет в статусе pending вместе с долями
+	CreateSplit(ctx context.Context, split entities.Split) (entities.Split, error)
+	// SetSplitStatus переводит ожидающий подтверждения счет, оплаченный paidBy, в status;
+	// sql.ErrNoRows — такого счета нет или он уже подтвержден или отменен
+	SetSplitStatus(ctx context.Context, id, paidBy int64, status string) error
+	// ChatSplits возвращает подтвержденные счета чата с долями
+	ChatSplits(ctx context.Context, chatID int64) ([]entities.Split, error)
+
+	// AddSettlement сохраняет перевод; false — перевод с таким же Key уже записан
+	AddSettlement(ctx context.Context, settlement entities.Settlement) (bool, error)
+	ChatSettlements(ctx context.Context, chatID int64) ([]entities.Settlement, error)
+}
+
+// SplitEqually делит сумму в копейках на n долей; лишние копейки достаются первым долям
+func SplitEqually(total int64, n int) []int64 {
+	shares := make([]int64, n)
+	for i := range shares {
+		shares[i] = total / int64(n)
+		if int64(i) < total%int64(n) {
+			shares[i]++
+		}
+	}
+	return shares
+}
+
+// ToCents переводит сумму в копейки с округлением
+func ToCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// DebtBalances сводит счета и переводы в балансы участников в копейках base по текущему
+// курсу: положительный баланс — участнику должны, отрицательный — должен он.
+// Копейки округления относятся на участника с наибольшим по модулю балансом, чтобы сумма была нулевой.
+func DebtBalances(ctx context.Context, rates ExchangeService, splits []entities.Split,
+	settlements []entities.Settlement, base string) (map[int64]int64, error) {
+	cache := map[string]float64{base: 1}
+	rateOf := func(currency string) (float64, error) {
+		if rate, ok := cache[currency]; ok {
+			return rate, nil
+		}
+		rate, err := rates.GetRate(ctx, currency, base)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert %s to %s: %w", currency, base, err)
+		}
+		cache[currency] = rate.Rate
+		return rate.Rate, nil
+	}
+
+	amounts := make(map[int64]float64)
+	for _, split := range splits {
+		rate, err := rateOf(split.Currency)
+		if err != nil {
+			return nil, err
+		}
+		amounts[split.PaidBy] += split.Amount * rate
+		for _, share := range split.Shares {
+			amounts[share.UserID] -= share.Amount * rate
+		}
+	}
+	for _, settlement := range settlements {
+		rate, err := rateOf(settlement.Currency)
+		if err != nil {
+			return nil, err
+		}
+		amounts[settlement.FromUser] += settlement.Amount * rate
+		amounts[settlement.ToUser] -= settlement.Amount * rate
+	}
+
+	balances := make(map[int64]int64, len(amounts))
+	var sum, largest int64
+	for _, userID := range slices.Sorted(maps.Keys(amounts)) {
+		cents := ToCents(amounts[userID])
+		balances[userID] = cents
+		sum += cents
+		if abs(cents) > abs(balances[largest]) {
+			largest = userID
+		}
+	}
+	if sum != 0 {
+		balances[largest] -= sum
+	}
+
+	return balances, nil
+}
+
+// SimplifyDebts возвращает наименьший набор переводов, обнуляющий балансы (сумма балансов
+// должна быть нулевой). Участники разбиваются на наибольшее число групп с нулевой суммой:
+// внутри группы из k человек хватает k-1 перевода.
+func SimplifyDebts(balances map[int64]int64) []entities.Transfer {
+	var ids []int64
+	for userID, balance := range balances {
+		if balance != 0 {
+			ids = append(ids, userID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	slices.Sort(ids)
+
+	var transfers []entities.Transfer
+	if len(ids) > maxExactDebtors {
+		transfers = settleGreedy(ids, balances)
+	} else {
+		for _, group := range zeroSumGroups(ids, balances) {
+			transfers = append(transfers, settleGreedy(group, balances)...)
+		}
+	}
+
+	slices.SortFunc(transfers, func(a, b entities.Transfer) int {
+		return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To))
+	})
+	return transfers
+}
+
+// zeroSumGroups разбивает участников на наибольшее число групп с нулевой суммой балансов.
+// best[mask] — сколько таких групп можно выстроить, убирая участников из mask по одному:
+// группы — отрезки между подмножествами с нулевой суммой.
+func zeroSumGroups(ids []int64, balances map[int64]int64) [][]int64 {
+	n := len(ids)
+	full := 1<<n - 1
+	sums := make([]int64, full+1)
+	best := make([]int, full+1)
+	for mask := 1; mask <= full; mask++ {
+		low := bits.TrailingZeros(uint(mask))
+		sums[mask] = sums[mask&(mask-1)] + balances[ids[low]]
+		for i := 0; i < n; i++ {
+			if mask&(1<<i) != 0 {
+				best[mask] = max(best[mask], best[mask&^(1<<i)])
+			}
+		}
+		if sums[mask] == 0 {
+			best[mask]++
+		}
+	}
+
+	var groups [][]int64
+	var group []int64
+	for mask := full; mask != 0; {
+		bonus := 0
+		if sums[mask] == 0 {
+			bonus = 1
+		}
+		for i := 0; i < n; i++ {
+			next := mask &^ (1 << i)
+			if mask&(1<<i) != 0 && best[next]+bonus == best[mask] {
+				group = append(group, ids[i])
+				mask = next
+				break
+			}
+		}
+		if sums[mask] == 0 {
+			groups = append(groups, group)
+			group = nil
+		}
+	}
+	return groups
+}
+
+// settleGreedy гасит балансы группы, каждый раз сводя самого крупного должника
+// с самым крупным кредитором; для группы с нулевой суммой дает не больше k-1 перевода
+func settleGreedy(ids []int64, balances map[int64]int64) []entities.Transfer {
+	left := make([]int64, len(ids))
+	for i, id := range ids {
+		left[i] = balances[id]
+	}
+
+	var transfers []entities.Transfer
+	for {
+		creditor, debtor := 0, 0
+		for i := range left {
+			if left[i] > left[creditor] {
+				creditor = i
+			}
+			if left[i] < left[debtor] {
+				debtor = i
+			}
+		}
+		if left[creditor] <= 0 || left[debtor] >= 0 {
+			return transfers
+		}
+
+		amount := min(left[creditor], -left[debtor])
+		transfers = append(transfers, entities.Transfer{From: ids[debtor], To: ids[creditor], Amount: amount})
+		left[creditor] -= amount
+		left[debtor] += amount
+	}
+}
+
+func abs(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
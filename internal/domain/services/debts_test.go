@@ -0,0 +1,91 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// applyTransfers проверяет, что переводы обнуляют все балансы
+func applyTransfers(t *testing.T, balances map[int64]int64, transfers []entities.Transfer) {
+	t.Helper()
+	left := make(map[int64]int64, len(balances))
+	for id, balance := range balances {
+		left[id] = balance
+	}
+	for _, transfer := range transfers {
+		assert.Positive(t, transfer.Amount)
+		left[transfer.From] += transfer.Amount
+		left[transfer.To] -= transfer.Amount
+	}
+	for id, balance := range left {
+		assert.Zero(t, balance, "balance of %d", id)
+	}
+}
+
+func TestSplitEqually(t *testing.T) {
+	assert.Equal(t, []int64{300000, 300000, 300000, 300000}, services.SplitEqually(1200000, 4))
+	assert.Equal(t, []int64{334, 333, 333}, services.SplitEqually(1000, 3))
+}
+
+func TestSimplifyDebts(t *testing.T) {
+	cases := []struct {
+		name      string
+		balances  map[int64]int64
+		transfers int
+	}{
+		{"settled", map[int64]int64{1: 0, 2: 0}, 0},
+		{"one payer", map[int64]int64{1: 900, 2: -300, 3: -300, 4: -300}, 3},
+		{"chain collapses", map[int64]int64{1: 500, 2: 0, 3: -500}, 1},
+		// Жадный подбор дает здесь 5 переводов: пары 3 и -3 он не замечает
+		{"zero-sum groups", map[int64]int64{1: -800, 2: 600, 3: -200, 4: 300, 5: 400, 6: -300}, 4},
+		{"independent pairs", map[int64]int64{1: 700, 2: -700, 3: 250, 4: -250, 5: 100, 6: -100}, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transfers := services.SimplifyDebts(tc.balances)
+			assert.Len(t, transfers, tc.transfers)
+			applyTransfers(t, tc.balances, transfers)
+		})
+	}
+}
+
+func TestSimplifyDebts_ManyParticipants(t *testing.T) {
+	balances := make(map[int64]int64)
+	var sum int64
+	for id := int64(1); id <= 20; id++ {
+		balances[id] = id*137%1000 - 500
+		sum += balances[id]
+	}
+	balances[21] = -sum
+
+	transfers := services.SimplifyDebts(balances)
+	assert.LessOrEqual(t, len(transfers), len(balances)-1)
+	applyTransfers(t, balances, transfers)
+}
+
+func TestDebtBalances(t *testing.T) {
+	provider := &MockExchangeProvider{}
+	provider.On("IsAvailable").Return(true)
+	provider.On("GetRate", mock.Anything, "KZT", "RUB").Return(0.2, nil)
+	service := services.NewExchangeService([]services.ExchangeProvider{provider}, cache.NewRatesCache(5))
+
+	splits := []entities.Split{{
+		PaidBy: 1, Amount: 12000, Currency: "KZT",
+		Shares: []entities.SplitShare{{UserID: 1, Amount: 4000}, {UserID: 2, Amount: 4000}, {UserID: 3, Amount: 4000}},
+	}}
+	settlements := []entities.Settlement{{FromUser: 2, ToUser: 1, Amount: 500, Currency: "RUB"}}
+
+	balances, err := services.DebtBalances(context.Background(), service, splits, settlements, "RUB")
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{1: 110000, 2: -30000, 3: -80000}, balances)
+
+	transfers := services.SimplifyDebts(balances)
+	assert.Equal(t, []entities.Transfer{{From: 2, To: 1, Amount: 30000}, {From: 3, To: 1, Amount: 80000}}, transfers)
+}
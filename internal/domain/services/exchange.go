@@ -2,18 +2,31 @@ package services
 
 import (
 	"context"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
 )
 
 // ExchangeService определяет операции для работы с курсами валют
 type ExchangeService interface {
-	GetRate(ctx context.Context, from, to string) (float64, error)
-	ConvertAmount(ctx context.Context, amount float64, from, to string) (float64, error)
+	GetRate(ctx context.Context, from, to string) (entities.ExchangeRate, error)
+	ConvertAmount(ctx context.Context, amount float64, from, to string) (entities.Conversion, error)
+	// GetRateAt возвращает курс на прошедшую дату у провайдеров с историей курсов
+	GetRateAt(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error)
+	// GetDailyChange возвращает текущий курс и курс предыдущего дня того же провайдера
+	GetDailyChange(ctx context.Context, from, to string) (entities.RateChange, error)
 	GetSupportedCurrencies(ctx context.Context) (map[string]string, error) // код -> название
 }
 
 // ExchangeProvider определяет контракт для провайдеров курсов валют
 type ExchangeProvider interface {
-	GetRate(ctx context.Context, from, to string) (float64, error)
+	GetRate(ctx context.Context, from, to string) (entities.ExchangeRate, error)
 	GetName() string
 	IsAvailable() bool
 }
+
+// HistoricalProvider — провайдер, умеющий отдавать курс на прошедшую дату
+type HistoricalProvider interface {
+	ExchangeProvider
+	GetRateAt(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error)
+}
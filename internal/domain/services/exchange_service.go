@@ -4,57 +4,199 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/tracing"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	// refreshLockTTL ограничивает время, на которое одна реплика занимает обновление пары
+	refreshLockTTL = 15 * time.Second
+	// refreshWait — сколько ждать курс, который обновляет другая реплика
+	refreshWait      = 2 * time.Second
+	refreshPollEvery = 100 * time.Millisecond
 )
 
 type ExchangeServiceImpl struct {
-	providers []ExchangeProvider
-	cache     *cache.RatesCache
+	chain      *ProviderChain
+	store      RatesStore
+	popularity *pairPopularity
+	history    *historyCache
+}
+
+// NewExchangeService опрашивает провайдеров в порядке их перечисления
+func NewExchangeService(providers []ExchangeProvider, store RatesStore) *ExchangeServiceImpl {
+	routes := make([]ProviderRoute, 0, len(providers))
+	for i, provider := range providers {
+		routes = append(routes, ProviderRoute{Provider: provider, Priority: i})
+	}
+
+	return NewRoutedExchangeService(NewProviderChain(routes), store)
 }
 
-func NewExchangeService(providers []ExchangeProvider, cache *cache.RatesCache) *ExchangeServiceImpl {
+// NewRoutedExchangeService опрашивает провайдеров согласно цепочке с маршрутизацией по валютам
+func NewRoutedExchangeService(chain *ProviderChain, store RatesStore) *ExchangeServiceImpl {
 	return &ExchangeServiceImpl{
-		providers: providers,
-		cache:     cache,
+		chain:      chain,
+		store:      store,
+		popularity: newPairPopularity(),
+		history:    newHistoryCache(),
 	}
 }
 
-func (s *ExchangeServiceImpl) GetRate(ctx context.Context, from, to string) (float64, error) {
+func (s *ExchangeServiceImpl) GetRate(ctx context.Context, from, to string) (rate entities.ExchangeRate, err error) {
 
 	from = strings.ToUpper(strings.TrimSpace(from))
 	to = strings.ToUpper(strings.TrimSpace(to))
 
+	ctx, span := tracing.Start(ctx, "ExchangeService.GetRate",
+		attribute.String("currency.from", from),
+		attribute.String("currency.to", to),
+	)
+	defer func() {
+		span.SetAttributes(
+			attribute.String("rate.provider", rate.Provider),
+			attribute.Bool("rate.from_cache", rate.FromCache),
+			attribute.Bool("rate.stale", rate.Stale),
+		)
+		tracing.Finish(span, err)
+	}()
+
 	if from == "" || to == "" {
-		return 0, fmt.Errorf("invalid currency codes: from='%s', to='%s'", from, to)
+		return entities.ExchangeRate{}, fmt.Errorf("invalid currency codes: from='%s', to='%s'", from, to)
+	}
+
+	s.popularity.hit(from, to)
+
+	if cached, ok := s.store.Get(ctx, from, to); ok {
+		return cached, nil
+	}
+
+	rate, err = s.fetchShared(ctx, from, to)
+	if err == nil {
+		return rate, nil
+	}
+
+	// Все провайдеры недоступны — отдаем последний известный курс с пометкой
+	if stale, ok := s.store.GetStale(ctx, from, to); ok {
+		return stale, nil
 	}
 
+	return entities.ExchangeRate{}, err
+}
+
+// fetchShared запрашивает курс под общей блокировкой. Если пару уже обновляет
+// другая реплика, ждет ее результат в хранилище и только затем опрашивает провайдеров сам.
+func (s *ExchangeServiceImpl) fetchShared(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
+	release, ok := s.store.Lock(ctx, refreshLockKey(from, to), refreshLockTTL)
+	if ok {
+		defer release()
+		return s.fetch(ctx, from, to)
+	}
+
+	deadline := time.Now().Add(refreshWait)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return entities.ExchangeRate{}, ctx.Err()
+		case <-time.After(refreshPollEvery):
+		}
+
+		if cached, ok := s.store.Get(ctx, from, to); ok {
+			return cached, nil
+		}
+	}
+
+	return s.fetch(ctx, from, to)
+}
+
+func refreshLockKey(from, to string) string {
+	return "refresh:" + from + "_" + to
+}
+
+// fetch опрашивает провайдеров по цепочке, минуя кэш, и сохраняет полученный курс
+func (s *ExchangeServiceImpl) fetch(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
 	var lastErr error
-	for _, provider := range s.providers {
+	for _, provider := range s.chain.For(from, to) {
 		if !provider.IsAvailable() {
 			continue
 		}
 
+		start := time.Now()
 		rate, err := provider.GetRate(ctx, from, to)
+
+		log := logger.FromContext(ctx).With(
+			zap.String("provider", provider.GetName()),
+			zap.String("from", from),
+			zap.String("to", to),
+			zap.Duration("latency", time.Since(start)),
+		)
 		if err != nil {
+			log.Warn("Provider request failed", zap.Error(err))
 			lastErr = err
 			continue
 		}
+		log.Debug("Provider returned rate", zap.Float64("rate", rate.Rate))
 
-		s.cache.Set(from, to, rate)
+		if rate.Provider == "" {
+			rate.Provider = provider.GetName()
+		}
+		s.store.Set(ctx, rate)
 		return rate, nil
 	}
 
-	return 0, fmt.Errorf("failed to get exchange rate: %w", lastErr)
+	return entities.ExchangeRate{}, fmt.Errorf("failed to get exchange rate: %w", lastErr)
+}
+
+// RunRefresher периодически обновляет популярные пары до истечения их срока в кэше.
+// Блокируется до отмены контекста.
+func (s *ExchangeServiceImpl) RunRefresher(ctx context.Context, interval time.Duration, topPairs int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refreshPopular(ctx, interval, topPairs)
+		}
+	}
+}
+
+// refreshPopular обновляет пары, которые истекут раньше следующего запуска
+func (s *ExchangeServiceImpl) refreshPopular(ctx context.Context, ahead time.Duration, topPairs int) {
+	for _, pair := range s.popularity.top(topPairs) {
+		if remaining, ok := s.store.Remaining(ctx, pair[0], pair[1]); ok && remaining > ahead {
+			continue
+		}
+
+		// Пару уже обновляет другая реплика
+		release, ok := s.store.Lock(ctx, refreshLockKey(pair[0], pair[1]), refreshLockTTL)
+		if !ok {
+			continue
+		}
+		// Ошибку не возвращаем: при сбое остается прежняя запись как резервная
+		_, _ = s.fetch(ctx, pair[0], pair[1])
+		release()
+	}
 }
 
-func (s *ExchangeServiceImpl) ConvertAmount(ctx context.Context, amount float64, from, to string) (float64, error) {
+func (s *ExchangeServiceImpl) ConvertAmount(ctx context.Context, amount float64, from, to string) (entities.Conversion, error) {
 	rate, err := s.GetRate(ctx, from, to)
 	if err != nil {
-		return 0, err
+		return entities.Conversion{}, err
 	}
 
-	return amount * rate, nil
+	return entities.Conversion{
+		Amount: amount,
+		Result: amount * rate.Rate,
+		Rate:   rate,
+	}, nil
 }
 
 func (s *ExchangeServiceImpl) GetSupportedCurrencies(ctx context.Context) (map[string]string, error) {
@@ -2,8 +2,11 @@ package services_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
 	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
 	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
 	"github.com/stretchr/testify/assert"
@@ -14,9 +17,9 @@ type MockExchangeProvider struct {
 	mock.Mock // ← Наследуем от mock.Mock
 }
 
-func (m *MockExchangeProvider) GetRate(ctx context.Context, from, to string) (float64, error) {
+func (m *MockExchangeProvider) GetRate(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
 	args := m.Called(ctx, from, to)
-	return args.Get(0).(float64), args.Error(1)
+	return entities.NewExchangeRate(from, to, args.Get(0).(float64), "", time.Time{}), args.Error(1)
 }
 
 func (m *MockExchangeProvider) GetName() string {
@@ -41,10 +44,31 @@ func TestExchangeService_GetRate(t *testing.T) {
 	rate, err := service.GetRate(context.Background(), "USD", "EUR")
 
 	assert.NoError(t, err)
-	assert.InDelta(t, 0.85, rate, 0.001)
+	assert.InDelta(t, 0.85, rate.Rate, 0.001)
+	assert.Equal(t, "mock-provider", rate.Provider)
+	assert.False(t, rate.FromCache)
 	mockProvider.AssertExpectations(t)
 }
 
+func TestExchangeService_GetRateFromCache(t *testing.T) {
+	mockProvider := &MockExchangeProvider{}
+	cache := cache.NewRatesCache(5)
+
+	mockProvider.On("IsAvailable").Return(true)
+	mockProvider.On("GetRate", mock.Anything, "USD", "EUR").Return(0.85, nil).Once()
+
+	service := services.NewExchangeService([]services.ExchangeProvider{mockProvider}, cache)
+
+	_, err := service.GetRate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+
+	rate, err := service.GetRate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.True(t, rate.FromCache)
+	assert.Equal(t, "mock-provider", rate.Provider)
+	mockProvider.AssertNumberOfCalls(t, "GetRate", 1)
+}
+
 func TestExchangeService_ConvertAmount(t *testing.T) {
 	mockProvider := &MockExchangeProvider{}
 	cache := cache.NewRatesCache(5)
@@ -57,7 +81,8 @@ func TestExchangeService_ConvertAmount(t *testing.T) {
 	result, err := service.ConvertAmount(context.Background(), 100.0, "USD", "EUR")
 
 	assert.NoError(t, err)
-	assert.InDelta(t, 85.0, result, 0.001)
+	assert.InDelta(t, 85.0, result.Result, 0.001)
+	assert.InDelta(t, 0.85, result.Rate.Rate, 0.001)
 	mockProvider.AssertExpectations(t)
 }
 
@@ -77,3 +102,119 @@ func TestExchangeService_NoAvailableProvider(t *testing.T) {
 	mockProvider.AssertNotCalled(t, "GetRate", mock.Anything, mock.Anything, mock.Anything)
 	mockProvider.AssertExpectations(t)
 }
+
+type namedProvider struct {
+	MockExchangeProvider
+	name string
+}
+
+func (p *namedProvider) GetName() string { return p.name }
+
+func TestProviderChain_CurrencyRouting(t *testing.T) {
+	frankfurter := &namedProvider{name: "Frankfurter"}
+	cbr := &namedProvider{name: "CBR"}
+
+	chain := services.NewProviderChain([]services.ProviderRoute{
+		{Provider: cbr, Priority: 20, Currencies: []string{"RUB"}},
+		{Provider: frankfurter, Priority: 10},
+	})
+
+	names := func(providers []services.ExchangeProvider) []string {
+		var result []string
+		for _, p := range providers {
+			result = append(result, p.GetName())
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"Frankfurter", "CBR"}, names(chain.All()))
+	assert.Equal(t, []string{"Frankfurter", "CBR"}, names(chain.For("USD", "EUR")))
+	assert.Equal(t, []string{"CBR", "Frankfurter"}, names(chain.For("USD", "RUB")))
+	assert.Equal(t, []string{"CBR", "Frankfurter"}, names(chain.For("RUB", "EUR")))
+}
+
+func TestExchangeService_StaleFallback(t *testing.T) {
+	mockProvider := &MockExchangeProvider{}
+	cache := cache.NewRatesCache(0, cache.WithMaxStale(time.Hour))
+
+	mockProvider.On("IsAvailable").Return(true)
+	mockProvider.On("GetRate", mock.Anything, "USD", "EUR").Return(0.85, nil).Once()
+	mockProvider.On("GetRate", mock.Anything, "USD", "EUR").Return(0.0, errors.New("provider down")).Once()
+
+	service := services.NewExchangeService([]services.ExchangeProvider{mockProvider}, cache)
+
+	fresh, err := service.GetRate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.False(t, fresh.Stale)
+
+	time.Sleep(time.Millisecond)
+
+	stale, err := service.GetRate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.True(t, stale.Stale)
+	assert.InDelta(t, 0.85, stale.Rate, 0.001)
+	mockProvider.AssertExpectations(t)
+}
+
+func TestProbeProviders(t *testing.T) {
+	healthy := &MockExchangeProvider{}
+	healthy.On("IsAvailable").Return(true)
+	healthy.On("GetRate", mock.Anything, "USD", "EUR").Return(0.0, errors.New("unsupported")).Once()
+	healthy.On("GetRate", mock.Anything, "USD", "RUB").Return(81.5, nil).Once()
+
+	unavailable := &MockExchangeProvider{}
+	unavailable.On("IsAvailable").Return(false)
+
+	statuses := services.ProbeProviders(context.Background(), []services.ExchangeProvider{healthy, unavailable})
+
+	assert.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Healthy())
+	assert.False(t, statuses[1].Healthy())
+	healthy.AssertExpectations(t)
+}
+
+type MockHistoricalProvider struct {
+	MockExchangeProvider
+}
+
+func (m *MockHistoricalProvider) GetRateAt(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	args := m.Called(ctx, from, to, date)
+	return entities.NewExchangeRate(from, to, args.Get(0).(float64), "", date), args.Error(1)
+}
+
+func TestExchangeService_GetDailyChange(t *testing.T) {
+	provider := &MockHistoricalProvider{}
+
+	provider.On("IsAvailable").Return(true)
+	provider.On("GetRate", mock.Anything, "USD", "RUB").Return(101.0, nil).Once()
+	provider.On("GetRateAt", mock.Anything, "USD", "RUB", mock.AnythingOfType("time.Time")).Return(100.0, nil).Once()
+
+	service := services.NewExchangeService([]services.ExchangeProvider{provider}, cache.NewRatesCache(5))
+
+	change, err := service.GetDailyChange(context.Background(), "USD", "RUB")
+	assert.NoError(t, err)
+	assert.InDelta(t, 101.0, change.Current.Rate, 0.001)
+	assert.InDelta(t, 100.0, change.Previous.Rate, 0.001)
+
+	percent, ok := change.Percent()
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0, percent, 0.001)
+	provider.AssertExpectations(t)
+}
+
+func TestExchangeService_GetRateAtCachesPastDays(t *testing.T) {
+	provider := &MockHistoricalProvider{}
+	date := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	provider.On("IsAvailable").Return(true)
+	provider.On("GetRateAt", mock.Anything, "USD", "RUB", date).Return(99.0, nil).Once()
+
+	service := services.NewExchangeService([]services.ExchangeProvider{provider}, cache.NewRatesCache(5))
+
+	for range 2 {
+		rate, err := service.GetRateAt(context.Background(), "USD", "RUB", date)
+		assert.NoError(t, err)
+		assert.InDelta(t, 99.0, rate.Rate, 0.001)
+	}
+	provider.AssertExpectations(t)
+}
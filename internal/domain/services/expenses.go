@@ -0,0 +1,108 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+)
+
+// ExpensesRepository хранит поездки, их участников и расходы
+type ExpensesRepository interface {
+	// CreateTrip создает поездку и делает ее активной в чате вместо предыдущей
+	CreateTrip(ctx context.Context, trip entities.Trip) (entities.Trip, error)
+	// ActiveTrip возвращает активную поездку чата; sql.ErrNoRows — поездка не начата
+	ActiveTrip(ctx context.Context, chatID int64) (entities.Trip, error)
+	CloseTrip(ctx context.Context, tripID int64) error
+
+	// AddTripMember добавляет участника или обновляет его имя
+	AddTripMember(ctx context.Context, member entities.TripMember) error
+	GetTripMembers(ctx context.Context, tripID int64) ([]entities.TripMember, error)
+
+	AddExpense(ctx context.Context, expense entities.Expense) (entities.Expense, error)
+	// GetTripExpenses возвращает расходы поездки от ранних к поздним
+	GetTripExpenses(ctx context.Context, tripID int64) ([]entities.Expense, error)
+	// DeleteExpense удаляет расход, записанный userID; sql.ErrNoRows — такого расхода нет
+	DeleteExpense(ctx context.Context, tripID, id, userID int64) error
+}
+
+// BuildExpenseReport пересчитывает расходы в currency по курсу на дату каждого расхода.
+// Если курса на дату нет (провайдеры без истории), используется текущий курс,
+// такие расходы учитываются в Approximate.
+func BuildExpenseReport(ctx context.Context, rates ExchangeService, expenses []entities.Expense, currency string) (entities.ExpenseReport, error) {
+	report := entities.ExpenseReport{Currency: currency, Count: len(expenses)}
+
+	type rateKey struct {
+		currency string
+		date     string
+	}
+	type rateValue struct {
+		rate        float64
+		approximate bool
+	}
+	cache := make(map[rateKey]rateValue)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	rateOf := func(from string, date time.Time) (rateValue, error) {
+		key := rateKey{from, date.Format(time.DateOnly)}
+		if value, ok := cache[key]; ok {
+			return value, nil
+		}
+
+		var value rateValue
+		if date.Before(today) {
+			if rate, err := rates.GetRateAt(ctx, from, currency, date); err == nil {
+				value = rateValue{rate: rate.Rate}
+			}
+		}
+		if value.rate == 0 {
+			rate, err := rates.GetRate(ctx, from, currency)
+			if err != nil {
+				return rateValue{}, fmt.Errorf("failed to convert %s to %s: %w", from, currency, err)
+			}
+			value = rateValue{rate: rate.Rate, approximate: date.Before(today)}
+		}
+
+		cache[key] = value
+		return value, nil
+	}
+
+	byCategory := make(map[string]float64)
+	byPayer := make(map[int64]float64)
+	for _, expense := range expenses {
+		amount := expense.Amount
+		if expense.Currency != currency {
+			value, err := rateOf(expense.Currency, expense.SpentOn)
+			if err != nil {
+				return entities.ExpenseReport{}, err
+			}
+			amount *= value.rate
+			if value.approximate {
+				report.Approximate++
+			}
+		}
+
+		report.Total += amount
+		byCategory[expense.Category] += amount
+		byPayer[expense.PaidBy] += amount
+	}
+
+	for category, amount := range byCategory {
+		report.ByCategory = append(report.ByCategory, entities.CategoryTotal{Category: category, Amount: amount})
+	}
+	slices.SortFunc(report.ByCategory, func(a, b entities.CategoryTotal) int {
+		return cmp.Or(cmp.Compare(b.Amount, a.Amount), cmp.Compare(a.Category, b.Category))
+	})
+
+	for userID, amount := range byPayer {
+		report.ByPayer = append(report.ByPayer, entities.PayerTotal{UserID: userID, Amount: amount})
+	}
+	slices.SortFunc(report.ByPayer, func(a, b entities.PayerTotal) int {
+		return cmp.Or(cmp.Compare(b.Amount, a.Amount), cmp.Compare(a.UserID, b.UserID))
+	})
+
+	return report, nil
+}
@@ -0,0 +1,56 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildExpenseReport(t *testing.T) {
+	provider := &MockHistoricalProvider{}
+	march14 := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	march15 := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	provider.On("IsAvailable").Return(true)
+	provider.On("GetRateAt", mock.Anything, "KZT", "RUB", march14).Return(0.2, nil).Once()
+	provider.On("GetRateAt", mock.Anything, "KZT", "RUB", march15).Return(0.0, errors.New("no data")).Once()
+	provider.On("GetRate", mock.Anything, "KZT", "RUB").Return(0.18, nil)
+
+	service := services.NewExchangeService([]services.ExchangeProvider{provider}, cache.NewRatesCache(5))
+
+	expenses := []entities.Expense{
+		{PaidBy: 1, Amount: 3500, Currency: "KZT", Category: entities.CategoryTransport, SpentOn: march14},
+		{PaidBy: 2, Amount: 10000, Currency: "KZT", Category: entities.CategoryFood, SpentOn: march14},
+		{PaidBy: 1, Amount: 1000, Currency: "KZT", Category: entities.CategoryFood, SpentOn: march15},
+		{PaidBy: 2, Amount: 500, Currency: "RUB", Category: entities.CategoryFood, SpentOn: today},
+		{PaidBy: 1, Amount: 100, Currency: "KZT", Category: entities.CategoryOther, SpentOn: today},
+	}
+
+	report, err := services.BuildExpenseReport(context.Background(), service, expenses, "RUB")
+	require.NoError(t, err)
+
+	// 700 + 2000 по курсу 14 марта, 180 по текущему курсу вместо курса 15 марта, 500 и 18 за сегодня
+	assert.InDelta(t, 3398.0, report.Total, 0.001)
+	assert.Equal(t, 5, report.Count)
+	assert.Equal(t, 1, report.Approximate)
+
+	require.Len(t, report.ByCategory, 3)
+	assert.Equal(t, entities.CategoryFood, report.ByCategory[0].Category)
+	assert.InDelta(t, 2680.0, report.ByCategory[0].Amount, 0.001)
+	assert.Equal(t, entities.CategoryTransport, report.ByCategory[1].Category)
+
+	require.Len(t, report.ByPayer, 2)
+	assert.Equal(t, int64(2), report.ByPayer[0].UserID)
+	assert.InDelta(t, 2500.0, report.ByPayer[0].Amount, 0.001)
+	assert.InDelta(t, 898.0, report.ByPayer[1].Amount, 0.001)
+	provider.AssertExpectations(t)
+}
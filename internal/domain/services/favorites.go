@@ -11,4 +11,12 @@ type FavoritesRepository interface {
 	AddFavorite(ctx context.Context, userID int64, fromCurrency, toCurrency string) error
 	GetUserFavorites(ctx context.Context, userID int64) ([]entities.UserFavorite, error)
 	RemoveFavorite(ctx context.Context, userID int64, fromCurrency, toCurrency string) error
+
+	GetFavorite(ctx context.Context, userID, id int64) (entities.UserFavorite, error)
+	// MoveFavorite сдвигает пару в списке на delta позиций (-1 — выше, 1 — ниже)
+	MoveFavorite(ctx context.Context, userID, id int64, delta int) error
+	// RenameFavorite задает подпись пары; пустая строка убирает подпись
+	RenameFavorite(ctx context.Context, userID, id int64, label string) error
+	SetDefaultAmount(ctx context.Context, userID, id int64, amount float64) error
+	SetPinned(ctx context.Context, userID, id int64, pinned bool) error
 }
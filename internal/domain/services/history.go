@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/tracing"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// maxHistoryEntries ограничивает кэш исторических курсов; при переполнении он очищается целиком
+const maxHistoryEntries = 10000
+
+// ErrNoHistory — ни один провайдер пары не отдает историю курсов
+var ErrNoHistory = errors.New("historical rates are not available for this pair")
+
+// historyCache хранит курсы на прошедшие дни: они больше не меняются
+type historyCache struct {
+	mu    sync.Mutex
+	rates map[string]entities.ExchangeRate
+}
+
+func newHistoryCache() *historyCache {
+	return &historyCache{rates: make(map[string]entities.ExchangeRate)}
+}
+
+func historyKey(from, to string, date time.Time) string {
+	return from + "_" + to + "_" + date.Format(time.DateOnly)
+}
+
+func (c *historyCache) get(key string) (entities.ExchangeRate, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rate, ok := c.rates[key]
+	return rate, ok
+}
+
+func (c *historyCache) set(key string, rate entities.ExchangeRate) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.rates) >= maxHistoryEntries {
+		clear(c.rates)
+	}
+	c.rates[key] = rate
+}
+
+func (s *ExchangeServiceImpl) GetRateAt(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	return s.rateAt(ctx, strings.ToUpper(from), strings.ToUpper(to), date, "")
+}
+
+// GetDailyChange сравнивает текущий курс с курсом предыдущего дня. Если история
+// недоступна, возвращается только текущий курс.
+func (s *ExchangeServiceImpl) GetDailyChange(ctx context.Context, from, to string) (entities.RateChange, error) {
+	current, err := s.GetRate(ctx, from, to)
+	if err != nil {
+		return entities.RateChange{}, err
+	}
+
+	published := current.LastUpdated
+	if published.IsZero() {
+		published = time.Now()
+	}
+
+	// Предыдущий курс берем у того же провайдера, иначе изменение покажет разницу источников
+	previous, err := s.rateAt(ctx, current.From.Code, current.To.Code, published.AddDate(0, 0, -1), current.Provider)
+	if err != nil {
+		logger.FromContext(ctx).Debug("Previous rate unavailable",
+			zap.String("from", current.From.Code), zap.String("to", current.To.Code), zap.Error(err))
+		return entities.RateChange{Current: current}, nil
+	}
+
+	return entities.RateChange{Current: current, Previous: previous}, nil
+}
+
+// rateAt опрашивает провайдеров с историей курсов; preferred, если задан, опрашивается первым
+func (s *ExchangeServiceImpl) rateAt(ctx context.Context, from, to string, date time.Time, preferred string) (rate entities.ExchangeRate, err error) {
+	ctx, span := tracing.Start(ctx, "ExchangeService.GetRateAt",
+		attribute.String("currency.from", from),
+		attribute.String("currency.to", to),
+		attribute.String("rate.date", date.Format(time.DateOnly)),
+	)
+	defer func() { tracing.Finish(span, err) }()
+
+	providers := s.chain.For(from, to)
+	if preferred != "" {
+		for i, provider := range providers {
+			if provider.GetName() == preferred {
+				providers = append([]ExchangeProvider{provider}, append(providers[:i:i], providers[i+1:]...)...)
+				break
+			}
+		}
+	}
+
+	lastErr := ErrNoHistory
+	for _, provider := range providers {
+		historical, ok := provider.(HistoricalProvider)
+		if !ok || !provider.IsAvailable() {
+			continue
+		}
+
+		key := provider.GetName() + ":" + historyKey(from, to, date)
+		if cached, ok := s.history.get(key); ok {
+			return cached, nil
+		}
+
+		rate, err := historical.GetRateAt(ctx, from, to, date)
+		if err != nil {
+			logger.FromContext(ctx).Warn("Provider history request failed",
+				zap.String("provider", provider.GetName()), zap.Error(err))
+			lastErr = err
+			continue
+		}
+
+		if rate.Provider == "" {
+			rate.Provider = provider.GetName()
+		}
+		// Курс на сегодня еще может быть не опубликован, кэшируем только прошедшие дни
+		if date.Before(time.Now().Truncate(24 * time.Hour)) {
+			s.history.set(key, rate)
+		}
+		return rate, nil
+	}
+
+	return entities.ExchangeRate{}, fmt.Errorf("failed to get historical rate: %w", lastErr)
+}
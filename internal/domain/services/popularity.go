@@ -0,0 +1,67 @@
+package services
+
+import (
+	"sort"
+	"sync"
+)
+
+// maxTrackedPairs ограничивает число пар, по которым ведется статистика запросов
+const maxTrackedPairs = 1000
+
+// pairPopularity считает запросы по парам, чтобы фоном обновлять самые востребованные
+type pairPopularity struct {
+	mu     sync.Mutex
+	counts map[[2]string]int
+}
+
+func newPairPopularity() *pairPopularity {
+	return &pairPopularity{counts: make(map[[2]string]int)}
+}
+
+func (p *pairPopularity) hit(from, to string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := [2]string{from, to}
+	if _, ok := p.counts[key]; !ok && len(p.counts) >= maxTrackedPairs {
+		p.decay()
+	}
+	p.counts[key]++
+}
+
+// decay вдвое уменьшает счетчики и забывает редкие пары, освобождая место для новых
+func (p *pairPopularity) decay() {
+	for key, count := range p.counts {
+		if count/2 == 0 {
+			delete(p.counts, key)
+		} else {
+			p.counts[key] = count / 2
+		}
+	}
+}
+
+// top возвращает до n самых запрашиваемых пар
+func (p *pairPopularity) top(n int) [][2]string {
+	p.mu.Lock()
+	pairs := make([][2]string, 0, len(p.counts))
+	for key := range p.counts {
+		pairs = append(pairs, key)
+	}
+	counts := make(map[[2]string]int, len(p.counts))
+	for key, count := range p.counts {
+		counts[key] = count
+	}
+	p.mu.Unlock()
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if counts[pairs[i]] != counts[pairs[j]] {
+			return counts[pairs[i]] > counts[pairs[j]]
+		}
+		return pairs[i][0]+pairs[i][1] < pairs[j][0]+pairs[j][1]
+	})
+
+	if len(pairs) > n {
+		pairs = pairs[:n]
+	}
+	return pairs
+}
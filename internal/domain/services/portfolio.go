@@ -0,0 +1,65 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// HoldingsRepository хранит портфели пользователей
+type HoldingsRepository interface {
+	GetHoldings(ctx context.Context, userID int64) ([]entities.Holding, error)
+	// AddHolding прибавляет amount к позиции, создавая ее при необходимости
+	AddHolding(ctx context.Context, userID int64, currency string, amount float64) (entities.Holding, error)
+	// RemoveHolding удаляет позицию; sql.ErrNoRows — такой валюты в портфеле нет
+	RemoveHolding(ctx context.Context, userID int64, currency string) error
+}
+
+// ValuePortfolio оценивает позиции в currency по текущему курсу и курсу предыдущего дня.
+// Позиции, курс которых недоступен, попадают в Unpriced и не входят в итог.
+func ValuePortfolio(ctx context.Context, rates ExchangeService, holdings []entities.Holding, currency string) entities.PortfolioValuation {
+	valuation := entities.PortfolioValuation{Currency: currency}
+	complete := true
+
+	for _, holding := range holdings {
+		position := entities.PositionValue{Currency: holding.Currency, Amount: holding.Amount}
+		if holding.Currency == currency {
+			position.Value, position.Previous = holding.Amount, holding.Amount
+		} else {
+			change, err := rates.GetDailyChange(ctx, holding.Currency, currency)
+			if err != nil {
+				logger.FromContext(ctx).Warn("Failed to value holding",
+					zap.String("from", holding.Currency), zap.String("to", currency), zap.Error(err))
+				valuation.Unpriced = append(valuation.Unpriced, holding.Currency)
+				continue
+			}
+			position.Value = holding.Amount * change.Current.Rate
+			position.Previous = holding.Amount * change.Previous.Rate
+		}
+
+		if position.Previous == 0 {
+			complete = false
+		}
+		valuation.Total += position.Value
+		valuation.Previous += position.Previous
+		valuation.Positions = append(valuation.Positions, position)
+	}
+
+	if !complete {
+		valuation.Previous = 0
+	}
+	for i := range valuation.Positions {
+		if valuation.Total > 0 {
+			valuation.Positions[i].Share = valuation.Positions[i].Value / valuation.Total * 100
+		}
+	}
+	slices.SortFunc(valuation.Positions, func(a, b entities.PositionValue) int {
+		return cmp.Or(cmp.Compare(b.Value, a.Value), cmp.Compare(a.Currency, b.Currency))
+	})
+
+	return valuation
+}
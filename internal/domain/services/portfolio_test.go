@@ -0,0 +1,55 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValuePortfolio(t *testing.T) {
+	provider := &MockHistoricalProvider{}
+	provider.On("IsAvailable").Return(true)
+	provider.On("GetRate", mock.Anything, "USD", "RUB").Return(100.0, nil)
+	provider.On("GetRateAt", mock.Anything, "USD", "RUB", mock.AnythingOfType("time.Time")).Return(98.0, nil)
+	provider.On("GetRate", mock.Anything, "KZT", "RUB").Return(0.2, nil)
+	provider.On("GetRateAt", mock.Anything, "KZT", "RUB", mock.AnythingOfType("time.Time")).Return(0.0, errors.New("no history"))
+	provider.On("GetRate", mock.Anything, "XAU", "RUB").Return(0.0, errors.New("unsupported"))
+
+	service := services.NewExchangeService([]services.ExchangeProvider{provider}, cache.NewRatesCache(5))
+	holdings := []entities.Holding{
+		{Currency: "KZT", Amount: 100000},
+		{Currency: "USD", Amount: 1000},
+		{Currency: "RUB", Amount: 80000},
+		{Currency: "XAU", Amount: 1},
+	}
+
+	valuation := services.ValuePortfolio(context.Background(), service, holdings, "RUB")
+	assert.InDelta(t, 200000.0, valuation.Total, 0.001)
+	assert.Equal(t, []string{"XAU"}, valuation.Unpriced)
+
+	require.Len(t, valuation.Positions, 3)
+	assert.Equal(t, "USD", valuation.Positions[0].Currency)
+	assert.InDelta(t, 50.0, valuation.Positions[0].Share, 0.001)
+	change, ok := valuation.Positions[0].Change()
+	assert.True(t, ok)
+	assert.InDelta(t, 2.0408, change, 0.001)
+
+	// У KZT нет истории, поэтому изменение всего портфеля неизвестно
+	_, ok = valuation.Positions[2].Change()
+	assert.False(t, ok)
+	_, _, ok = valuation.Change()
+	assert.False(t, ok)
+
+	valuation = services.ValuePortfolio(context.Background(), service, holdings[1:3], "RUB")
+	diff, percent, ok := valuation.Change()
+	assert.True(t, ok)
+	assert.InDelta(t, 2000.0, diff, 0.001)
+	assert.InDelta(t, 1.1236, percent, 0.001)
+}
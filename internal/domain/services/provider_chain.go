@@ -0,0 +1,64 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// ProviderRoute описывает место провайдера в цепочке опроса
+type ProviderRoute struct {
+	Provider ExchangeProvider
+	Priority int // меньшее значение — раньше в цепочке
+	// Currencies — валюты, для пар с которыми провайдер опрашивается первым
+	Currencies []string
+}
+
+// ProviderChain определяет порядок опроса провайдеров для конкретной пары
+type ProviderChain struct {
+	routes []ProviderRoute
+}
+
+// NewProviderChain упорядочивает маршруты по приоритету, сохраняя исходный порядок при равенстве
+func NewProviderChain(routes []ProviderRoute) *ProviderChain {
+	sorted := make([]ProviderRoute, len(routes))
+	copy(sorted, routes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	return &ProviderChain{routes: sorted}
+}
+
+// For возвращает провайдеров для пары: сначала закрепленные за одной из валют, затем остальные
+func (c *ProviderChain) For(from, to string) []ExchangeProvider {
+	preferred := make([]ExchangeProvider, 0, len(c.routes))
+	var rest []ExchangeProvider
+
+	for _, route := range c.routes {
+		if route.matches(from) || route.matches(to) {
+			preferred = append(preferred, route.Provider)
+		} else {
+			rest = append(rest, route.Provider)
+		}
+	}
+
+	return append(preferred, rest...)
+}
+
+// All возвращает всех провайдеров в порядке приоритета
+func (c *ProviderChain) All() []ExchangeProvider {
+	providers := make([]ExchangeProvider, 0, len(c.routes))
+	for _, route := range c.routes {
+		providers = append(providers, route.Provider)
+	}
+	return providers
+}
+
+func (r ProviderRoute) matches(currency string) bool {
+	for _, c := range r.Currencies {
+		if strings.EqualFold(c, currency) {
+			return true
+		}
+	}
+	return false
+}
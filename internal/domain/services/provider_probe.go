@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var errProviderUnavailable = errors.New("provider unavailable")
+
+// CanaryPairs — пары для проверки провайдеров; достаточно ответа хотя бы по одной,
+// так как ЦБ РФ работает только с рублем, а Frankfurter — без него
+var CanaryPairs = [][2]string{{"USD", "EUR"}, {"USD", "RUB"}}
+
+// ProviderStatus — результат проверки одного провайдера
+type ProviderStatus struct {
+	Name    string
+	Latency time.Duration // время первого успешного ответа или всех попыток
+	Err     error
+}
+
+// Healthy сообщает, вернул ли провайдер курс хотя бы по одной канареечной паре
+func (s ProviderStatus) Healthy() bool {
+	return s.Err == nil
+}
+
+// ProbeProviders запрашивает канареечные пары у каждого провайдера в обход кэша
+func ProbeProviders(ctx context.Context, providers []ExchangeProvider) []ProviderStatus {
+	statuses := make([]ProviderStatus, 0, len(providers))
+
+	for _, provider := range providers {
+		status := ProviderStatus{Name: provider.GetName()}
+		if !provider.IsAvailable() {
+			status.Err = errProviderUnavailable
+			statuses = append(statuses, status)
+			continue
+		}
+
+		start := time.Now()
+		for _, pair := range CanaryPairs {
+			if _, status.Err = provider.GetRate(ctx, pair[0], pair[1]); status.Err == nil {
+				break
+			}
+		}
+		status.Latency = time.Since(start)
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
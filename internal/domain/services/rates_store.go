@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+)
+
+// RatesStore определяет хранилище курсов, общее для всех запросов сервиса.
+// Ошибки хранилища не должны ломать получение курса, поэтому методы
+// сообщают только о наличии записи.
+type RatesStore interface {
+	// Get возвращает курс, срок жизни которого еще не истек
+	Get(ctx context.Context, from, to string) (entities.ExchangeRate, bool)
+	// GetStale возвращает курс и после истечения срока жизни, помечая его как устаревший
+	GetStale(ctx context.Context, from, to string) (entities.ExchangeRate, bool)
+	// Remaining возвращает оставшееся время жизни записи
+	Remaining(ctx context.Context, from, to string) (time.Duration, bool)
+	Set(ctx context.Context, rate entities.ExchangeRate)
+	// Lock захватывает общую блокировку, чтобы пару обновлял только один экземпляр бота.
+	// Возвращенную функцию нужно вызвать для освобождения блокировки.
+	Lock(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool)
+}
+
+// CacheTTLPolicy выбирает срок жизни курса в кэше; общая для хранилищ в памяти и в Redis,
+// чтобы курсы в них устаревали одинаково
+type CacheTTLPolicy struct {
+	ttl         time.Duration
+	providerTTL map[string]time.Duration
+	currencyTTL map[string]time.Duration
+}
+
+// NewCacheTTLPolicy создает политику со сроком по умолчанию и переопределениями:
+// для провайдеров — например, дольше для ежедневных фиксингов ЦБ РФ,
+// для валют — например, короче для криптовалют
+func NewCacheTTLPolicy(ttl time.Duration, providers, currencies map[string]time.Duration) CacheTTLPolicy {
+	p := CacheTTLPolicy{
+		ttl:         ttl,
+		providerTTL: make(map[string]time.Duration, len(providers)),
+		currencyTTL: make(map[string]time.Duration, len(currencies)),
+	}
+	for provider, ttl := range providers {
+		p.providerTTL[strings.ToUpper(provider)] = ttl
+	}
+	for currency, ttl := range currencies {
+		p.currencyTTL[strings.ToUpper(currency)] = ttl
+	}
+	return p
+}
+
+// TTL выбирает срок жизни курса: по валюте (меньший из двух), затем по провайдеру, иначе общий
+func (p CacheTTLPolicy) TTL(rate entities.ExchangeRate) time.Duration {
+	ttl, found := time.Duration(0), false
+	for _, code := range []string{rate.From.Code, rate.To.Code} {
+		if override, ok := p.currencyTTL[code]; ok && (!found || override < ttl) {
+			ttl, found = override, true
+		}
+	}
+	if found {
+		return ttl
+	}
+
+	if override, ok := p.providerTTL[strings.ToUpper(rate.Provider)]; ok {
+		return override
+	}
+
+	return p.ttl
+}
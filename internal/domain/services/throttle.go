@@ -0,0 +1,252 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// BanRepository хранит временные блокировки пользователей
+type BanRepository interface {
+	Ban(ctx context.Context, ban entities.Ban) error
+	Unban(ctx context.Context, userID int64) error
+	// Active возвращает блокировки, срок которых еще не истек
+	Active(ctx context.Context) ([]entities.Ban, error)
+}
+
+// ThrottleConfig задает лимиты входящих запросов одного пользователя
+type ThrottleConfig struct {
+	Rate  float64 // устойчивый поток, запросов в секунду
+	Burst int     // сколько запросов подряд допускается без ожидания
+	// MaxViolations превышений за ViolationWindow приводят к блокировке на BanDuration
+	MaxViolations   int
+	ViolationWindow time.Duration
+	BanDuration     time.Duration
+}
+
+// ThrottleVerdict — решение по входящему запросу
+type ThrottleVerdict struct {
+	Allowed bool
+	// Notify — первое отклонение подряд: пользователю стоит ответить, остальные игнорируются молча
+	Notify      bool
+	BannedUntil time.Time // не нулевое, если пользователь заблокирован
+}
+
+const banReasonFlood = "flood"
+
+// Throttler ограничивает частоту запросов пользователей и блокирует тех,
+// кто раз за разом превышает лимит. Лимиты считаются в памяти экземпляра,
+// блокировки сохраняются в репозитории и периодически перечитываются.
+type Throttler struct {
+	cfg  ThrottleConfig
+	bans BanRepository
+
+	mu     sync.Mutex
+	users  map[int64]*userThrottle
+	banned map[int64]time.Time
+	// issued — блокировки этого экземпляра, которых еще может не быть в репозитории
+	issued map[int64]issuedBan
+}
+
+// issuedBan — выданная экземпляром блокировка; savedAt — когда удалась запись в репозиторий
+type issuedBan struct {
+	ban     entities.Ban
+	savedAt time.Time
+}
+
+type userThrottle struct {
+	limiter     *rate.Limiter
+	violations  int
+	windowStart time.Time
+	notified    bool
+	lastSeen    time.Time
+}
+
+func NewThrottler(cfg ThrottleConfig, bans BanRepository) *Throttler {
+	return &Throttler{
+		cfg:    cfg,
+		bans:   bans,
+		users:  make(map[int64]*userThrottle),
+		banned: make(map[int64]time.Time),
+		issued: make(map[int64]issuedBan),
+	}
+}
+
+// Check учитывает запрос пользователя и решает, обрабатывать ли его
+func (t *Throttler) Check(ctx context.Context, userID int64) ThrottleVerdict {
+	now := time.Now()
+
+	t.mu.Lock()
+	if until, ok := t.banned[userID]; ok {
+		if now.Before(until) {
+			t.mu.Unlock()
+			return ThrottleVerdict{BannedUntil: until}
+		}
+		delete(t.banned, userID)
+	}
+
+	user, ok := t.users[userID]
+	if !ok {
+		user = &userThrottle{limiter: rate.NewLimiter(rate.Limit(t.cfg.Rate), t.cfg.Burst)}
+		t.users[userID] = user
+	}
+	user.lastSeen = now
+
+	if user.limiter.AllowN(now, 1) {
+		user.notified = false
+		t.mu.Unlock()
+		return ThrottleVerdict{Allowed: true}
+	}
+
+	if now.Sub(user.windowStart) > t.cfg.ViolationWindow {
+		user.windowStart = now
+		user.violations = 0
+	}
+	user.violations++
+
+	if t.cfg.MaxViolations > 0 && user.violations >= t.cfg.MaxViolations {
+		until := now.Add(t.cfg.BanDuration)
+		ban := entities.Ban{UserID: userID, Reason: banReasonFlood, BannedUntil: until}
+		t.banned[userID] = until
+		t.issued[userID] = issuedBan{ban: ban}
+		delete(t.users, userID)
+		t.mu.Unlock()
+
+		logger.FromContext(ctx).Warn("User banned for flooding",
+			zap.Int64("banned_user_id", userID), zap.Time("banned_until", until))
+		t.save(ctx, ban)
+		return ThrottleVerdict{Notify: true, BannedUntil: until}
+	}
+
+	notify := !user.notified
+	user.notified = true
+	t.mu.Unlock()
+
+	return ThrottleVerdict{Notify: notify}
+}
+
+// Cooldown — через сколько у пользователя появится следующий разрешенный запрос
+func (t *Throttler) Cooldown() time.Duration {
+	if t.cfg.Rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / t.cfg.Rate)
+}
+
+// Bans возвращает действующие блокировки
+func (t *Throttler) Bans(ctx context.Context) ([]entities.Ban, error) {
+	return t.bans.Active(ctx)
+}
+
+// Unban снимает блокировку досрочно
+func (t *Throttler) Unban(ctx context.Context, userID int64) error {
+	t.mu.Lock()
+	delete(t.banned, userID)
+	delete(t.issued, userID)
+	t.mu.Unlock()
+
+	return t.bans.Unban(ctx, userID)
+}
+
+// Run периодически перечитывает блокировки, выданные другими экземплярами,
+// и удаляет лимитеры неактивных пользователей
+func (t *Throttler) Run(ctx context.Context, interval time.Duration) {
+	t.reload(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.reload(ctx)
+			t.cleanup()
+		}
+	}
+}
+
+// save записывает блокировку в репозиторий; неудачная запись повторяется при перечитывании
+func (t *Throttler) save(ctx context.Context, ban entities.Ban) {
+	if err := t.bans.Ban(ctx, ban); err != nil {
+		logger.FromContext(ctx).Error("Failed to save ban", zap.Error(err))
+		return
+	}
+
+	t.mu.Lock()
+	if issued, ok := t.issued[ban.UserID]; ok && issued.ban == ban {
+		issued.savedAt = time.Now()
+		t.issued[ban.UserID] = issued
+	}
+	t.mu.Unlock()
+}
+
+// reload заменяет блокировки прочитанными из репозитория, сохраняя свои, которых там еще нет:
+// незаписанные и записанные после начала чтения. Своя блокировка, записанная раньше
+// и пропавшая из репозитория, снята на другом экземпляре.
+func (t *Throttler) reload(ctx context.Context) {
+	start := time.Now()
+
+	t.mu.Lock()
+	var unsaved []entities.Ban
+	for _, issued := range t.issued {
+		if issued.savedAt.IsZero() {
+			unsaved = append(unsaved, issued.ban)
+		}
+	}
+	t.mu.Unlock()
+	for _, ban := range unsaved {
+		t.save(ctx, ban)
+	}
+
+	bans, err := t.bans.Active(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to load bans", zap.Error(err))
+		return
+	}
+
+	banned := make(map[int64]time.Time, len(bans))
+	for _, ban := range bans {
+		banned[ban.UserID] = ban.BannedUntil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for userID, issued := range t.issued {
+		_, stored := banned[userID]
+		switch {
+		case stored || !now.Before(issued.ban.BannedUntil):
+			delete(t.issued, userID)
+		case issued.savedAt.IsZero() || issued.savedAt.After(start):
+			banned[userID] = issued.ban.BannedUntil
+		default:
+			delete(t.issued, userID)
+		}
+	}
+	t.banned = banned
+}
+
+func (t *Throttler) cleanup() {
+	// Лимитер простоявшего столько пользователя полностью восстановился
+	idle := t.cfg.ViolationWindow
+	if full := time.Duration(float64(t.cfg.Burst) * float64(t.Cooldown())); full > idle {
+		idle = full
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for userID, user := range t.users {
+		if time.Since(user.lastSeen) > idle {
+			delete(t.users, userID)
+		}
+	}
+}
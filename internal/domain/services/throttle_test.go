@@ -0,0 +1,137 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeBans struct {
+	mu   sync.Mutex
+	bans map[int64]entities.Ban
+	err  error // ошибка записи блокировки
+}
+
+func (f *fakeBans) Ban(_ context.Context, ban entities.Ban) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.bans[ban.UserID] = ban
+	return nil
+}
+
+func (f *fakeBans) Unban(_ context.Context, userID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.bans, userID)
+	return nil
+}
+
+func (f *fakeBans) Active(context.Context) ([]entities.Ban, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var bans []entities.Ban
+	for _, ban := range f.bans {
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+func TestThrottler_LimitsAndNotifiesOnce(t *testing.T) {
+	ctx := context.Background()
+	throttler := services.NewThrottler(services.ThrottleConfig{
+		Rate: 0.001, Burst: 2, MaxViolations: 10, ViolationWindow: time.Minute, BanDuration: time.Hour,
+	}, &fakeBans{bans: make(map[int64]entities.Ban)})
+
+	assert.True(t, throttler.Check(ctx, 1).Allowed)
+	assert.True(t, throttler.Check(ctx, 1).Allowed)
+
+	first := throttler.Check(ctx, 1)
+	assert.False(t, first.Allowed)
+	assert.True(t, first.Notify)
+
+	second := throttler.Check(ctx, 1)
+	assert.False(t, second.Allowed)
+	assert.False(t, second.Notify, "cooldown reply must be sent only once")
+
+	assert.True(t, throttler.Check(ctx, 2).Allowed, "limits are per user")
+}
+
+func TestThrottler_BansRepeatOffenders(t *testing.T) {
+	ctx := context.Background()
+	bans := &fakeBans{bans: make(map[int64]entities.Ban)}
+	throttler := services.NewThrottler(services.ThrottleConfig{
+		Rate: 0.001, Burst: 1, MaxViolations: 3, ViolationWindow: time.Minute, BanDuration: time.Hour,
+	}, bans)
+
+	throttler.Check(ctx, 1)
+	throttler.Check(ctx, 1)
+	throttler.Check(ctx, 1)
+	verdict := throttler.Check(ctx, 1)
+
+	assert.False(t, verdict.Allowed)
+	assert.True(t, verdict.Notify)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), verdict.BannedUntil, time.Second)
+	assert.Contains(t, bans.bans, int64(1))
+
+	banned := throttler.Check(ctx, 1)
+	assert.False(t, banned.Allowed)
+	assert.False(t, banned.Notify)
+
+	assert.NoError(t, throttler.Unban(ctx, 1))
+	assert.True(t, throttler.Check(ctx, 1).Allowed)
+	assert.Empty(t, bans.bans)
+}
+
+// reloadOnce перечитывает блокировки: Run с отмененным контекстом делает только первое чтение
+func reloadOnce(throttler *services.Throttler) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	throttler.Run(ctx, time.Hour)
+}
+
+func TestThrottler_ReloadKeepsUnsavedBan(t *testing.T) {
+	ctx := context.Background()
+	bans := &fakeBans{bans: make(map[int64]entities.Ban), err: errors.New("connection refused")}
+	throttler := services.NewThrottler(services.ThrottleConfig{
+		Rate: 0.001, Burst: 1, MaxViolations: 1, ViolationWindow: time.Minute, BanDuration: time.Hour,
+	}, bans)
+
+	throttler.Check(ctx, 1)
+	assert.False(t, throttler.Check(ctx, 1).BannedUntil.IsZero())
+	assert.Empty(t, bans.bans)
+
+	reloadOnce(throttler)
+	assert.False(t, throttler.Check(ctx, 1).Allowed, "ban that failed to save must survive reload")
+
+	bans.err = nil
+	reloadOnce(throttler)
+	assert.Contains(t, bans.bans, int64(1), "unsaved ban must be saved again on reload")
+	assert.False(t, throttler.Check(ctx, 1).Allowed)
+}
+
+func TestThrottler_ReloadDropsBanLiftedElsewhere(t *testing.T) {
+	ctx := context.Background()
+	bans := &fakeBans{bans: make(map[int64]entities.Ban)}
+	throttler := services.NewThrottler(services.ThrottleConfig{
+		Rate: 0.001, Burst: 1, MaxViolations: 1, ViolationWindow: time.Minute, BanDuration: time.Hour,
+	}, bans)
+
+	throttler.Check(ctx, 1)
+	throttler.Check(ctx, 1)
+	assert.Contains(t, bans.bans, int64(1))
+
+	// Другой экземпляр снял блокировку
+	assert.NoError(t, bans.Unban(ctx, 1))
+	reloadOnce(throttler)
+	assert.True(t, throttler.Check(ctx, 1).Allowed)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// UserRows — строки таблиц с данными пользователя: имя таблицы → строки в виде колонка → значение
+type UserRows map[string][]map[string]any
+
+// UserDataSource — хранилище, которое выгружает и удаляет данные пользователя по Telegram ID
+type UserDataSource interface {
+	// UserDataTables — таблицы, за данные пользователя в которых отвечает источник
+	UserDataTables() []string
+	ExportUserData(ctx context.Context, userID int64) (UserRows, error)
+	// DeleteUserData удаляет данные пользователя и возвращает число затронутых строк
+	DeleteUserData(ctx context.Context, userID int64) (int64, error)
+}
+
+// UserDataRegistry — единый список хранилищ с данными пользователей: /mydata и /forgetme
+// обходят все зарегистрированные источники
+type UserDataRegistry struct {
+	sources []UserDataSource
+}
+
+func NewUserDataRegistry(sources ...UserDataSource) *UserDataRegistry {
+	r := &UserDataRegistry{}
+	for _, source := range sources {
+		r.Register(source)
+	}
+	return r
+}
+
+// Register добавляет источник; одна таблица не может принадлежать двум источникам
+func (r *UserDataRegistry) Register(source UserDataSource) {
+	for _, table := range source.UserDataTables() {
+		if slices.Contains(r.Tables(), table) {
+			panic(fmt.Sprintf("user data table %q registered twice", table))
+		}
+	}
+	r.sources = append(r.sources, source)
+}
+
+// Tables возвращает все зарегистрированные таблицы
+func (r *UserDataRegistry) Tables() []string {
+	var tables []string
+	for _, source := range r.sources {
+		tables = append(tables, source.UserDataTables()...)
+	}
+	return tables
+}
+
+// Export собирает данные пользователя из всех источников
+func (r *UserDataRegistry) Export(ctx context.Context, userID int64) (UserRows, error) {
+	rows := make(UserRows)
+	for _, source := range r.sources {
+		data, err := source.ExportUserData(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for table, tableRows := range data {
+			rows[table] = append(rows[table], tableRows...)
+		}
+	}
+	return rows, nil
+}
+
+// Delete удаляет данные пользователя во всех источниках. Ошибка одного источника
+// не останавливает остальные: повторный вызов дочищает то, что не удалилось.
+func (r *UserDataRegistry) Delete(ctx context.Context, userID int64) (int64, error) {
+	var total int64
+	var errs []error
+	for _, source := range r.sources {
+		deleted, err := source.DeleteUserData(ctx, userID)
+		total += deleted
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return total, errors.Join(errs...)
+}
@@ -0,0 +1,54 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserData отдает одну строку на таблицу и считает удаления
+type fakeUserData struct {
+	tables  []string
+	err     error
+	deletes int
+}
+
+func (f *fakeUserData) UserDataTables() []string { return f.tables }
+
+func (f *fakeUserData) ExportUserData(_ context.Context, userID int64) (services.UserRows, error) {
+	rows := make(services.UserRows)
+	for _, table := range f.tables {
+		rows[table] = []map[string]any{{"user_id": userID}}
+	}
+	return rows, nil
+}
+
+func (f *fakeUserData) DeleteUserData(context.Context, int64) (int64, error) {
+	f.deletes++
+	return int64(len(f.tables)), f.err
+}
+
+func TestUserDataRegistry(t *testing.T) {
+	failing := &fakeUserData{tables: []string{"holdings"}, err: errors.New("connection reset")}
+	favorites := &fakeUserData{tables: []string{"user_favorites", "users"}}
+	registry := services.NewUserDataRegistry(failing, favorites)
+
+	assert.Equal(t, []string{"holdings", "user_favorites", "users"}, registry.Tables())
+
+	rows, err := registry.Export(context.Background(), 42)
+	require.NoError(t, err)
+	assert.Len(t, rows, 3)
+	assert.Equal(t, int64(42), rows["users"][0]["user_id"])
+
+	// Ошибка одного источника не мешает удалить данные в остальных
+	deleted, err := registry.Delete(context.Background(), 42)
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.Equal(t, 1, favorites.deletes)
+
+	assert.Panics(t, func() { registry.Register(&fakeUserData{tables: []string{"users"}}) })
+}
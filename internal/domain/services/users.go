@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+)
+
+// UsersRepository хранит пользователей бота
+type UsersRepository interface {
+	// Touch создает пользователя или обновляет время последнего обращения;
+	// написавший снова пользователь считается разблокировавшим бота
+	Touch(ctx context.Context, userID int64, username string) error
+	MarkBlocked(ctx context.Context, userID int64) error
+	Counts(ctx context.Context) (entities.UserCounts, error)
+	// ActiveIDsAfter возвращает не заблокировавших бота пользователей с ID больше afterID
+	ActiveIDsAfter(ctx context.Context, afterID int64, limit int) ([]int64, error)
+
+	// FindByUsername ищет пользователя по @имени без учета регистра; sql.ErrNoRows — боту не писал
+	FindByUsername(ctx context.Context, username string) (entities.User, error)
+	// SetPreferredCurrency задает валюту взаиморасчетов; пустая строка сбрасывает ее
+	SetPreferredCurrency(ctx context.Context, userID int64, currency string) error
+	// PreferredCurrencies возвращает выбранные валюты; пользователи без валюты пропускаются
+	PreferredCurrencies(ctx context.Context, userIDs []int64) (map[int64]string, error)
+}
+
+// BroadcastRepository хранит рассылки и прогресс их доставки
+type BroadcastRepository interface {
+	Create(ctx context.Context, adminID int64, text string) (entities.Broadcast, error)
+	Get(ctx context.Context, id int64) (entities.Broadcast, error)
+	// SetStatus переводит рассылку в status, только если текущий статус входит в from
+	SetStatus(ctx context.Context, id int64, status string, from ...string) (bool, error)
+	// Progress сохраняет курсор доставки, чтобы продолжить рассылку после перезапуска
+	Progress(ctx context.Context, id, lastUserID int64, sent, failed int) error
+	// Sending возвращает рассылки, доставка которых не завершена
+	Sending(ctx context.Context) ([]entities.Broadcast, error)
+}
@@ -0,0 +1,138 @@
+// Package health реализует проверки живости и готовности для HTTP-проб
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Статусы проверок в ответе
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc проверяет одну зависимость и возвращает причину отказа
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult — результат одной проверки
+type CheckResult struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// Report — тело ответа /readyz
+type Report struct {
+	Status    string                 `json:"status"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker выполняет проверки готовности и кэширует результат, чтобы пробы оставались дешевыми
+type Checker struct {
+	checks  []namedCheck
+	ttl     time.Duration
+	timeout time.Duration
+
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewChecker создает проверку, результат которой переиспользуется в течение ttl
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{ttl: ttl, timeout: timeout}
+}
+
+// Add регистрирует проверку. Вызывается до запуска HTTP-сервера.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, fn: fn})
+}
+
+// Check возвращает отчет, выполняя проверки заново только по истечении ttl
+func (c *Checker) Check(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.ttl {
+		return *c.report
+	}
+
+	report := c.run(ctx)
+	c.report = &report
+	return report
+}
+
+// run выполняет проверки параллельно, каждую со своим таймаутом
+func (c *Checker) run(ctx context.Context) Report {
+	results := make([]CheckResult, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check namedCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.fn(checkCtx)
+
+			results[i] = CheckResult{Status: StatusOK, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Status = StatusFail
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now(),
+		Checks:    make(map[string]CheckResult, len(c.checks)),
+	}
+	for i, check := range c.checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
+
+// LivenessHandler отвечает 200, пока процесс способен обслуживать HTTP
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+	})
+}
+
+// ReadinessHandler отвечает 503 с описанием проваленных проверок, если бот не готов
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadinessHandler_ReportsFailingChecks(t *testing.T) {
+	checker := NewChecker(time.Minute, time.Second)
+	checker.Add("database", func(context.Context) error { return nil })
+	checker.Add("telegram", func(context.Context) error { return errors.New("getMe: unauthorized") })
+
+	rec := httptest.NewRecorder()
+	checker.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var report Report
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+	assert.Equal(t, StatusFail, report.Checks["telegram"].Status)
+	assert.Equal(t, "getMe: unauthorized", report.Checks["telegram"].Error)
+}
+
+func TestChecker_CachesResult(t *testing.T) {
+	calls := 0
+	checker := NewChecker(time.Minute, time.Second)
+	checker.Add("database", func(context.Context) error {
+		calls++
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		report := checker.Check(context.Background())
+		assert.Equal(t, StatusOK, report.Status)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestChecker_AppliesTimeout(t *testing.T) {
+	checker := NewChecker(0, 10*time.Millisecond)
+	checker.Add("provider", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := checker.Check(context.Background())
+	assert.Equal(t, StatusFail, report.Status)
+	assert.Contains(t, report.Checks["provider"].Error, "deadline exceeded")
+}
+
+func TestLivenessHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
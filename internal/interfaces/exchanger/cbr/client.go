@@ -6,11 +6,25 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/tracing"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"golang.org/x/net/html/charset"
 )
 
+// DefaultBaseURL — адрес ежедневных курсов ЦБ РФ
+const DefaultBaseURL = "https://www.cbr.ru/scripts/XML_daily.asp"
+
+// dateLayout — формат даты в ответах ЦБ РФ
+const dateLayout = "02.01.2006"
+
 type ValCurs struct {
+	Date    string `xml:"Date,attr"`
 	Valutes []struct {
 		CharCode string `xml:"CharCode"`
 		Value    string `xml:"Value"`
@@ -19,32 +33,120 @@ type ValCurs struct {
 }
 
 type CBRClient struct {
-	baseURL string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Option настраивает клиент ЦБ РФ
+type Option func(*CBRClient)
+
+// WithBaseURL переопределяет адрес источника
+func WithBaseURL(baseURL string) Option {
+	return func(c *CBRClient) {
+		if baseURL != "" {
+			c.baseURL = baseURL
+		}
+	}
+}
+
+// WithTimeout задает таймаут HTTP-запросов
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *CBRClient) {
+		if timeout > 0 {
+			c.httpClient.Timeout = timeout
+		}
+	}
+}
+
+func New(opts ...Option) *CBRClient {
+	c := &CBRClient{
+		baseURL: DefaultBaseURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func New() *CBRClient {
-	return &CBRClient{baseURL: "https://www.cbr.ru/scripts/XML_daily.asp"}
+func (c *CBRClient) GetRate(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
+	ctx, span := tracing.StartClient(ctx, c.GetName()+" GetRate",
+		attribute.String("rate.provider", c.GetName()),
+		attribute.String("currency.from", from),
+		attribute.String("currency.to", to),
+	)
+	rate, err := c.getRate(ctx, from, to, time.Time{})
+	tracing.Finish(span, err)
+
+	return rate, err
 }
 
-func (c *CBRClient) GetRate(ctx context.Context, from, to string) (float64, error) {
+// GetRateAt возвращает курс, установленный ЦБ РФ на указанную дату
+// (или на ближайшую предыдущую, если в этот день курс не устанавливался)
+func (c *CBRClient) GetRateAt(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	ctx, span := tracing.StartClient(ctx, c.GetName()+" GetRateAt",
+		attribute.String("rate.provider", c.GetName()),
+		attribute.String("currency.from", from),
+		attribute.String("currency.to", to),
+		attribute.String("rate.date", date.Format(time.DateOnly)),
+	)
+	rate, err := c.getRate(ctx, from, to, date)
+	tracing.Finish(span, err)
+
+	return rate, err
+}
+
+// getRate запрашивает курс на дату; нулевая дата — последний установленный курс
+func (c *CBRClient) getRate(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
 	if to != "RUB" && from != "RUB" {
-		return 0, fmt.Errorf("CBR provider only supports RUB pairs")
+		return entities.ExchangeRate{}, fmt.Errorf("CBR provider only supports RUB pairs")
+	}
+
+	url := c.baseURL
+	if !date.IsZero() {
+		url += "?date_req=" + date.Format("02/01/2006")
 	}
 
-	resp, err := http.Get(c.baseURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return 0, err
+		return entities.ExchangeRate{}, err
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return entities.ExchangeRate{}, err
 	}
 	defer resp.Body.Close()
 
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("server.address", req.URL.Host),
+		attribute.Int("http.response.status_code", resp.StatusCode),
+	)
+	logger.FromContext(ctx).Debug("Provider HTTP response",
+		zap.String("provider", c.GetName()),
+		zap.String("host", req.URL.Host),
+		zap.Int("status", resp.StatusCode),
+		zap.Duration("latency", time.Since(start)),
+	)
+
+	if resp.StatusCode != http.StatusOK {
+		return entities.ExchangeRate{}, fmt.Errorf("API error: %d", resp.StatusCode)
+	}
+
 	decoder := xml.NewDecoder(resp.Body)
 	decoder.CharsetReader = charset.NewReaderLabel
 
 	var data ValCurs
 	if err := decoder.Decode(&data); err != nil {
-		return 0, err
+		return entities.ExchangeRate{}, err
 	}
 
+	// Дата отсутствует только в нестандартных ответах — тогда оставляем нулевую
+	published, _ := time.Parse(dateLayout, data.Date)
+
 	target := from
 	if from == "RUB" {
 		target = to
@@ -57,12 +159,12 @@ func (c *CBRClient) GetRate(ctx context.Context, from, to string) (float64, erro
 			_, _ = fmt.Sscanf(valStr, "%f", &rate)
 			res := rate / float64(v.Nominal)
 			if from == "RUB" {
-				return 1 / res, nil
+				res = 1 / res
 			}
-			return res, nil
+			return entities.NewExchangeRate(from, to, res, c.GetName(), published), nil
 		}
 	}
-	return 0, fmt.Errorf("currency %s not found", target)
+	return entities.ExchangeRate{}, fmt.Errorf("currency %s not found", target)
 }
 
 func (c *CBRClient) GetName() string   { return "CBR" }
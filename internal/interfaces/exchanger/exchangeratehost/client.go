@@ -3,58 +3,182 @@ package exchangeratehost
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/tracing"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
+// DefaultBaseURL — адрес API Frankfurter
+const DefaultBaseURL = "https://api.frankfurter.app"
+
+// dateLayout — формат даты в ответах API
+const dateLayout = "2006-01-02"
+
 type ExchangeRateHostResponse struct {
+	Date  string             `json:"date"`
 	Rates map[string]float64 `json:"rates"`
 }
 
 type ExchangeRateHostClient struct {
 	baseURL    string
+	apiKey     string
 	httpClient *http.Client
 }
 
-func New() *ExchangeRateHostClient {
-	return &ExchangeRateHostClient{
-		baseURL: "https://api.frankfurter.app",
+// Option настраивает клиент
+type Option func(*ExchangeRateHostClient)
+
+// WithBaseURL переопределяет адрес API
+func WithBaseURL(baseURL string) Option {
+	return func(c *ExchangeRateHostClient) {
+		if baseURL != "" {
+			c.baseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithTimeout задает таймаут HTTP-запросов
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *ExchangeRateHostClient) {
+		if timeout > 0 {
+			c.httpClient.Timeout = timeout
+		}
+	}
+}
+
+// WithAPIKey передает ключ доступа для совместимых API (exchangerate.host и т.п.)
+func WithAPIKey(apiKey string) Option {
+	return func(c *ExchangeRateHostClient) {
+		c.apiKey = apiKey
+	}
+}
+
+func New(opts ...Option) *ExchangeRateHostClient {
+	c := &ExchangeRateHostClient{
+		baseURL: DefaultBaseURL,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *ExchangeRateHostClient) GetRate(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
+	ctx, span := tracing.StartClient(ctx, c.GetName()+" GetRate",
+		attribute.String("rate.provider", c.GetName()),
+		attribute.String("currency.from", from),
+		attribute.String("currency.to", to),
+	)
+	rate, err := c.getRate(ctx, from, to, time.Time{})
+	tracing.Finish(span, err)
+
+	return rate, err
+}
+
+// GetRateAt возвращает курс на указанную дату или на ближайший предыдущий рабочий день
+func (c *ExchangeRateHostClient) GetRateAt(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	ctx, span := tracing.StartClient(ctx, c.GetName()+" GetRateAt",
+		attribute.String("rate.provider", c.GetName()),
+		attribute.String("currency.from", from),
+		attribute.String("currency.to", to),
+		attribute.String("rate.date", date.Format(dateLayout)),
+	)
+	rate, err := c.getRate(ctx, from, to, date)
+	tracing.Finish(span, err)
+
+	return rate, err
 }
 
-func (c *ExchangeRateHostClient) GetRate(ctx context.Context, from, to string) (float64, error) {
-	url := fmt.Sprintf("%s/latest?from=%s&to=%s", c.baseURL, from, to)
+// getRate запрашивает курс на дату; нулевая дата — последний опубликованный курс
+func (c *ExchangeRateHostClient) getRate(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	endpoint := "latest"
+	if !date.IsZero() {
+		endpoint = date.Format(dateLayout)
+	}
+
+	// API принимает ключ только в query-параметре, поэтому он вырезается из ошибок
+	query := url.Values{"from": {from}, "to": {to}}
+	if c.apiKey != "" {
+		query.Set("access_key", c.apiKey)
+	}
+	requestURL := c.baseURL + "/" + endpoint + "?" + query.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
 	if err != nil {
-		return 0, err
+		return entities.ExchangeRate{}, c.redact(err)
 	}
 
+	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return 0, err
+		return entities.ExchangeRate{}, c.redact(err)
 	}
 	defer resp.Body.Close()
 
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("server.address", req.URL.Host),
+		attribute.Int("http.response.status_code", resp.StatusCode),
+	)
+	logger.FromContext(ctx).Debug("Provider HTTP response",
+		zap.String("provider", c.GetName()),
+		zap.String("host", req.URL.Host),
+		zap.Int("status", resp.StatusCode),
+		zap.Duration("latency", time.Since(start)),
+	)
+
 	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("API error: %d", resp.StatusCode)
+		return entities.ExchangeRate{}, fmt.Errorf("API error: %d", resp.StatusCode)
 	}
 
 	var apiResponse ExchangeRateHostResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return 0, err
+		return entities.ExchangeRate{}, err
 	}
 
 	rate, exists := apiResponse.Rates[to]
 	if !exists {
-		return 0, fmt.Errorf("rate not found")
+		return entities.ExchangeRate{}, fmt.Errorf("rate not found")
 	}
 
-	return rate, nil
+	published, _ := time.Parse(dateLayout, apiResponse.Date)
+
+	return entities.NewExchangeRate(from, to, rate, c.GetName(), published), nil
+}
+
+// redact убирает ключ доступа из адреса запроса в *url.Error, который попадает в логи;
+// адрес, который не разбирается, остается без query-параметров
+func (c *ExchangeRateHostClient) redact(err error) error {
+	var urlErr *url.Error
+	if c.apiKey == "" || !errors.As(err, &urlErr) {
+		return err
+	}
+
+	u, parseErr := url.Parse(urlErr.URL)
+	if parseErr != nil {
+		urlErr.URL, _, _ = strings.Cut(urlErr.URL, "?")
+		return err
+	}
+
+	query := u.Query()
+	if query.Has("access_key") {
+		query.Set("access_key", "REDACTED")
+		u.RawQuery = query.Encode()
+	}
+	urlErr.URL = u.String()
+	return err
 }
 
 func (c *ExchangeRateHostClient) GetName() string {
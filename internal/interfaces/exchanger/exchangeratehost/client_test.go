@@ -0,0 +1,36 @@
+package exchangeratehost
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_APIKey(t *testing.T) {
+	const apiKey = "k&ey+with%chars"
+
+	var received url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.URL.Query()
+		_, _ = w.Write([]byte(`{"date":"2024-05-01","rates":{"EUR":0.92}}`))
+	}))
+
+	client := New(WithBaseURL(server.URL), WithAPIKey(apiKey))
+	rate, err := client.GetRate(context.Background(), "USD", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, 0.92, rate.Rate)
+	assert.Equal(t, apiKey, received.Get("access_key"), "key is sent encoded and arrives intact")
+	assert.Equal(t, "USD", received.Get("from"))
+
+	// Ошибка соединения содержит адрес запроса и попадает в логи
+	server.Close()
+	_, err = client.GetRate(context.Background(), "USD", "EUR")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), url.QueryEscape(apiKey))
+	assert.Contains(t, err.Error(), "access_key=REDACTED")
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// adminTopPairs — сколько популярных пар показывать в /stats
+	adminTopPairs = 10
+	// providerProbeTimeout ограничивает проверку провайдеров в /providers
+	providerProbeTimeout = 15 * time.Second
+)
+
+// AdminDeps — зависимости команд администратора
+type AdminDeps struct {
+	Stats     services.StatsRepository
+	Audit     services.AuditRepository
+	Cache     services.CacheAdmin // nil, если хранилище курсов не поддерживает обслуживание
+	Providers []services.ExchangeProvider
+
+	Broadcasts  services.BroadcastRepository
+	Broadcaster *Broadcaster
+}
+
+// WithAdmin включает команды администратора для пользователей из списка;
+// статистика и рассылки используют репозиторий из WithUsers
+func WithAdmin(ids []int64, deps AdminDeps) Option {
+	return func(h *BotHandler) {
+		for _, id := range ids {
+			h.admins[id] = true
+		}
+		h.admin = deps
+	}
+}
+
+func (h *BotHandler) isAdmin(user *tgbotapi.User) bool {
+	return user != nil && h.admins[user.ID]
+}
+
+// handleAdminCommand выполняет команду администратора; false — команда не административная
+func (h *BotHandler) handleAdminCommand(ctx context.Context, message *tgbotapi.Message) bool {
+	if !message.IsCommand() {
+		return false
+	}
+
+	switch message.Command() {
+	case "stats":
+		h.handleAdminStats(ctx, message)
+	case "providers":
+		h.handleAdminProviders(ctx, message)
+	case "cache":
+		h.handleAdminCache(ctx, message)
+	case "broadcast":
+		h.handleAdminBroadcast(ctx, message)
+	case "bans":
+		h.handleAdminBans(ctx, message)
+	case "unban":
+		h.handleAdminUnban(ctx, message)
+	default:
+		return false
+	}
+	return true
+}
+
+// audit записывает действие администратора; ошибка журнала не прерывает команду
+func (h *BotHandler) audit(ctx context.Context, adminID int64, action, details string) {
+	entry := entities.AuditEntry{
+		AdminID: adminID,
+		Action:  action,
+		Details: details,
+	}
+
+	if err := h.admin.Audit.Record(ctx, entry); err != nil {
+		logger.FromContext(ctx).Error("Failed to record admin action",
+			zap.String("action", action), zap.Error(err))
+	}
+}
+
+func (h *BotHandler) handleAdminStats(ctx context.Context, message *tgbotapi.Message) {
+	h.audit(ctx, message.From.ID, "stats", "")
+
+	stats, err := h.admin.Stats.FavoritesStats(ctx, adminTopPairs)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get favorites stats", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "❌ Не удалось получить статистику."))
+		return
+	}
+
+	counts, err := h.users.Counts(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to count users", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "❌ Не удалось получить статистику."))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📈 *Статистика*\n\n")
+	sb.WriteString(fmt.Sprintf("👥 Пользователей: %d, активных за сутки: %d\n", counts.Total, counts.Active))
+	sb.WriteString(fmt.Sprintf("🚫 Заблокировали бота: %d\n", counts.Blocked))
+	sb.WriteString(fmt.Sprintf("⭐ Пользователей с избранным: %d\n", stats.Users))
+	sb.WriteString(fmt.Sprintf("⭐ Избранных пар: %d\n", stats.Favorites))
+
+	if len(stats.TopPairs) > 0 {
+		sb.WriteString("\n*Популярные пары:*\n")
+		for i, pair := range stats.TopPairs {
+			sb.WriteString(fmt.Sprintf("%d. %s/%s — %d\n", i+1, pair.FromCurrency, pair.ToCurrency, pair.Count))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, sb.String())
+	msg.ParseMode = "Markdown"
+	h.sendMessage(ctx, msg)
+}
+
+func (h *BotHandler) handleAdminProviders(ctx context.Context, message *tgbotapi.Message) {
+	h.audit(ctx, message.From.ID, "providers", "")
+
+	probeCtx, cancel := context.WithTimeout(ctx, providerProbeTimeout)
+	defer cancel()
+
+	var sb strings.Builder
+	sb.WriteString("🏦 Провайдеры курсов\n\n")
+
+	for _, status := range services.ProbeProviders(probeCtx, h.admin.Providers) {
+		latency := status.Latency.Round(time.Millisecond)
+		if status.Healthy() {
+			sb.WriteString(fmt.Sprintf("✅ %s — %s\n", status.Name, latency))
+		} else {
+			sb.WriteString(fmt.Sprintf("❌ %s — %s: %v\n", status.Name, latency, status.Err))
+		}
+	}
+
+	// Текст ошибок может содержать символы разметки, поэтому без ParseMode
+	h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, sb.String()))
+}
+
+func (h *BotHandler) handleAdminCache(ctx context.Context, message *tgbotapi.Message) {
+	args := strings.TrimSpace(message.CommandArguments())
+	h.audit(ctx, message.From.ID, "cache", args)
+
+	if h.admin.Cache == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "❌ Хранилище курсов не поддерживает обслуживание."))
+		return
+	}
+
+	switch args {
+	case "":
+	case "flush":
+		if err := h.admin.Cache.Flush(ctx); err != nil {
+			logger.FromContext(ctx).Error("Failed to flush rates cache", zap.Error(err))
+			h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "❌ Не удалось очистить кэш."))
+			return
+		}
+		logger.FromContext(ctx).Info("Rates cache flushed by admin")
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "🧹 Кэш курсов очищен."))
+		return
+	default:
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "Использование: /cache или /cache flush"))
+		return
+	}
+
+	stats := h.admin.Cache.Stats()
+
+	size := "неизвестно"
+	if stats.Size >= 0 {
+		size = fmt.Sprintf("%d", stats.Size)
+		if stats.Capacity > 0 {
+			size += fmt.Sprintf(" из %d", stats.Capacity)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🗄 *Кэш курсов*\n\n")
+	sb.WriteString(fmt.Sprintf("Записей: %s\n", size))
+	sb.WriteString(fmt.Sprintf("Попадания: %.1f%% (%d из %d)\n",
+		stats.HitRatio()*100, stats.Hits, stats.Hits+stats.Misses))
+	sb.WriteString(fmt.Sprintf("Устаревшие ответы: %d\n", stats.StaleHits))
+	sb.WriteString(fmt.Sprintf("Вытеснения: %d\n", stats.Evictions))
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, sb.String())
+	msg.ParseMode = "Markdown"
+	h.sendMessage(ctx, msg)
+}
+
+func (h *BotHandler) handleAdminBans(ctx context.Context, message *tgbotapi.Message) {
+	h.audit(ctx, message.From.ID, "bans", "")
+
+	if h.throttler == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "Ограничение запросов отключено."))
+		return
+	}
+
+	bans, err := h.throttler.Bans(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get bans", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "❌ Не удалось получить список блокировок."))
+		return
+	}
+
+	if len(bans) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "✅ Заблокированных пользователей нет."))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🚫 Заблокированы (%d):\n\n", len(bans)))
+	for _, ban := range bans {
+		sb.WriteString(fmt.Sprintf("%d — %s, до %s UTC\n",
+			ban.UserID, ban.Reason, ban.BannedUntil.UTC().Format("15:04 02.01.2006")))
+	}
+	sb.WriteString("\nСнять блокировку: /unban <id>")
+
+	h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, sb.String()))
+}
+
+func (h *BotHandler) handleAdminUnban(ctx context.Context, message *tgbotapi.Message) {
+	userID, err := strconv.ParseInt(strings.TrimSpace(message.CommandArguments()), 10, 64)
+	if err != nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "Использование: /unban <id>"))
+		return
+	}
+
+	h.audit(ctx, message.From.ID, "unban", strconv.FormatInt(userID, 10))
+
+	if h.throttler == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "Ограничение запросов отключено."))
+		return
+	}
+
+	if err := h.throttler.Unban(ctx, userID); err != nil {
+		logger.FromContext(ctx).Error("Failed to unban user", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "❌ Не удалось снять блокировку."))
+		return
+	}
+
+	h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, fmt.Sprintf("✅ Блокировка %d снята.", userID)))
+}
+
+func (h *BotHandler) handleAdminBroadcast(ctx context.Context, message *tgbotapi.Message) {
+	text := strings.TrimSpace(message.CommandArguments())
+	if text == "" {
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "Использование: /broadcast <текст>"))
+		return
+	}
+
+	broadcast, err := h.admin.Broadcasts.Create(ctx, message.From.ID, text)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create broadcast", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "❌ Не удалось создать рассылку."))
+		return
+	}
+	h.audit(ctx, message.From.ID, "broadcast_draft", fmt.Sprintf("id=%d %s", broadcast.ID, text))
+
+	counts, err := h.users.Counts(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to count users", zap.Error(err))
+	}
+
+	// Предпросмотр без разметки: администратор видит текст так же, как получатели
+	preview := fmt.Sprintf("📣 Предпросмотр рассылки #%d\nПолучателей: %d\n\n%s",
+		broadcast.ID, counts.Total-counts.Blocked, text)
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, preview)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			h.button(ctx, "✅ Отправить", callbackBroadcast, "send", broadcast.ID),
+			h.button(ctx, "❌ Отменить", callbackBroadcast, "cancel", broadcast.ID),
+		),
+	)
+	h.sendMessage(ctx, msg)
+}
+
+// handleBroadcastCallback подтверждает или отменяет рассылку по кнопкам предпросмотра
+func (h *BotHandler) handleBroadcastCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data telegram.CallbackData) {
+	if !h.isAdmin(callback.From) {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	action := data.Arg(0)
+	id, err := data.Int64(1)
+	if err != nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+
+	var (
+		changed bool
+		status  string
+		markup  tgbotapi.InlineKeyboardMarkup
+	)
+
+	switch action {
+	case "send":
+		changed, err = h.admin.Broadcasts.SetStatus(ctx, id, entities.BroadcastSending, entities.BroadcastDraft)
+		status = "▶️ Отправка запущена"
+		markup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			h.button(ctx, "⏹ Остановить", callbackBroadcast, "cancel", id),
+		))
+	case "cancel":
+		changed, err = h.admin.Broadcasts.SetStatus(ctx, id, entities.BroadcastCancelled,
+			entities.BroadcastDraft, entities.BroadcastSending)
+		status = "⏹ Рассылка отменена"
+	default:
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to update broadcast", zap.Int64("broadcast_id", id), zap.Error(err))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "❌ Ошибка"))
+		return
+	}
+	if !changed {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "Рассылка уже завершена или отменена"))
+		return
+	}
+
+	h.audit(ctx, callback.From.ID, "broadcast_"+action, fmt.Sprintf("id=%d", id))
+	if action == "send" {
+		h.admin.Broadcaster.Notify()
+	}
+
+	edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID,
+		callback.Message.Text+"\n\n"+status)
+	if len(markup.InlineKeyboard) > 0 {
+		edit.ReplyMarkup = &markup
+	}
+	_, _ = h.sender.Send(ctx, edit)
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, status))
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/money"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// autoConvertCooldown — минимальный интервал между автоматическими ответами в одном чате
+	autoConvertCooldown = 30 * time.Second
+	// autoConvertRepeat — сколько одна и та же сумма не пересчитывается повторно
+	autoConvertRepeat = 10 * time.Minute
+	// maxAutoMentions ограничивает число сумм в одном ответе
+	maxAutoMentions = 3
+	// maxAutoTargets ограничивает число валют, в которые пересчитывается сумма
+	maxAutoTargets = 4
+	// maxAutoConvertEntries — при таком размере из памяти удаляются устаревшие записи
+	maxAutoConvertEntries = 10000
+)
+
+// autoConvertState хранит время последних ответов по чатам и уже пересчитанные суммы
+type autoConvertState struct {
+	mu        sync.Mutex
+	lastReply map[int64]time.Time
+	seen      map[string]time.Time
+}
+
+func newAutoConvertState() autoConvertState {
+	return autoConvertState{
+		lastReply: make(map[int64]time.Time),
+		seen:      make(map[string]time.Time),
+	}
+}
+
+// reserve отбирает еще не пересчитанные недавно упоминания и занимает интервал чата;
+// result — метка метрики, если отвечать не нужно
+func (s *autoConvertState) reserve(chatID int64, mentions []money.Mention, now time.Time) ([]money.Mention, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.seen) >= maxAutoConvertEntries {
+		for key, at := range s.seen {
+			if now.Sub(at) > autoConvertRepeat {
+				delete(s.seen, key)
+			}
+		}
+	}
+	if len(s.lastReply) >= maxAutoConvertEntries {
+		for id, at := range s.lastReply {
+			if now.Sub(at) > autoConvertCooldown {
+				delete(s.lastReply, id)
+			}
+		}
+	}
+
+	var fresh []money.Mention
+	for _, mention := range mentions {
+		key := fmt.Sprintf("%d:%s:%g", chatID, mention.Currency, mention.Amount)
+		if at, ok := s.seen[key]; ok && now.Sub(at) < autoConvertRepeat {
+			continue
+		}
+		fresh = append(fresh, mention)
+	}
+	if len(fresh) == 0 {
+		return nil, "repeat"
+	}
+	if now.Sub(s.lastReply[chatID]) < autoConvertCooldown {
+		return nil, "cooldown"
+	}
+
+	s.lastReply[chatID] = now
+	for _, mention := range fresh {
+		s.seen[fmt.Sprintf("%d:%s:%g", chatID, mention.Currency, mention.Amount)] = now
+	}
+	return fresh, ""
+}
+
+// autoConvert отвечает на обычное сообщение группы пересчетом упомянутых в нем сумм,
+// если в чате включен автоматический пересчет
+func (h *BotHandler) autoConvert(ctx context.Context, message *tgbotapi.Message) {
+	if !isGroup(message.Chat) || message.From == nil || message.From.IsBot {
+		return
+	}
+
+	settings, _ := h.chatSettings(ctx, message.Chat.ID)
+	targets := settings.AutoTargets()
+	if !settings.AutoConvert || len(targets) == 0 {
+		return
+	}
+
+	text := message.Text
+	if text == "" {
+		text = message.Caption
+	}
+	mentions := money.Find(text)
+	if len(mentions) == 0 {
+		return
+	}
+	if len(mentions) > maxAutoMentions {
+		mentions = mentions[:maxAutoMentions]
+	}
+
+	mentions, skipped := h.autoConverted.reserve(message.Chat.ID, mentions, time.Now())
+	if skipped != "" {
+		metrics.AutoConversionsTotal.WithLabelValues(skipped).Inc()
+		return
+	}
+
+	var lines []string
+	for _, mention := range mentions {
+		if line := h.autoConvertLine(ctx, mention, targets); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		metrics.AutoConversionsTotal.WithLabelValues("error").Inc()
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, strings.Join(lines, "\n"))
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableNotification = true
+	h.sendMessage(ctx, msg)
+	metrics.AutoConversionsTotal.WithLabelValues("sent").Inc()
+}
+
+// autoConvertLine печатает сумму в валютах чата: «4 500 KZT ≈ 812.35 RUB · 8.95 USD»
+func (h *BotHandler) autoConvertLine(ctx context.Context, mention money.Mention, targets []string) string {
+	var parts []string
+	for _, target := range targets {
+		if target == mention.Currency {
+			continue
+		}
+		if len(parts) == maxAutoTargets {
+			break
+		}
+
+		conversion, err := h.exchangeService.ConvertAmount(ctx, mention.Amount, mention.Currency, target)
+		if err != nil {
+			logger.FromContext(ctx).Debug("Auto conversion failed",
+				zap.String("from", mention.Currency), zap.String("to", target), zap.Error(err))
+			continue
+		}
+		parts = append(parts, formatMoney(conversion.Result)+" "+target)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("💱 %s %s ≈ %s", formatMoney(mention.Amount), mention.Currency, strings.Join(parts, " · "))
+}
+
+// formatMoney печатает сумму с разделителями разрядов и без копеек у целых: 4 500, 812.35
+func formatMoney(amount float64) string {
+	text := strconv.FormatFloat(amount, 'f', 2, 64)
+	text = strings.TrimSuffix(text, ".00")
+
+	integer, fraction, hasFraction := strings.Cut(text, ".")
+	var sb strings.Builder
+	for i, r := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteRune(' ')
+		}
+		sb.WriteRune(r)
+	}
+	if hasFraction {
+		sb.WriteString("." + fraction)
+	}
+	return sb.String()
+}
+
+// handleAutoConvertCommand включает или выключает пересчет упомянутых сумм: /autoconvert [on [валюты]|off]
+func (h *BotHandler) handleAutoConvertCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.chats == nil || !isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Автоматический пересчет настраивается только в группах."))
+		return
+	}
+
+	settings, _ := h.chatSettings(ctx, chatID)
+	args := strings.Fields(strings.ToUpper(message.CommandArguments()))
+
+	if len(args) == 0 {
+		text := "Автоматический пересчет выключен.\nИспользование: /autoconvert on RUB USD или /autoconvert off"
+		if settings.AutoConvert {
+			text = "Автоматический пересчет включен, валюты: " + strings.Join(settings.AutoTargets(), ", ")
+		}
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, text))
+		return
+	}
+
+	if !h.canConfigureChat(ctx, message) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Менять настройки группы могут только ее администраторы."))
+		return
+	}
+
+	var reply string
+	switch args[0] {
+	case "OFF":
+		settings.AutoConvert = false
+		reply = "✅ Автоматический пересчет выключен."
+	case "ON":
+		codes := args[1:]
+		if len(codes) > maxAutoTargets {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ Укажите не больше %d валют.", maxAutoTargets)))
+			return
+		}
+		for _, code := range codes {
+			if !isCurrencyCode(code) {
+				h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Неизвестный код валюты: "+code))
+				return
+			}
+		}
+		if len(codes) > 0 {
+			settings.AutoCurrencies = strings.Join(codes, ",")
+		}
+		if len(settings.AutoTargets()) == 0 {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+				"❌ Укажите валюты для пересчета: /autoconvert on RUB USD или задайте /defaults."))
+			return
+		}
+		settings.AutoConvert = true
+		// Обычные сообщения группы приходят боту, только если у него отключен режим приватности
+		reply = "✅ Суммы в сообщениях будут пересчитываться в " + strings.Join(settings.AutoTargets(), ", ") +
+			". Бот видит обычные сообщения группы, только если у него отключен режим приватности."
+	default:
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Использование: /autoconvert on RUB USD или /autoconvert off"))
+		return
+	}
+
+	settings.UpdatedBy = message.From.ID
+	if err := h.saveChatSettings(ctx, settings); err != nil {
+		logger.FromContext(ctx).Error("Failed to save auto conversion settings", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось сохранить настройки."))
+		return
+	}
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, reply))
+}
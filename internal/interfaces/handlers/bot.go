@@ -3,71 +3,250 @@ package handlers
 import (
 	"context"
 	"fmt"
-	"log"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
 	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	"github.com/crocxdued/currency-telegram-bot/internal/tracing"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
 	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
 )
 
 type BotHandler struct {
-	bot             *tgbotapi.BotAPI
+	sender          *telegram.Sender
 	exchangeService services.ExchangeService
 	favoritesRepo   services.FavoritesRepository
-	userStates      map[int64]string
+	userStates      dialogStates
+	users           services.UsersRepository
+	throttler       *services.Throttler
+	callbacks       *telegram.CallbackCodec
+
+	// self и mention нужны, чтобы узнавать обращения к боту в группах
+	self          tgbotapi.User
+	mention       *regexp.Regexp
+	chats         services.ChatSettingsRepository
+	settingsCache chatSettingsCache
+	autoConverted autoConvertState
+
+	expenses services.ExpensesRepository
+	debts    services.DebtsRepository
+	holdings services.HoldingsRepository
+	exports  exportState
+
+	conversions      services.ConversionsRepository
+	historyRetention time.Duration
+	userData         *services.UserDataRegistry
+
+	admins map[int64]bool
+	admin  AdminDeps
+}
+
+// Option настраивает BotHandler
+type Option func(*BotHandler)
+
+// WithSender задает общий для приложения отправитель с лимитами Telegram
+func WithSender(sender *telegram.Sender) Option {
+	return func(h *BotHandler) {
+		h.sender = sender
+	}
+}
+
+// WithUsers включает учет пользователей: время обращений и блокировку бота
+func WithUsers(users services.UsersRepository) Option {
+	return func(h *BotHandler) {
+		h.users = users
+	}
 }
 
 func NewBotHandler(
 	bot *tgbotapi.BotAPI,
 	exchangeService services.ExchangeService,
 	favoritesRepo services.FavoritesRepository,
+	opts ...Option,
 ) *BotHandler {
-	return &BotHandler{
-		bot:             bot,
+	h := &BotHandler{
+		sender:          telegram.NewSender(bot),
 		exchangeService: exchangeService,
 		favoritesRepo:   favoritesRepo,
-		userStates:      make(map[int64]string),
+		userStates:      newDialogStates(),
+		admins:          make(map[int64]bool),
+		self:            bot.Self,
+		settingsCache:   chatSettingsCache{entries: make(map[int64]chatSettingsEntry)},
+		autoConverted:   newAutoConvertState(),
+		exports:         newExportState(),
+	}
+	if bot.Self.UserName != "" {
+		h.mention = regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(bot.Self.UserName) + `\b`)
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	if h.callbacks == nil {
+		h.callbacks = telegram.NewCallbackCodec([]byte(bot.Token))
+	}
+	return h
 }
 
 // HandleUpdate обрабатывает входящие сообщения
-func (h *BotHandler) HandleUpdate(update tgbotapi.Update) {
+func (h *BotHandler) HandleUpdate(ctx context.Context, update tgbotapi.Update) {
+	updateType, command := updateLabels(update)
+
+	ctx, span := tracing.Start(ctx, "telegram.update",
+		attribute.Int("telegram.update_id", update.UpdateID),
+		attribute.String("telegram.update_type", updateType),
+		attribute.String("telegram.command", command),
+	)
+	ctx = logger.With(ctx, updateFields(update, updateType, command)...)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		ctx = logger.With(ctx, zap.String("trace_id", traceID))
+	}
+
+	start := time.Now()
+	defer func() {
+		latency := time.Since(start)
+		metrics.UpdatesTotal.WithLabelValues(updateType, command).Inc()
+		metrics.HandlerDuration.WithLabelValues(updateType, command).Observe(latency.Seconds())
+		logger.FromContext(ctx).Info("Update handled", zap.Duration("latency", latency))
+		span.End()
+	}()
+
+	// В группах бот видит и чужие разговоры: они не учитываются и не ограничиваются,
+	// а суммы в них пересчитываются, если это включено в чате
+	if update.Message != nil {
+		message, ok := h.addressedMessage(ctx, update.Message)
+		if !ok {
+			h.autoConvert(ctx, update.Message)
+			return
+		}
+		update.Message = message
+	}
+
+	// Ограничение проверяется до записи в базу: отклоненный флуд не должен стоить запроса к Postgres
+	if !h.allowUpdate(ctx, update) {
+		return
+	}
+
+	h.touchUser(ctx, update)
+
 	if update.Message != nil {
-		h.handleMessage(update.Message)
+		h.handleMessage(ctx, update.Message)
 	} else if update.CallbackQuery != nil {
-		h.handleCallback(update.CallbackQuery)
+		h.handleCallback(ctx, update.CallbackQuery)
+	}
+}
+
+// touchUser отмечает обращение пользователя; ошибка учета не мешает ответу
+func (h *BotHandler) touchUser(ctx context.Context, update tgbotapi.Update) {
+	if h.users == nil {
+		return
+	}
+
+	var user *tgbotapi.User
+	switch {
+	case update.Message != nil:
+		user = update.Message.From
+	case update.CallbackQuery != nil:
+		user = update.CallbackQuery.From
+	}
+	if user == nil || user.IsBot {
+		return
+	}
+
+	if err := h.users.Touch(ctx, user.ID, user.UserName); err != nil {
+		logger.FromContext(ctx).Warn("Failed to touch user", zap.Error(err))
 	}
 }
 
 // handleMessage обрабатывает текстовые сообщения
-func (h *BotHandler) handleMessage(message *tgbotapi.Message) {
+func (h *BotHandler) handleMessage(ctx context.Context, message *tgbotapi.Message) {
+	if message.From == nil {
+		return
+	}
 	text := message.Text
 
+	if h.isAdmin(message.From) && h.handleAdminCommand(ctx, message) {
+		return
+	}
+
+	// Состояние действует до следующего сообщения пользователя в этом чате
+	key := stateKey{message.Chat.ID, message.From.ID}
+	if state, ok := h.userStates.take(key); ok {
+		// Ответ на запрос подписи или суммы из настроек избранного
+		if strings.HasPrefix(state, "fav_") {
+			if text == "/cancel" {
+				h.sendMessage(ctx, tgbotapi.NewMessage(message.Chat.ID, "Изменение отменено."))
+				return
+			}
+			if !message.IsCommand() && buttonCommands[text] == "" {
+				h.handleFavoriteInput(ctx, message, state)
+				return
+			}
+		}
+	}
+
 	if strings.HasPrefix(text, "/fav_") {
-		h.handleAddFavorite(message)
+		h.handleAddFavorite(ctx, message)
 		return
 	}
 
 	switch text {
 	case "/start":
-		h.handleStart(message)
+		h.handleStart(ctx, message)
 	case "/help", "ℹ️ Помощь":
-		h.handleHelp(message)
+		h.handleHelp(ctx, message)
 	case "💱 Конвертировать":
-		h.handleConvert(message)
-	case "⭐ Избранное":
-		h.handleFavorites(message)
-	case "📊 Курсы валют":
-		h.handleRates(message)
+		h.handleConvert(ctx, message)
+	case "/favorites", "⭐ Избранное":
+		h.handleFavorites(ctx, message)
+	case "/rates", "📊 Курсы валют":
+		h.handleRates(ctx, message)
 	default:
-		h.handleText(message)
+		switch {
+		case message.IsCommand() && message.Command() == "defaults":
+			h.handleDefaultsCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "trigger":
+			h.handleTriggerCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "autoconvert":
+			h.handleAutoConvertCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "trip":
+			h.handleTripCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "spent":
+			h.handleSpentCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "expenses":
+			h.handleExpensesCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "report":
+			h.handleReportCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "split":
+			h.handleSplitCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "settle":
+			h.handleSettleCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "currency":
+			h.handleCurrencyCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "portfolio":
+			h.handlePortfolioCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "history":
+			h.handleHistoryCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "export":
+			h.handleExportCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "mydata":
+			h.handleMyDataCommand(ctx, message)
+		case message.IsCommand() && message.Command() == "forgetme":
+			h.handleForgetMeCommand(ctx, message)
+		default:
+			h.handleText(ctx, message)
+		}
 	}
 }
 
 // handleStart приветственное сообщение
-func (h *BotHandler) handleStart(message *tgbotapi.Message) {
+func (h *BotHandler) handleStart(ctx context.Context, message *tgbotapi.Message) {
 	msg := tgbotapi.NewMessage(message.Chat.ID, `
 🤖 *Currency Exchange Bot*
 
@@ -83,56 +262,67 @@ func (h *BotHandler) handleStart(message *tgbotapi.Message) {
 
 Используйте кнопки ниже или введите запрос вручную!`)
 	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = telegram.CreateMainKeyboard()
+	// В группе постоянная клавиатура мешала бы всем участникам
+	if !isGroup(message.Chat) {
+		msg.ReplyMarkup = telegram.CreateMainKeyboard()
+	}
 
-	h.sendMessage(msg)
+	h.sendMessage(ctx, msg)
 }
 
 // handleConvert начинает процесс конвертации
-func (h *BotHandler) handleConvert(message *tgbotapi.Message) {
+func (h *BotHandler) handleConvert(ctx context.Context, message *tgbotapi.Message) {
 	msg := tgbotapi.NewMessage(message.Chat.ID, "Введите запрос в формате:\n`100 USD to RUB`\nили\n`EUR/RUB`")
 	msg.ParseMode = "Markdown"
 
-	h.sendMessage(msg)
-	h.userStates[message.Chat.ID] = "converting"
+	h.sendMessage(ctx, msg)
+	h.userStates.set(stateKey{message.Chat.ID, message.From.ID}, "converting")
 }
 
 // handleText обрабатывает произвольный текст для конвертации
-func (h *BotHandler) handleText(message *tgbotapi.Message) {
-	text := strings.TrimSpace(message.Text)
-	userID := message.Chat.ID
-
-	result, err := h.parseAndConvert(userID, text)
+func (h *BotHandler) handleText(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	settings, _ := h.chatSettings(ctx, chatID)
+
+	query, err := parseQuery(message.Text, settings)
+	var conversion entities.Conversion
+	if err == nil {
+		conversion, err = h.convertQuery(ctx, query)
+	}
 	if err != nil {
-		msg := tgbotapi.NewMessage(userID, "❌ "+err.Error())
+		msg := tgbotapi.NewMessage(chatID, "❌ "+err.Error())
 		msg.ParseMode = "Markdown"
-		h.sendMessage(msg)
+		h.replyInGroup(&msg, message)
+		h.sendMessage(ctx, msg)
 		return
 	}
 
-	cleanText := strings.ToUpper(text)
-	parts := strings.Fields(strings.ReplaceAll(cleanText, "/", " "))
-	var currs []string
-	for _, p := range parts {
-
-		if _, err := strconv.ParseFloat(p, 64); err != nil && len(p) == 3 {
-			currs = append(currs, p)
-		}
-	}
-
-	msg := tgbotapi.NewMessage(userID, result)
+	msg := tgbotapi.NewMessage(chatID, formatConversion(query, conversion))
 	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = h.createConversionKeyboard(ctx, query.From, query.To)
+	h.replyInGroup(&msg, message)
 
-	if len(currs) >= 2 {
-		msg.ReplyMarkup = h.createConversionKeyboard(currs[0], currs[1])
+	h.sendMessage(ctx, msg)
+	h.recordConversion(ctx, message.From.ID, chatID, message.Text, query, conversion)
+}
+
+// replyInGroup привязывает ответ к запросу, чтобы в группе было видно, кому бот отвечает
+func (h *BotHandler) replyInGroup(msg *tgbotapi.MessageConfig, message *tgbotapi.Message) {
+	if isGroup(message.Chat) {
+		msg.ReplyToMessageID = message.MessageID
 	}
+}
 
-	h.sendMessage(msg)
+// conversionQuery — разобранный запрос конвертации
+type conversionQuery struct {
+	Amount   float64
+	From, To string
 }
 
-// parseAndConvert парсит и выполняет конвертацию
-func (h *BotHandler) parseAndConvert(_ int64, text string) (string, error) {
-	ctx := context.Background()
+// parseQuery разбирает запрос вида «100 USD to RUB»; недостающие валюты берутся из настроек чата:
+// «100» — из валюты по умолчанию в целевую, «100 EUR» — из EUR в целевую, а если указана сама
+// целевая валюта — обратно в исходную
+func parseQuery(text string, settings entities.ChatSettings) (conversionQuery, error) {
 	text = strings.ToUpper(strings.TrimSpace(text))
 	text = strings.ReplaceAll(text, "/", " ")
 	text = strings.ReplaceAll(text, ",", ".")
@@ -156,27 +346,97 @@ func (h *BotHandler) parseAndConvert(_ int64, text string) (string, error) {
 		}
 	}
 
+	if len(currencies) < 2 && settings.HasDefaults() {
+		switch {
+		case len(currencies) == 0:
+			currencies = []string{settings.DefaultFrom, settings.DefaultTo}
+		case currencies[0] == settings.DefaultTo:
+			currencies = append(currencies, settings.DefaultFrom)
+		default:
+			currencies = append(currencies, settings.DefaultTo)
+		}
+	}
+
 	if len(currencies) < 2 {
-		return "", fmt.Errorf("нужно 2 валюты (напр. USD RUB)")
+		return conversionQuery{}, fmt.Errorf("нужно 2 валюты (напр. USD RUB)")
 	}
 
-	from, to := currencies[0], currencies[1]
-	converted, err := h.exchangeService.ConvertAmount(ctx, amount, from, to)
+	return conversionQuery{Amount: amount, From: currencies[0], To: currencies[1]}, nil
+}
+
+// convertQuery пересчитывает сумму запроса по текущему курсу
+func (h *BotHandler) convertQuery(ctx context.Context, query conversionQuery) (entities.Conversion, error) {
+	conversion, err := h.exchangeService.ConvertAmount(ctx, query.Amount, query.From, query.To)
 	if err != nil {
-		return "", err
+		return entities.Conversion{}, err
 	}
 
+	logger.FromContext(ctx).Debug("Conversion done",
+		zap.String("from", query.From),
+		zap.String("to", query.To),
+		zap.String("provider", conversion.Rate.Provider),
+		zap.Bool("from_cache", conversion.Rate.FromCache),
+		zap.Bool("stale", conversion.Rate.Stale),
+	)
+	return conversion, nil
+}
+
+// formatConversion печатает результат конвертации в Markdown
+func formatConversion(query conversionQuery, conversion entities.Conversion) string {
+	amount, from, to := query.Amount, query.From, query.To
+
 	var sb strings.Builder
 	sb.WriteString("💎 *Результат обмена*\n\n") // Ошибка S1039 исправлена (убран fmt.Sprintf)
 	sb.WriteString(fmt.Sprintf("📤 *Отдаете:* %.2f %s\n", amount, from))
-	sb.WriteString(fmt.Sprintf("📥 *Получаете:* %.2f %s\n", converted, to))
+	sb.WriteString(fmt.Sprintf("📥 *Получаете:* %.2f %s\n", conversion.Result, to))
 	sb.WriteString("───\n")
-	sb.WriteString(fmt.Sprintf("📊 *Курс:* 1 %s = %.4f %s", from, converted/amount, to))
+	sb.WriteString(fmt.Sprintf("📊 *Курс:* 1 %s = %.4f %s\n", from, conversion.Rate.Rate, to))
+	if conversion.Rate.Stale {
+		sb.WriteString(staleWarning + "\n")
+	}
+	sb.WriteString(formatRateSources([]entities.ExchangeRate{conversion.Rate}))
+
+	return sb.String()
+}
+
+// staleWarning предупреждает, что показан последний известный курс
+const staleWarning = "⚠️ *Курс устарел:* источники недоступны, показано последнее известное значение"
+
+// formatRateSources формирует подпись с источником и датой публикации курсов
+func formatRateSources(rates []entities.ExchangeRate) string {
+	var sources []string
+	seen := make(map[string]bool)
 
-	return sb.String(), nil
+	for _, rate := range rates {
+		source := rate.Provider
+		if !rate.LastUpdated.IsZero() {
+			source += ", курс на " + rate.LastUpdated.Format("02.01.2006")
+		}
+		if rate.Stale {
+			source += " (устаревший)"
+		} else if rate.FromCache {
+			source += " (из кэша)"
+		}
+
+		if !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+
+	if len(sources) == 0 {
+		return ""
+	}
+
+	label := "Источник"
+	if len(sources) > 1 {
+		label = "Источники"
+	}
+
+	return fmt.Sprintf("🏦 _%s: %s_", label, strings.Join(sources, "; "))
 }
 
-func (h *BotHandler) handleHelp(message *tgbotapi.Message) {
+func (h *BotHandler) handleHelp(ctx context.Context, message *tgbotapi.Message) {
 	msg := tgbotapi.NewMessage(message.Chat.ID, `
 *📖 Справка по использованию бота*
 
@@ -190,54 +450,43 @@ func (h *BotHandler) handleHelp(message *tgbotapi.Message) {
 • 50.5 EUR USD
 
 *Избранное:*
-Добавляйте часто используемые пары в избранное для быстрого доступа!`)
-	msg.ParseMode = "Markdown"
+Добавляйте часто используемые пары в избранное для быстрого доступа!
+В списке можно менять порядок, закреплять пары, задавать подписи и сумму для пересчета в одно нажатие.
 
-	h.sendMessage(msg)
-}
+*Портфель:*
+/portfolio add 1000 USD — добавить наличные, /portfolio — сколько они стоят сегодня и как изменились за день
 
-// handleFavorites показывает избранное пользователя
-func (h *BotHandler) handleFavorites(message *tgbotapi.Message) {
-	userID := message.Chat.ID
-	ctx := context.Background()
+*История:*
+/history — последние конвертации с повтором по сегодняшнему курсу, /history clear — удалить историю
 
-	favorites, err := h.favoritesRepo.GetUserFavorites(ctx, userID)
-	if err != nil {
-		log.Printf("Error getting favorites: %v", err)
-		h.sendMessage(tgbotapi.NewMessage(userID, "❌ Не удалось загрузить список избранного."))
-		return
-	}
+*Выгрузка:*
+/export favorites xlsx — избранное файлом, /export rates USD RUB 01.01.2025 31.03.2025 — курсы за период в CSV, /export history — история конвертаций
 
-	if len(favorites) == 0 {
-		msg := tgbotapi.NewMessage(userID, "🌟 У вас пока нет избранных пар.\n\nЧтобы добавить, отправьте команду: `/fav_USD_RUB` или воспользуйтесь кнопкой «В избранное» после конвертации.")
-		msg.ParseMode = "Markdown"
-		h.sendMessage(msg)
-		return
-	}
+*Ваши данные:*
+/mydata — файл со всем, что бот о вас хранит, /forgetme — удалить все ваши данные
 
-	var rows [][]tgbotapi.InlineKeyboardButton
-	for _, fav := range favorites {
-		pairText := fmt.Sprintf("%s/%s", fav.FromCurrency, fav.ToCurrency)
+*Расходы в поездке:*
+/trip new Алматы RUB — начать поездку, /trip join — присоединиться
+/spent 3500 KZT такси — записать расход (можно добавить «вчера» или дату 14.03)
+/report — итоги по категориям и участникам по курсу на дату каждого расхода
 
-		// Создаем строку из двух кнопок:
-		// Первая кнопка — сама пара (при нажатии сработает как текст "USD/RUB")
-		// Вторая кнопка — корзина (триггерит удаление через callback)
-		row := tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(pairText, pairText),
-			tgbotapi.NewInlineKeyboardButtonData("🗑️", fmt.Sprintf("remfav_%s_%s", fav.FromCurrency, fav.ToCurrency)),
-		)
-		rows = append(rows, row)
-	}
+*Общие счета в группе:*
+/split 12000 KZT @anna @boris ужин — разделить счет поровну между вами и упомянутыми
+/settle — кто кому сколько переводит, чтобы рассчитаться; /currency USD — в какой валюте вам показывать суммы
+
+*Валюты по умолчанию:*
+/defaults USD RUB — тогда «100» считается как 100 USD в RUB
 
-	msg := tgbotapi.NewMessage(userID, "⭐ *Ваши избранные пары:*\nНажмите на пару для быстрого расчета или на корзину для удаления.")
+*В группах:*
+Бот отвечает на команды, упоминания и ответы на свои сообщения. Избранное у каждого участника свое.
+Администраторы группы задают валюты по умолчанию (/defaults) и шаблон вызова без упоминания (/trigger).
+/autoconvert on RUB USD — пересчитывать суммы, упомянутые в разговоре: «ужин 4500 тенге», «$20».`)
 	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
 
-	h.sendMessage(msg)
+	h.sendMessage(ctx, msg)
 }
 
-func (h *BotHandler) handleRates(message *tgbotapi.Message) {
-	ctx := context.Background()
+func (h *BotHandler) handleRates(ctx context.Context, message *tgbotapi.Message) {
 	pairs := [][2]string{
 		{"USD", "RUB"},
 		{"EUR", "RUB"},
@@ -251,181 +500,201 @@ func (h *BotHandler) handleRates(message *tgbotapi.Message) {
 	var ratesText strings.Builder
 	ratesText.WriteString("📊 *Текущие курсы:*\n\n")
 
-	found := false
+	var rates []entities.ExchangeRate
+	stale := false
 	for _, pair := range pairs {
 		rate, err := h.exchangeService.GetRate(ctx, pair[0], pair[1])
 		if err != nil {
-			log.Printf("LOG: Ошибка для %s/%s: %v", pair[0], pair[1], err)
+			logger.FromContext(ctx).Warn("Failed to get rate",
+				zap.String("from", pair[0]), zap.String("to", pair[1]), zap.Error(err))
 			continue
 		}
-		found = true
-		ratesText.WriteString(fmt.Sprintf("💱 *%s/%s:* %.4f\n", pair[0], pair[1], rate))
+		rates = append(rates, rate)
+
+		marker := ""
+		if rate.Stale {
+			marker = " ⚠️"
+			stale = true
+		}
+		ratesText.WriteString(fmt.Sprintf("💱 *%s/%s:* %.4f%s\n", pair[0], pair[1], rate.Rate, marker))
 	}
 
-	if !found {
+	if len(rates) == 0 {
 		ratesText.WriteString("❌ Сервисы временно недоступны.")
+	} else {
+		if stale {
+			ratesText.WriteString("\n" + staleWarning)
+		}
+		ratesText.WriteString("\n" + formatRateSources(rates))
 	}
 
 	msg := tgbotapi.NewMessage(message.Chat.ID, ratesText.String())
 	msg.ParseMode = "Markdown"
-	h.sendMessage(msg)
+	h.sendMessage(ctx, msg)
 }
 
 // handleCallback обрабатывает нажатия на инлайн-кнопки
-func (h *BotHandler) handleCallback(callback *tgbotapi.CallbackQuery) {
-	data := callback.Data
+func (h *BotHandler) handleCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) {
+	data, ok := h.decodeCallback(ctx, callback)
+	if !ok {
+		return
+	}
+	// У кнопок inline-режима и слишком старых сообщений Telegram не присылает сообщение
+	if callback.Message == nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+
 	userID := callback.Message.Chat.ID
 	messageID := callback.Message.MessageID
 
-	if strings.Contains(data, "/") {
-
-		cleanData := data
-		if idx := strings.Index(data, "_"); idx != -1 && !strings.HasPrefix(data, "conv_") {
-
-			cleanData = data[idx+1:]
+	switch data.Action {
+	case callbackBroadcast:
+		h.handleBroadcastCallback(ctx, callback, data)
+		return
+	case callbackFavorite:
+		h.handleFavoriteCallback(ctx, callback, data)
+		return
+	case callbackDeleteExpense:
+		h.handleDeleteExpenseCallback(ctx, callback, data)
+		return
+	case callbackSplit:
+		h.handleSplitCallback(ctx, callback, data)
+		return
+	case callbackSettle:
+		h.handleSettleCallback(ctx, callback, data)
+		return
+	case callbackHistory:
+		h.handleHistoryCallback(ctx, callback, data)
+		return
+	case callbackForget:
+		h.handleForgetCallback(ctx, callback, data)
+		return
+	case callbackConvert:
+		amount, err := data.Float(0)
+		from, to := data.Arg(1), data.Arg(2)
+		if err != nil || from == "" || to == "" {
+			break
 		}
 
-		result, err := h.parseAndConvert(userID, cleanData)
+		query := conversionQuery{Amount: amount, From: from, To: to}
+		conversion, err := h.convertQuery(ctx, query)
 		if err != nil {
-			_, _ = h.bot.Request(tgbotapi.NewCallback(callback.ID, "❌ "+err.Error()))
+			_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "Ошибка"))
 			return
 		}
 
-		msg := tgbotapi.NewMessage(userID, result)
-		msg.ParseMode = "Markdown"
+		editMsg := tgbotapi.NewEditMessageText(userID, messageID, formatConversion(query, conversion))
+		editMsg.ParseMode = "Markdown"
+		kb := h.createConversionKeyboard(ctx, from, to)
+		editMsg.ReplyMarkup = &kb
 
-		parts := strings.Split(cleanData, "/")
-		if len(parts) == 2 {
-			msg.ReplyMarkup = h.createConversionKeyboard(parts[0], parts[1])
-		}
-
-		h.sendMessage(msg)
-		_, _ = h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+		_, _ = h.sender.Send(ctx, editMsg)
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
 		return
-	}
-
-	if strings.HasPrefix(data, "conv_") {
-		parts := strings.Split(data, "_")
-		if len(parts) == 4 {
-			amountStr := parts[1]
-			from := parts[2]
-			to := parts[3]
-
-			result, err := h.parseAndConvert(userID, fmt.Sprintf("%s %s %s", amountStr, from, to))
-			if err != nil {
-				_, _ = h.bot.Request(tgbotapi.NewCallback(callback.ID, "Ошибка"))
-				return
-			}
-
-			editMsg := tgbotapi.NewEditMessageText(userID, messageID, result)
-			editMsg.ParseMode = "Markdown"
-			kb := h.createConversionKeyboard(from, to)
-			editMsg.ReplyMarkup = &kb
-
-			_, _ = h.bot.Send(editMsg)
-			_, _ = h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
-			return
+	case callbackAddFavorite:
+		from, to := data.Arg(0), data.Arg(1)
+		if from == "" || to == "" {
+			break
 		}
-	}
-
-	if strings.HasPrefix(data, "addfav_") {
-		parts := strings.Split(data, "_")
-		if len(parts) == 3 {
-			from := parts[1]
-			to := parts[2]
-			ctx := context.Background()
 
-			err := h.favoritesRepo.AddFavorite(ctx, userID, from, to)
+		err := h.favoritesRepo.AddFavorite(ctx, callback.From.ID, from, to)
 
-			var callbackText string
-			if err != nil {
-				callbackText = "❌ Ошибка при сохранении"
-			} else {
-				callbackText = fmt.Sprintf("✅ Пара %s/%s добавлена в избранное!", from, to)
-			}
-
-			callbackCfg := tgbotapi.NewCallback(callback.ID, callbackText)
-			_, _ = h.bot.Request(callbackCfg)
-			return
+		var callbackText string
+		if err != nil {
+			callbackText = "❌ Ошибка при сохранении"
+		} else {
+			callbackText = fmt.Sprintf("✅ Пара %s/%s добавлена в избранное!", from, to)
 		}
-	}
 
-	if strings.HasPrefix(data, "remfav_") {
-		parts := strings.Split(data, "_")
-		if len(parts) == 3 {
-			from, to := parts[1], parts[2]
+		callbackCfg := tgbotapi.NewCallback(callback.ID, callbackText)
+		_, _ = h.sender.Request(ctx, callbackCfg)
+		return
+	case callbackRemoveFavorite:
+		from, to := data.Arg(0), data.Arg(1)
+		if from == "" || to == "" {
+			break
+		}
 
-			err := h.favoritesRepo.RemoveFavorite(context.Background(), userID, from, to)
+		err := h.favoritesRepo.RemoveFavorite(ctx, callback.From.ID, from, to)
 
-			var text string
-			if err != nil {
-				text = "❌ Ошибка при удалении"
-			} else {
-				text = fmt.Sprintf("🗑️ %s/%s удалено из избранного", from, to)
-			}
-
-			callbackCfg := tgbotapi.NewCallback(callback.ID, text)
-			_, _ = h.bot.Request(callbackCfg)
-			return
+		var text string
+		if err != nil {
+			text = "❌ Ошибка при удалении"
+		} else {
+			text = fmt.Sprintf("🗑️ %s/%s удалено из избранного", from, to)
 		}
+
+		callbackCfg := tgbotapi.NewCallback(callback.ID, text)
+		_, _ = h.sender.Request(ctx, callbackCfg)
+		return
 	}
 
-	_, _ = h.bot.Request(tgbotapi.NewCallback(callback.ID, ""))
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
 }
 
 // sendMessage отправляет сообщение с обработкой ошибок
-func (h *BotHandler) sendMessage(msg tgbotapi.MessageConfig) {
-	if _, err := h.bot.Send(msg); err != nil {
-		log.Printf("Error sending message: %v", err)
+func (h *BotHandler) sendMessage(ctx context.Context, msg tgbotapi.MessageConfig) {
+	_, span := tracing.StartClient(ctx, "telegram.sendMessage", attribute.Int64("telegram.chat_id", msg.ChatID))
+	_, err := h.sender.Send(ctx, msg)
+	tracing.Finish(span, err)
+
+	if err != nil {
+		metrics.TelegramSendFailuresTotal.WithLabelValues("sendMessage").Inc()
+		logger.FromContext(ctx).Error("Failed to send message", zap.Error(err))
+
+		if telegram.IsBlocked(err) && h.users != nil && msg.ChatID > 0 {
+			if err := h.users.MarkBlocked(ctx, msg.ChatID); err != nil {
+				logger.FromContext(ctx).Warn("Failed to mark user blocked", zap.Error(err))
+			}
+		}
 	}
 }
 
-func (h *BotHandler) handleAddFavorite(message *tgbotapi.Message) {
+func (h *BotHandler) handleAddFavorite(ctx context.Context, message *tgbotapi.Message) {
 
 	parts := strings.Split(message.Text, "_")
 
 	if len(parts) < 3 {
 		msg := tgbotapi.NewMessage(message.Chat.ID, "❌ Неверный формат. Используйте: /fav_USD_RUB")
-		h.sendMessage(msg)
+		h.sendMessage(ctx, msg)
 		return
 	}
 
 	fromCurrency := strings.ToUpper(strings.TrimSpace(parts[1]))
 	toCurrency := strings.ToUpper(strings.TrimSpace(parts[2]))
-
-	ctx := context.Background()
-	err := h.favoritesRepo.AddFavorite(ctx, message.Chat.ID, fromCurrency, toCurrency)
+	err := h.favoritesRepo.AddFavorite(ctx, message.From.ID, fromCurrency, toCurrency)
 	if err != nil {
 
-		log.Printf("Error adding favorite: %v", err)
+		logger.FromContext(ctx).Error("Failed to add favorite", zap.Error(err))
 
 		msg := tgbotapi.NewMessage(message.Chat.ID, "❌ Не удалось сохранить пару в избранное.")
-		h.sendMessage(msg)
+		h.sendMessage(ctx, msg)
 		return
 	}
 
 	successText := fmt.Sprintf("✅ Пара *%s/%s* добавлена в ваше избранное!", fromCurrency, toCurrency)
 	msg := tgbotapi.NewMessage(message.Chat.ID, successText)
 	msg.ParseMode = "Markdown"
-	h.sendMessage(msg)
+	h.sendMessage(ctx, msg)
 }
 
-func (h *BotHandler) createConversionKeyboard(from, to string) tgbotapi.InlineKeyboardMarkup {
+func (h *BotHandler) createConversionKeyboard(ctx context.Context, from, to string) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("10 "+from, fmt.Sprintf("conv_10_%s_%s", from, to)),
-			tgbotapi.NewInlineKeyboardButtonData("100 "+from, fmt.Sprintf("conv_100_%s_%s", from, to)),
+			h.button(ctx, "10 "+from, callbackConvert, 10, from, to),
+			h.button(ctx, "100 "+from, callbackConvert, 100, from, to),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("500 "+from, fmt.Sprintf("conv_500_%s_%s", from, to)),
-			tgbotapi.NewInlineKeyboardButtonData("1000 "+from, fmt.Sprintf("conv_1000_%s_%s", from, to)),
+			h.button(ctx, "500 "+from, callbackConvert, 500, from, to),
+			h.button(ctx, "1000 "+from, callbackConvert, 1000, from, to),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🔄 Обратный курс ("+to+"/"+from+")", fmt.Sprintf("conv_1_%s_%s", to, from)),
+			h.button(ctx, "🔄 Обратный курс ("+to+"/"+from+")", callbackConvert, 1, to, from),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("⭐ Добавить "+from+"/"+to+" в избранное", fmt.Sprintf("addfav_%s_%s", from, to)),
-			tgbotapi.NewInlineKeyboardButtonData("🗑️ Удалить", fmt.Sprintf("remfav_%s_%s", from, to)),
+			h.button(ctx, "⭐ Добавить "+from+"/"+to+" в избранное", callbackAddFavorite, from, to),
+			h.button(ctx, "🗑️ Удалить", callbackRemoveFavorite, from, to),
 		),
 	)
 }
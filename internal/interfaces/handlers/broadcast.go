@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// broadcastInterval держит рассылку ниже глобального лимита Telegram в 30 сообщений
+	// в секунду, оставляя запас для ответов пользователям; каждому чату уходит одно сообщение
+	broadcastInterval = 50 * time.Millisecond
+	// broadcastBatchSize — сколько получателей доставляется под одной блокировкой
+	broadcastBatchSize = 100
+	// broadcastLockTTL должен перекрывать доставку одной пачки
+	broadcastLockTTL = time.Minute
+	// broadcastPollInterval — как часто проверять рассылки, запущенные другим экземпляром
+	broadcastPollInterval = time.Minute
+)
+
+// Locker захватывает общую блокировку между экземплярами бота
+type Locker interface {
+	Lock(ctx context.Context, key string, ttl time.Duration) (release func(), ok bool)
+}
+
+// Broadcaster доставляет подтвержденные рассылки в фоне. Прогресс сохраняется
+// после каждого сообщения, поэтому после перезапуска доставка продолжается с места остановки.
+type Broadcaster struct {
+	sender     *telegram.Sender
+	users      services.UsersRepository
+	broadcasts services.BroadcastRepository
+	locker     Locker
+	wake       chan struct{}
+}
+
+func NewBroadcaster(
+	sender *telegram.Sender,
+	users services.UsersRepository,
+	broadcasts services.BroadcastRepository,
+	locker Locker,
+) *Broadcaster {
+	return &Broadcaster{
+		sender:     sender,
+		users:      users,
+		broadcasts: broadcasts,
+		locker:     locker,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Notify будит доставку после подтверждения рассылки
+func (b *Broadcaster) Notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run доставляет незавершенные рассылки до отмены контекста
+func (b *Broadcaster) Run(ctx context.Context) {
+	ticker := time.NewTicker(broadcastPollInterval)
+	defer ticker.Stop()
+
+	for {
+		b.deliverPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b *Broadcaster) deliverPending(ctx context.Context) {
+	pending, err := b.broadcasts.Sending(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to load pending broadcasts", zap.Error(err))
+		return
+	}
+
+	for _, broadcast := range pending {
+		b.deliver(logger.With(ctx, zap.Int64("broadcast_id", broadcast.ID)), broadcast.ID)
+	}
+}
+
+func (b *Broadcaster) deliver(ctx context.Context, id int64) {
+	key := fmt.Sprintf("broadcast:%d", id)
+
+	for ctx.Err() == nil {
+		release, ok := b.locker.Lock(ctx, key, broadcastLockTTL)
+		if !ok {
+			// Рассылку доставляет другой экземпляр
+			return
+		}
+
+		more, err := b.deliverBatch(ctx, id)
+		release()
+
+		if err != nil {
+			logger.FromContext(ctx).Error("Broadcast delivery failed", zap.Error(err))
+			return
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+// deliverBatch отправляет следующую пачку; false — рассылка завершена или отменена
+func (b *Broadcaster) deliverBatch(ctx context.Context, id int64) (bool, error) {
+	// Курсор перечитывается под блокировкой: его мог сдвинуть другой экземпляр
+	broadcast, err := b.broadcasts.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if broadcast.Status != entities.BroadcastSending {
+		return false, nil
+	}
+
+	recipients, err := b.users.ActiveIDsAfter(ctx, broadcast.LastUserID, broadcastBatchSize)
+	if err != nil {
+		return false, err
+	}
+
+	if len(recipients) == 0 {
+		return false, b.finish(ctx, broadcast)
+	}
+
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	for _, userID := range recipients {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case <-ticker.C:
+		}
+
+		if _, err := b.sender.Send(ctx, tgbotapi.NewMessage(userID, broadcast.Text)); err != nil {
+			broadcast.Failed++
+			if telegram.IsBlocked(err) {
+				if err := b.users.MarkBlocked(ctx, userID); err != nil {
+					logger.FromContext(ctx).Warn("Failed to mark user blocked", zap.Error(err))
+				}
+			} else {
+				logger.FromContext(ctx).Warn("Broadcast message failed",
+					zap.Int64("recipient", userID), zap.Error(err))
+			}
+		} else {
+			broadcast.Sent++
+		}
+
+		broadcast.LastUserID = userID
+		if err := b.broadcasts.Progress(ctx, id, userID, broadcast.Sent, broadcast.Failed); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (b *Broadcaster) finish(ctx context.Context, broadcast entities.Broadcast) error {
+	finished, err := b.broadcasts.SetStatus(ctx, broadcast.ID, entities.BroadcastDone, entities.BroadcastSending)
+	if err != nil || !finished {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Broadcast finished",
+		zap.Int("sent", broadcast.Sent), zap.Int("failed", broadcast.Failed))
+
+	report := fmt.Sprintf("📣 Рассылка #%d завершена: доставлено %d, ошибок %d",
+		broadcast.ID, broadcast.Sent, broadcast.Failed)
+	err = b.sender.Enqueue(ctx, tgbotapi.NewMessage(broadcast.AdminID, report), func(_ tgbotapi.Message, err error) {
+		if err != nil {
+			logger.FromContext(ctx).Warn("Failed to report broadcast result", zap.Error(err))
+		}
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to queue broadcast report", zap.Error(err))
+	}
+	return nil
+}
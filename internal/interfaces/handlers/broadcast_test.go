@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/interfaces/repository/cache"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeUsers struct {
+	mu      sync.Mutex
+	ids     []int64
+	blocked map[int64]bool
+}
+
+func (f *fakeUsers) Touch(context.Context, int64, string) error { return nil }
+
+func (f *fakeUsers) FindByUsername(context.Context, string) (entities.User, error) {
+	return entities.User{}, sql.ErrNoRows
+}
+
+func (f *fakeUsers) SetPreferredCurrency(context.Context, int64, string) error { return nil }
+
+func (f *fakeUsers) PreferredCurrencies(context.Context, []int64) (map[int64]string, error) {
+	return nil, nil
+}
+
+func (f *fakeUsers) MarkBlocked(_ context.Context, userID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blocked[userID] = true
+	return nil
+}
+
+func (f *fakeUsers) Counts(context.Context) (entities.UserCounts, error) {
+	return entities.UserCounts{Total: len(f.ids)}, nil
+}
+
+func (f *fakeUsers) ActiveIDsAfter(_ context.Context, afterID int64, limit int) ([]int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var ids []int64
+	for _, id := range f.ids {
+		if id > afterID && !f.blocked[id] && len(ids) < limit {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type fakeBroadcasts struct {
+	mu        sync.Mutex
+	broadcast entities.Broadcast
+}
+
+func (f *fakeBroadcasts) Create(context.Context, int64, string) (entities.Broadcast, error) {
+	return f.broadcast, nil
+}
+
+func (f *fakeBroadcasts) Get(context.Context, int64) (entities.Broadcast, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.broadcast, nil
+}
+
+func (f *fakeBroadcasts) SetStatus(_ context.Context, _ int64, status string, from ...string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range from {
+		if f.broadcast.Status == s {
+			f.broadcast.Status = status
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeBroadcasts) Progress(_ context.Context, _ int64, lastUserID int64, sent, failed int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.broadcast.LastUserID, f.broadcast.Sent, f.broadcast.Failed = lastUserID, sent, failed
+	return nil
+}
+
+func (f *fakeBroadcasts) Sending(context.Context) ([]entities.Broadcast, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.broadcast.Status == entities.BroadcastSending {
+		return []entities.Broadcast{f.broadcast}, nil
+	}
+	return nil, nil
+}
+
+// newTestBot поднимает заглушку Bot API; чаты из blocked отвечают 403
+func newTestBot(t *testing.T, blocked map[int64]bool) (*tgbotapi.BotAPI, func() []int64) {
+	var (
+		mu   sync.Mutex
+		sent []int64
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bottest/getMe" {
+			fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`)
+			return
+		}
+
+		require.NoError(t, r.ParseForm())
+		var chatID int64
+		fmt.Sscan(r.FormValue("chat_id"), &chatID)
+
+		if blocked[chatID] {
+			fmt.Fprint(w, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`)
+			return
+		}
+
+		mu.Lock()
+		sent = append(sent, chatID)
+		mu.Unlock()
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"chat":{"id":%d}}}`, chatID)
+	}))
+	t.Cleanup(server.Close)
+
+	bot, err := tgbotapi.NewBotAPIWithClient("test", server.URL+"/bot%s/%s", server.Client())
+	require.NoError(t, err)
+
+	return bot, func() []int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		result := append([]int64(nil), sent...)
+		sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+		return result
+	}
+}
+
+func TestBroadcaster_ResumesAndMarksBlocked(t *testing.T) {
+	bot, sent := newTestBot(t, map[int64]bool{30: true})
+
+	users := &fakeUsers{ids: []int64{10, 20, 30, 40}, blocked: make(map[int64]bool)}
+	// Рассылка прервана после пользователя 10
+	broadcasts := &fakeBroadcasts{broadcast: entities.Broadcast{
+		ID: 1, AdminID: 99, Text: "maintenance", Status: entities.BroadcastSending,
+		LastUserID: 10, Sent: 1,
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sender := telegram.NewSender(bot)
+	go sender.Run(ctx)
+
+	broadcaster := NewBroadcaster(sender, users, broadcasts, cache.NewRatesCache(5))
+	broadcaster.deliverPending(ctx)
+
+	result := broadcasts.broadcast
+	assert.Equal(t, entities.BroadcastDone, result.Status)
+	assert.Equal(t, 3, result.Sent)
+	assert.Equal(t, 1, result.Failed)
+	assert.True(t, users.blocked[30])
+	// 99 — отчет администратору о завершении, отправляется через очередь
+	assert.Eventually(t, func() bool { return len(sent()) == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int64{20, 40, 99}, sent())
+}
+
+func TestBroadcaster_SkipsLockedBroadcast(t *testing.T) {
+	bot, sent := newTestBot(t, nil)
+
+	users := &fakeUsers{ids: []int64{10}, blocked: make(map[int64]bool)}
+	broadcasts := &fakeBroadcasts{broadcast: entities.Broadcast{ID: 1, Status: entities.BroadcastSending}}
+
+	locker := cache.NewRatesCache(5)
+	release, ok := locker.Lock(context.Background(), "broadcast:1", broadcastLockTTL)
+	require.True(t, ok)
+	defer release()
+
+	NewBroadcaster(telegram.NewSender(bot), users, broadcasts, locker).deliverPending(context.Background())
+
+	assert.Empty(t, sent())
+	assert.Equal(t, entities.BroadcastSending, broadcasts.broadcast.Status)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Действия инлайн-кнопок
+const (
+	// callbackConvert — сумма, исходная и целевая валюта
+	callbackConvert = "conv"
+	// callbackAddFavorite и callbackRemoveFavorite — исходная и целевая валюта
+	callbackAddFavorite    = "addfav"
+	callbackRemoveFavorite = "remfav"
+	// callbackBroadcast — send или cancel и ID рассылки
+	callbackBroadcast = "bcast"
+	// callbackFavorite — действие со списком избранного и ID пары
+	callbackFavorite = "fav"
+	// callbackDeleteExpense — ID поездки и ID расхода
+	callbackDeleteExpense = "expdel"
+	// callbackSplit — confirm или cancel и ID счета
+	callbackSplit = "split"
+	// callbackSettle — должник, получатель, сумма в копейках и валюта перевода
+	callbackSettle = "settle"
+	// callbackHistory — page и номер страницы, repeat и ID записи, clear или keep
+	callbackHistory = "hist"
+	// callbackForget — confirm или cancel удаления данных пользователя
+	callbackForget = "forget"
+)
+
+// WithCallbackCodec задает кодек данных кнопок с общим для реплик ключом подписи
+func WithCallbackCodec(codec *telegram.CallbackCodec) Option {
+	return func(h *BotHandler) {
+		h.callbacks = codec
+	}
+}
+
+// button создает инлайн-кнопку с подписанными данными. Если данные не удалось
+// сохранить, кнопка получает только действие и при нажатии сообщит, что устарела.
+func (h *BotHandler) button(ctx context.Context, text, action string, args ...any) tgbotapi.InlineKeyboardButton {
+	data, err := h.callbacks.Encode(ctx, telegram.NewCallbackData(action, args...))
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to encode callback data", zap.String("action", action), zap.Error(err))
+		data, _ = h.callbacks.Encode(ctx, telegram.NewCallbackData(action))
+	}
+	return tgbotapi.NewInlineKeyboardButtonData(text, data)
+}
+
+// decodeCallback разбирает данные нажатой кнопки; на неразборчивые данные сразу отвечает пользователю
+func (h *BotHandler) decodeCallback(ctx context.Context, callback *tgbotapi.CallbackQuery) (telegram.CallbackData, bool) {
+	data, err := h.callbacks.Decode(ctx, callback.Data)
+	if err == nil {
+		return data, true
+	}
+
+	switch {
+	case errors.Is(err, telegram.ErrCallbackSignature):
+		logger.FromContext(ctx).Warn("Rejected callback with invalid signature")
+	case errors.Is(err, telegram.ErrCallbackMalformed),
+		errors.Is(err, telegram.ErrCallbackVersion),
+		errors.Is(err, telegram.ErrCallbackExpired):
+		logger.FromContext(ctx).Debug("Outdated callback data", zap.Error(err))
+	default:
+		logger.FromContext(ctx).Error("Failed to decode callback data", zap.Error(err))
+	}
+
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+	return telegram.CallbackData{}, false
+}
+
+// staleButton отвечает на кнопки старых сообщений, данные которых больше не разбираются
+const staleButton = "⌛ Кнопка устарела, повторите запрос"
@@ -0,0 +1,539 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/money"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxTripName и maxExpenseNote совпадают с размерами колонок
+	maxTripName    = 64
+	maxExpenseNote = 200
+	maxMemberName  = 64
+	// recentExpenses — сколько последних расходов показывает /expenses
+	recentExpenses = 10
+	// defaultTripCurrency — валюта итогов, если она не указана и в чате нет валют по умолчанию
+	defaultTripCurrency = "RUB"
+)
+
+// expenseCategories сопоставляет слова из описания расхода с категориями
+var expenseCategories = map[string]string{
+	"еда": entities.CategoryFood, "food": entities.CategoryFood, "кафе": entities.CategoryFood,
+	"ресторан": entities.CategoryFood, "обед": entities.CategoryFood, "ужин": entities.CategoryFood,
+	"завтрак": entities.CategoryFood, "продукты": entities.CategoryFood, "кофе": entities.CategoryFood,
+	"dinner": entities.CategoryFood, "lunch": entities.CategoryFood, "breakfast": entities.CategoryFood,
+	"cafe": entities.CategoryFood, "groceries": entities.CategoryFood,
+
+	"такси": entities.CategoryTransport, "taxi": entities.CategoryTransport, "транспорт": entities.CategoryTransport,
+	"transport": entities.CategoryTransport, "метро": entities.CategoryTransport, "автобус": entities.CategoryTransport,
+	"поезд": entities.CategoryTransport, "самолет": entities.CategoryTransport, "билеты": entities.CategoryTransport,
+	"бензин": entities.CategoryTransport, "uber": entities.CategoryTransport, "bus": entities.CategoryTransport,
+	"train": entities.CategoryTransport, "flight": entities.CategoryTransport, "fuel": entities.CategoryTransport,
+
+	"жилье": entities.CategoryLodging, "отель": entities.CategoryLodging, "гостиница": entities.CategoryLodging,
+	"хостел": entities.CategoryLodging, "квартира": entities.CategoryLodging, "hotel": entities.CategoryLodging,
+	"hostel": entities.CategoryLodging, "airbnb": entities.CategoryLodging, "lodging": entities.CategoryLodging,
+
+	"развлечения": entities.CategoryEntertainment, "экскурсия": entities.CategoryEntertainment,
+	"музей": entities.CategoryEntertainment, "кино": entities.CategoryEntertainment, "бар": entities.CategoryEntertainment,
+	"концерт": entities.CategoryEntertainment, "tour": entities.CategoryEntertainment, "museum": entities.CategoryEntertainment,
+	"bar": entities.CategoryEntertainment, "entertainment": entities.CategoryEntertainment,
+
+	"покупки": entities.CategoryShopping, "магазин": entities.CategoryShopping, "сувениры": entities.CategoryShopping,
+	"одежда": entities.CategoryShopping, "shopping": entities.CategoryShopping, "souvenirs": entities.CategoryShopping,
+
+	"другое": entities.CategoryOther, "other": entities.CategoryOther,
+}
+
+// categoryTitles — подписи категорий в отчетах
+var categoryTitles = map[string]string{
+	entities.CategoryFood:          "🍽 Еда",
+	entities.CategoryTransport:     "🚕 Транспорт",
+	entities.CategoryLodging:       "🏨 Жилье",
+	entities.CategoryEntertainment: "🎭 Развлечения",
+	entities.CategoryShopping:      "🛍 Покупки",
+	entities.CategoryOther:         "📦 Другое",
+}
+
+// WithExpenses включает учет расходов в поездках
+func WithExpenses(repo services.ExpensesRepository) Option {
+	return func(h *BotHandler) {
+		h.expenses = repo
+	}
+}
+
+// expenseInput — разобранная команда /spent
+type expenseInput struct {
+	Amount   float64
+	Currency string
+	Category string
+	Note     string
+	SpentOn  time.Time
+}
+
+var errExpenseUsage = errors.New("usage: /spent <amount> <currency> [description] [date]")
+
+// parseExpense разбирает «3500 KZT такси вчера», «$20 ужин 14.03», «1 200 руб. сувениры».
+// Категория определяется по первому знакомому слову описания, дата — по слову «вчера» или дате.
+func parseExpense(text string, now time.Time) (expenseInput, error) {
+	input := expenseInput{Category: entities.CategoryOther}
+
+	amount, currency, rest, ok := parseMoneyPrefix(text)
+	if !ok {
+		return input, errExpenseUsage
+	}
+	input.Amount, input.Currency = amount, currency
+
+	input.SpentOn = dateOf(now)
+	var note []string
+	for _, word := range strings.Fields(rest) {
+		if date, ok := parsePastDate(word, now); ok {
+			input.SpentOn = date
+			continue
+		}
+		if category, ok := expenseCategories[strings.ToLower(strings.Trim(word, ",.!"))]; ok && input.Category == entities.CategoryOther {
+			input.Category = category
+		}
+		note = append(note, word)
+	}
+	input.Note = truncateRunes(strings.Join(note, " "), maxExpenseNote)
+
+	return input, nil
+}
+
+// parseMoneyPrefix берет сумму с валютой из начала текста и возвращает остаток
+func parseMoneyPrefix(text string) (float64, string, string, bool) {
+	text = strings.TrimSpace(text)
+	if mentions := money.Find(text); len(mentions) > 0 && strings.TrimSpace(text[:mentions[0].Start]) == "" {
+		mention := mentions[0]
+		return mention.Amount, mention.Currency, text[mention.End:], mention.Amount > 0
+	}
+
+	// Коды, которые парсер сумм в тексте пропускает, в команде принимаются в любом регистре
+	fields := strings.Fields(text)
+	if len(fields) < 2 {
+		return 0, "", "", false
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(fields[0], ",", "."), 64)
+	if err != nil || amount <= 0 || !isCurrencyCode(strings.ToUpper(fields[1])) {
+		return 0, "", "", false
+	}
+	return amount, strings.ToUpper(fields[1]), strings.Join(fields[2:], " "), true
+}
+
+// parsePastDate понимает «сегодня», «вчера», «позавчера», 14.03, 14.03.2025 и 2025-03-14;
+// будущие даты не принимаются
+func parsePastDate(word string, now time.Time) (time.Time, bool) {
+	today := dateOf(now)
+	switch strings.ToLower(word) {
+	case "сегодня", "today":
+		return today, true
+	case "вчера", "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "позавчера":
+		return today.AddDate(0, 0, -2), true
+	}
+
+	for _, layout := range []string{time.DateOnly, "02.01.2006", "02.01"} {
+		date, err := time.Parse(layout, word)
+		if err != nil {
+			continue
+		}
+		if layout == "02.01" {
+			date = date.AddDate(today.Year(), 0, 0)
+			// Дата без года в будущем относится к прошлому году: 28.12 в январе
+			if date.After(today) {
+				date = date.AddDate(-1, 0, 0)
+			}
+		}
+		return date, !date.After(today)
+	}
+	return time.Time{}, false
+}
+
+// dateOf возвращает дату в UTC без времени: так же хранятся и запрашиваются курсы на дату
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit])
+}
+
+// memberName — имя участника для отчетов
+func memberName(user *tgbotapi.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" && user.UserName != "" {
+		name = "@" + user.UserName
+	}
+	if name == "" {
+		name = "id" + strconv.FormatInt(user.ID, 10)
+	}
+	return truncateRunes(name, maxMemberName)
+}
+
+// activeTrip возвращает поездку чата; если ее нет или она недоступна, сообщает об этом в чат
+func (h *BotHandler) activeTrip(ctx context.Context, chatID int64) (entities.Trip, bool) {
+	if h.expenses == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Учет расходов недоступен."))
+		return entities.Trip{}, false
+	}
+
+	trip, err := h.expenses.ActiveTrip(ctx, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+			"🧳 Поездка не начата. Начните ее командой /trip new Алматы RUB — расходы будут считаться в RUB."))
+		return entities.Trip{}, false
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get active trip", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить поездку."))
+		return entities.Trip{}, false
+	}
+	return trip, true
+}
+
+// handleTripCommand управляет поездкой чата: /trip [new <название> [валюта]|join|end]
+func (h *BotHandler) handleTripCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.expenses == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Учет расходов недоступен."))
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		h.showTrip(ctx, message)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "new":
+		h.startTrip(ctx, message, args[1:])
+	case "join":
+		trip, ok := h.activeTrip(ctx, chatID)
+		if !ok {
+			return
+		}
+		if err := h.joinTrip(ctx, trip, message.From); err != nil {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось добавить вас в поездку."))
+			return
+		}
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ %s теперь в поездке «%s».", memberName(message.From), trip.Name)))
+	case "end":
+		trip, ok := h.activeTrip(ctx, chatID)
+		if !ok {
+			return
+		}
+		if trip.CreatedBy != message.From.ID && !h.canConfigureChat(ctx, message) {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Завершить поездку может ее автор или администратор группы."))
+			return
+		}
+		if err := h.expenses.CloseTrip(ctx, trip.ID); err != nil {
+			logger.FromContext(ctx).Error("Failed to close trip", zap.Error(err))
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось завершить поездку."))
+			return
+		}
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, fmt.Sprintf("🏁 Поездка «%s» завершена. Итоги остались в истории.", trip.Name)))
+	default:
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, tripUsage))
+	}
+}
+
+const tripUsage = "Использование:\n/trip new Алматы RUB — начать поездку с итогами в RUB\n/trip join — присоединиться\n/trip end — завершить\n/spent 3500 KZT такси — записать расход"
+
+func (h *BotHandler) startTrip(ctx context.Context, message *tgbotapi.Message, args []string) {
+	chatID := message.Chat.ID
+
+	currency := defaultTripCurrency
+	if settings, _ := h.chatSettings(ctx, chatID); settings.DefaultTo != "" {
+		currency = settings.DefaultTo
+	}
+	if n := len(args); n > 1 && isCurrencyCode(strings.ToUpper(args[n-1])) {
+		currency = strings.ToUpper(args[n-1])
+		args = args[:n-1]
+	}
+	name := truncateRunes(strings.Join(args, " "), maxTripName)
+	if name == "" {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, tripUsage))
+		return
+	}
+
+	if current, err := h.expenses.ActiveTrip(ctx, chatID); err == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+			fmt.Sprintf("❌ Уже идет поездка «%s». Сначала завершите ее: /trip end", current.Name)))
+		return
+	}
+
+	trip, err := h.expenses.CreateTrip(ctx, entities.Trip{
+		ChatID:       chatID,
+		Name:         name,
+		BaseCurrency: currency,
+		CreatedBy:    message.From.ID,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create trip", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось начать поездку."))
+		return
+	}
+	_ = h.joinTrip(ctx, trip, message.From)
+
+	text := fmt.Sprintf("🧳 Поездка «%s» начата, итоги в %s.\nЗаписывайте расходы: /spent 3500 KZT такси", trip.Name, trip.BaseCurrency)
+	if isGroup(message.Chat) {
+		text += "\nУчастники присоединяются командой /trip join или первым расходом."
+	}
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, text))
+}
+
+func (h *BotHandler) joinTrip(ctx context.Context, trip entities.Trip, user *tgbotapi.User) error {
+	err := h.expenses.AddTripMember(ctx, entities.TripMember{TripID: trip.ID, UserID: user.ID, Name: memberName(user)})
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to add trip member", zap.Error(err))
+	}
+	return err
+}
+
+func (h *BotHandler) showTrip(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	trip, err := h.expenses.ActiveTrip(ctx, chatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "🧳 Поездка не начата.\n\n"+tripUsage))
+		return
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get active trip", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить поездку."))
+		return
+	}
+
+	members, err := h.expenses.GetTripMembers(ctx, trip.ID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get trip members", zap.Error(err))
+	}
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		names = append(names, member.Name)
+	}
+
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, fmt.Sprintf("🧳 Поездка «%s», итоги в %s\nУчастники: %s\n\n/report — итоги, /expenses — последние расходы",
+		trip.Name, trip.BaseCurrency, strings.Join(names, ", "))))
+}
+
+// handleSpentCommand записывает расход в активную поездку чата: /spent 3500 KZT такси
+func (h *BotHandler) handleSpentCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	input, err := parseExpense(message.CommandArguments(), time.Now())
+	if err != nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+			"Использование: /spent 3500 KZT такси\nМожно указать дату: /spent 20 EUR ужин вчера, /spent 40 USD музей 14.03"))
+		return
+	}
+
+	trip, ok := h.activeTrip(ctx, chatID)
+	if !ok {
+		return
+	}
+
+	expense, err := h.expenses.AddExpense(ctx, entities.Expense{
+		TripID:   trip.ID,
+		PaidBy:   message.From.ID,
+		Amount:   input.Amount,
+		Currency: input.Currency,
+		Category: input.Category,
+		Note:     input.Note,
+		SpentOn:  input.SpentOn,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to add expense", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось записать расход."))
+		return
+	}
+	_ = h.joinTrip(ctx, trip, message.From)
+
+	text := "✅ Записано: " + formatExpense(expense)
+	if expense.Currency != trip.BaseCurrency {
+		report, err := services.BuildExpenseReport(ctx, h.exchangeService, []entities.Expense{expense}, trip.BaseCurrency)
+		if err == nil {
+			text += fmt.Sprintf("\n≈ %s %s", formatMoney(report.Total), trip.BaseCurrency)
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	h.replyInGroup(&msg, message)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		h.button(ctx, "↩️ Отменить", callbackDeleteExpense, trip.ID, expense.ID),
+	))
+	h.sendMessage(ctx, msg)
+}
+
+// formatExpense печатает расход: «3 500 KZT · 🚕 Транспорт · такси · 14.03»
+func formatExpense(expense entities.Expense) string {
+	parts := []string{formatMoney(expense.Amount) + " " + expense.Currency, categoryTitles[expense.Category]}
+	if expense.Note != "" {
+		parts = append(parts, expense.Note)
+	}
+	parts = append(parts, expense.SpentOn.Format("02.01"))
+	return strings.Join(parts, " · ")
+}
+
+// handleDeleteExpenseCallback отменяет расход; удалить его может только записавший
+func (h *BotHandler) handleDeleteExpenseCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data telegram.CallbackData) {
+	tripID, err := data.Int64(0)
+	if err != nil || h.expenses == nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+	id, err := data.Int64(1)
+	if err != nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+
+	err = h.expenses.DeleteExpense(ctx, tripID, id, callback.From.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "Отменить расход может только тот, кто его записал"))
+		return
+	case err != nil:
+		logger.FromContext(ctx).Error("Failed to delete expense", zap.Error(err))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "❌ Не удалось отменить расход"))
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID,
+		"↩️ Отменено: "+strings.TrimPrefix(callback.Message.Text, "✅ Записано: "))
+	_, _ = h.sender.Send(ctx, edit)
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "Расход отменен"))
+}
+
+// handleExpensesCommand показывает последние расходы поездки
+func (h *BotHandler) handleExpensesCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	trip, ok := h.activeTrip(ctx, chatID)
+	if !ok {
+		return
+	}
+
+	expenses, names, ok := h.tripExpenses(ctx, chatID, trip)
+	if !ok {
+		return
+	}
+	if len(expenses) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Расходов пока нет. Запишите первый: /spent 3500 KZT такси"))
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧾 Последние расходы «%s»:\n\n", trip.Name)
+	for _, expense := range expenses[max(0, len(expenses)-recentExpenses):] {
+		fmt.Fprintf(&sb, "%s — %s\n", formatExpense(expense), names.of(expense.PaidBy))
+	}
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, sb.String()))
+}
+
+// handleReportCommand показывает итоги поездки по категориям и участникам: /report [валюта]
+func (h *BotHandler) handleReportCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	trip, ok := h.activeTrip(ctx, chatID)
+	if !ok {
+		return
+	}
+
+	currency := trip.BaseCurrency
+	if arg := strings.ToUpper(strings.TrimSpace(message.CommandArguments())); arg != "" {
+		if !isCurrencyCode(arg) {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Использование: /report или /report USD"))
+			return
+		}
+		currency = arg
+	}
+
+	expenses, names, ok := h.tripExpenses(ctx, chatID, trip)
+	if !ok {
+		return
+	}
+	if len(expenses) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Расходов пока нет. Запишите первый: /spent 3500 KZT такси"))
+		return
+	}
+
+	report, err := services.BuildExpenseReport(ctx, h.exchangeService, expenses, currency)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to build expense report", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось пересчитать расходы: курс недоступен."))
+		return
+	}
+
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, formatExpenseReport(trip, report, names)))
+}
+
+// formatExpenseReport печатает итоги без разметки: названия и имена вводят пользователи
+func formatExpenseReport(trip entities.Trip, report entities.ExpenseReport, names memberNames) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 Поездка «%s»\nИтого: %s %s (расходов: %d)\n", trip.Name, formatMoney(report.Total), report.Currency, report.Count)
+
+	sb.WriteString("\nПо категориям:\n")
+	for _, line := range report.ByCategory {
+		fmt.Fprintf(&sb, "%s — %s %s (%.0f%%)\n", categoryTitles[line.Category], formatMoney(line.Amount), report.Currency,
+			line.Amount/report.Total*100)
+	}
+
+	sb.WriteString("\nКто сколько заплатил:\n")
+	for _, line := range report.ByPayer {
+		fmt.Fprintf(&sb, "%s — %s %s\n", names.of(line.UserID), formatMoney(line.Amount), report.Currency)
+	}
+
+	if report.Approximate > 0 {
+		fmt.Fprintf(&sb, "\n⚠️ Расходов по текущему курсу: %d — курса на их дату нет.", report.Approximate)
+	}
+	return sb.String()
+}
+
+// memberNames — имена участников поездки по ID
+type memberNames map[int64]string
+
+func (n memberNames) of(userID int64) string {
+	if name, ok := n[userID]; ok {
+		return name
+	}
+	return "id" + strconv.FormatInt(userID, 10)
+}
+
+// tripExpenses загружает расходы и имена участников поездки
+func (h *BotHandler) tripExpenses(ctx context.Context, chatID int64, trip entities.Trip) ([]entities.Expense, memberNames, bool) {
+	expenses, err := h.expenses.GetTripExpenses(ctx, trip.ID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get trip expenses", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить расходы."))
+		return nil, nil, false
+	}
+
+	members, err := h.expenses.GetTripMembers(ctx, trip.ID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get trip members", zap.Error(err))
+	}
+	names := make(memberNames, len(members))
+	for _, member := range members {
+		names[member.UserID] = member.Name
+	}
+
+	return expenses, names, true
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpense(t *testing.T) {
+	now := time.Date(2025, 3, 16, 21, 30, 0, 0, time.UTC)
+	today := time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		text string
+		want expenseInput
+	}{
+		{"3500 KZT taxi", expenseInput{Amount: 3500, Currency: "KZT", Category: entities.CategoryTransport, Note: "taxi", SpentOn: today}},
+		{"$20 ужин в кафе вчера", expenseInput{Amount: 20, Currency: "USD", Category: entities.CategoryFood, Note: "ужин в кафе", SpentOn: today.AddDate(0, 0, -1)}},
+		{"1 200 руб. сувениры 14.03", expenseInput{Amount: 1200, Currency: "RUB", Category: entities.CategoryShopping, Note: "сувениры", SpentOn: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)}},
+		{"45,5 try паром", expenseInput{Amount: 45.5, Currency: "TRY", Category: entities.CategoryOther, Note: "паром", SpentOn: today}},
+		{"900 gel 28.12", expenseInput{Amount: 900, Currency: "GEL", Category: entities.CategoryOther, SpentOn: time.Date(2024, 12, 28, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, tc := range cases {
+		got, err := parseExpense(tc.text, now)
+		require.NoError(t, err, tc.text)
+		assert.Equal(t, tc.want, got, tc.text)
+	}
+
+	for _, text := range []string{"", "3500", "такси 3500", "0 USD", "100 доллларов"} {
+		_, err := parseExpense(text, now)
+		assert.Error(t, err, text)
+	}
+
+	// Будущая дата остается в описании, расход записывается сегодняшним днем
+	got, err := parseExpense("10 EUR музей 2025-04-01", now)
+	require.NoError(t, err)
+	assert.Equal(t, today, got.SpentOn)
+	assert.Equal(t, "музей 2025-04-01", got.Note)
+}
+
+func TestFormatExpenseReport(t *testing.T) {
+	trip := entities.Trip{Name: "Алматы", BaseCurrency: "RUB"}
+	report := entities.ExpenseReport{
+		Currency:    "RUB",
+		Total:       4000,
+		Count:       3,
+		ByCategory:  []entities.CategoryTotal{{Category: entities.CategoryFood, Amount: 3000}, {Category: entities.CategoryTransport, Amount: 1000}},
+		ByPayer:     []entities.PayerTotal{{UserID: 1, Amount: 2500}, {UserID: 2, Amount: 1500}},
+		Approximate: 1,
+	}
+
+	text := formatExpenseReport(trip, report, memberNames{1: "Аня"})
+	assert.Contains(t, text, "Итого: 4 000 RUB (расходов: 3)")
+	assert.Contains(t, text, "🍽 Еда — 3 000 RUB (75%)")
+	assert.Contains(t, text, "Аня — 2 500 RUB")
+	assert.Contains(t, text, "id2 — 1 500 RUB")
+	assert.Contains(t, text, "Расходов по текущему курсу: 1")
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	"github.com/crocxdued/currency-telegram-bot/pkg/export"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxExportDays ограничивает период выгрузки курсов: каждый день — запрос к провайдеру
+	maxExportDays = 366
+	// defaultExportDays — период выгрузки курсов, если даты не указаны
+	defaultExportDays = 30
+	// maxConcurrentExports — сколько файлов готовится одновременно на все чаты
+	maxConcurrentExports = 2
+	exportTimeout        = 5 * time.Minute
+)
+
+const exportUsage = "Использование:\n/export favorites [csv|xlsx] — избранные пары\n/export history [csv|xlsx] — история конвертаций\n/export rates USD RUB [с] [по] [csv|xlsx] — курсы за период, например /export rates USD RUB 01.01.2025 31.03.2025 xlsx"
+
+// errExportEmpty — в выгрузке нет ни одной строки
+var errExportEmpty = errors.New("export is empty")
+
+// exportJob описывает выгрузку: rows пишет строки по мере получения, не собирая их в памяти
+type exportJob struct {
+	dataset  string // метка метрики
+	filename string // имя файла без расширения
+	caption  string
+	header   []any
+	rows     func(ctx context.Context, w export.Writer) (int, error)
+}
+
+// exportState не дает пользователю запускать выгрузки параллельно и ограничивает их общее число
+type exportState struct {
+	mu      sync.Mutex
+	running map[int64]bool
+	slots   chan struct{}
+}
+
+func newExportState() exportState {
+	return exportState{running: make(map[int64]bool), slots: make(chan struct{}, maxConcurrentExports)}
+}
+
+func (s *exportState) start(userID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running[userID] {
+		return false
+	}
+	s.running[userID] = true
+	return true
+}
+
+func (s *exportState) finish(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, userID)
+}
+
+// handleExportCommand выгружает данные пользователя файлом: /export favorites|rates ... [csv|xlsx]
+func (h *BotHandler) handleExportCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	// В выгрузке личные данные, в группе файл увидели бы все участники
+	if isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "🔒 Выгрузка доступна только в личном чате с ботом."))
+		return
+	}
+
+	args := strings.Fields(message.CommandArguments())
+	format := export.CSV
+	if n := len(args); n > 1 {
+		if f, err := export.ParseFormat(strings.ToLower(args[n-1])); err == nil {
+			format, args = f, args[:n-1]
+		}
+	}
+	if len(args) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, exportUsage))
+		return
+	}
+
+	var job exportJob
+	switch strings.ToLower(args[0]) {
+	case "favorites", "избранное":
+		job = h.favoritesExport(message.From.ID)
+	case "history", "conversions", "история":
+		if h.conversions == nil {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ История недоступна."))
+			return
+		}
+		job = h.conversionsExport(message.From.ID)
+	case "rates", "курсы":
+		query, err := parseRatesExport(args[1:], time.Now())
+		if err != nil {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ "+err.Error()+"\n\n"+exportUsage))
+			return
+		}
+		job = h.ratesExport(query)
+	default:
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, exportUsage))
+		return
+	}
+
+	userID := message.From.ID
+	if !h.exports.start(userID) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "⏳ Предыдущий файл еще готовится."))
+		return
+	}
+
+	// Обновления обрабатываются по одному: долгая выгрузка не должна задерживать ответы другим
+	go func() {
+		defer h.exports.finish(userID)
+
+		ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+		defer cancel()
+		h.runExport(ctx, chatID, job, format)
+	}()
+}
+
+// runExport готовит файл во временном каталоге и отправляет его документом
+func (h *BotHandler) runExport(ctx context.Context, chatID int64, job exportJob, format export.Format) {
+	select {
+	case h.exports.slots <- struct{}{}:
+		defer func() { <-h.exports.slots }()
+	case <-ctx.Done():
+		return
+	}
+	_, _ = h.sender.Request(ctx, tgbotapi.NewChatAction(chatID, tgbotapi.ChatUploadDocument))
+
+	dir, err := os.MkdirTemp("", "export-*")
+	if err == nil {
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, job.filename+"."+string(format))
+		if err = writeExport(ctx, path, job, format); err == nil {
+			doc := tgbotapi.NewDocument(chatID, exportFile(path))
+			doc.Caption = job.caption
+			_, err = h.sender.Send(ctx, doc)
+		}
+	}
+
+	result := metrics.Result(err)
+	switch {
+	case errors.Is(err, errExportEmpty):
+		result = "empty"
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Нет данных для выгрузки."))
+	case errors.Is(err, services.ErrNoHistory):
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Для этой пары нет истории курсов."))
+	case err != nil:
+		logger.FromContext(ctx).Error("Failed to export", zap.String("dataset", job.dataset), zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось подготовить файл."))
+	}
+	metrics.ExportsTotal.WithLabelValues(job.dataset, string(format), result).Inc()
+}
+
+// writeExport пишет заголовок и строки выгрузки в файл path
+func writeExport(ctx context.Context, path string, job exportJob, format export.Format) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := export.NewWriter(format, f, job.filename)
+	if err != nil {
+		return err
+	}
+	if err := w.Write(job.header...); err != nil {
+		return err
+	}
+	n, err := job.rows(ctx, w)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errExportEmpty
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// exportFile открывает файл заново при каждой попытке отправки: отправитель повторяет
+// запросы, а прочитанный поток повторить нельзя. Telegram получает только имя файла.
+type exportFile string
+
+func (f exportFile) NeedsUpload() bool { return true }
+
+func (f exportFile) UploadData() (string, io.Reader, error) {
+	file, err := os.Open(string(f))
+	return filepath.Base(string(f)), file, err
+}
+
+func (f exportFile) SendData() string {
+	panic("export file must be uploaded")
+}
+
+func (h *BotHandler) favoritesExport(userID int64) exportJob {
+	return exportJob{
+		dataset:  "favorites",
+		filename: "favorites",
+		caption:  "⭐ Избранные пары",
+		header:   []any{"Пара", "Из", "В", "Подпись", "Сумма по умолчанию", "Закреплена", "Позиция", "Добавлена"},
+		rows: func(ctx context.Context, w export.Writer) (int, error) {
+			favorites, err := h.favoritesRepo.GetUserFavorites(ctx, userID)
+			if err != nil {
+				return 0, err
+			}
+			for _, fav := range favorites {
+				var amount any
+				if fav.DefaultAmount > 0 {
+					amount = fav.DefaultAmount
+				}
+				if err := w.Write(fav.Pair(), fav.FromCurrency, fav.ToCurrency, fav.Label, amount,
+					fav.Pinned, fav.Position, fav.CreatedAt.UTC()); err != nil {
+					return 0, err
+				}
+			}
+			return len(favorites), nil
+		},
+	}
+}
+
+// conversionsExport выгружает историю конвертаций, читая ее из базы построчно
+func (h *BotHandler) conversionsExport(userID int64) exportJob {
+	return exportJob{
+		dataset:  "history",
+		filename: "conversions",
+		caption:  "🕘 История конвертаций",
+		header:   []any{"Время (UTC)", "Запрос", "Сумма", "Из", "В", "Курс", "Результат", "Источник"},
+		rows: func(ctx context.Context, w export.Writer) (int, error) {
+			n := 0
+			err := h.conversions.EachConversion(ctx, userID, func(record entities.ConversionRecord) error {
+				n++
+				return w.Write(record.CreatedAt.UTC(), record.Input, record.Amount, record.From, record.To,
+					record.Rate, record.Result, record.Provider)
+			})
+			return n, err
+		},
+	}
+}
+
+// ratesExportQuery — пара и период выгрузки курсов, даты включительно
+type ratesExportQuery struct {
+	From, To string
+	Since    time.Time
+	Until    time.Time
+}
+
+// parseRatesExport разбирает «USD RUB [с] [по]» или «USD/RUB [с] [по]»; без дат берутся последние 30 дней
+func parseRatesExport(args []string, now time.Time) (ratesExportQuery, error) {
+	if len(args) > 0 {
+		if from, to, ok := strings.Cut(args[0], "/"); ok {
+			args = append([]string{from, to}, args[1:]...)
+		}
+	}
+	if len(args) < 2 || len(args) > 4 {
+		return ratesExportQuery{}, errors.New("укажите пару валют")
+	}
+
+	query := ratesExportQuery{From: strings.ToUpper(args[0]), To: strings.ToUpper(args[1])}
+	if !isCurrencyCode(query.From) || !isCurrencyCode(query.To) {
+		return ratesExportQuery{}, errors.New("неизвестный код валюты")
+	}
+
+	query.Until = dateOf(now)
+	query.Since = query.Until.AddDate(0, 0, -(defaultExportDays - 1))
+	dates := make([]time.Time, 0, 2)
+	for _, word := range args[2:] {
+		date, ok := parsePastDate(word, now)
+		if !ok {
+			return ratesExportQuery{}, fmt.Errorf("не понял дату %q", word)
+		}
+		dates = append(dates, date)
+	}
+	switch len(dates) {
+	case 2:
+		query.Since, query.Until = dates[0], dates[1]
+	case 1:
+		query.Since = dates[0]
+	}
+
+	if query.Since.After(query.Until) {
+		query.Since, query.Until = query.Until, query.Since
+	}
+	if days := int(query.Until.Sub(query.Since).Hours()/24) + 1; days > maxExportDays {
+		return ratesExportQuery{}, fmt.Errorf("период не длиннее %d дней", maxExportDays)
+	}
+	return query, nil
+}
+
+// ratesExport запрашивает курс на каждый день периода; дни без курса пропускаются
+func (h *BotHandler) ratesExport(query ratesExportQuery) exportJob {
+	return exportJob{
+		dataset: "rates",
+		filename: fmt.Sprintf("%s_%s_%s_%s", query.From, query.To,
+			query.Since.Format(time.DateOnly), query.Until.Format(time.DateOnly)),
+		caption: fmt.Sprintf("📈 %s/%s с %s по %s", query.From, query.To,
+			query.Since.Format("02.01.2006"), query.Until.Format("02.01.2006")),
+		header: []any{"Дата", "Из", "В", "Курс", "Дата публикации", "Источник"},
+		rows: func(ctx context.Context, w export.Writer) (int, error) {
+			n := 0
+			for date := query.Since; !date.After(query.Until); date = date.AddDate(0, 0, 1) {
+				rate, err := h.exchangeService.GetRateAt(ctx, query.From, query.To, date)
+				switch {
+				case errors.Is(err, services.ErrNoHistory) && n == 0:
+					return 0, err
+				case ctx.Err() != nil:
+					return 0, ctx.Err()
+				case err != nil:
+					logger.FromContext(ctx).Debug("Rate for export unavailable",
+						zap.Time("date", date), zap.Error(err))
+					continue
+				}
+
+				var published any
+				if !rate.LastUpdated.IsZero() {
+					published = dateOf(rate.LastUpdated)
+				}
+				if err := w.Write(date, query.From, query.To, rate.Rate, published, rate.Provider); err != nil {
+					return 0, err
+				}
+				n++
+			}
+			return n, nil
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/export"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRatesExport(t *testing.T) {
+	now := time.Date(2025, 3, 16, 21, 30, 0, 0, time.UTC)
+	day := func(m time.Month, d int) time.Time { return time.Date(2025, m, d, 0, 0, 0, 0, time.UTC) }
+
+	query, err := parseRatesExport([]string{"usd/rub"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, ratesExportQuery{From: "USD", To: "RUB", Since: day(2, 15), Until: day(3, 16)}, query)
+
+	query, err = parseRatesExport([]string{"USD", "EUR", "31.03.2024", "01.01.2024"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), query.Since)
+	assert.Equal(t, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), query.Until)
+
+	for _, args := range [][]string{
+		{"USD"},
+		{"USD", "RUB", "завтра"},
+		{"USD", "RUB", "01.01.2023", "01.03.2025"},
+		{"US", "RUB"},
+	} {
+		_, err := parseRatesExport(args, now)
+		assert.Error(t, err, args)
+	}
+}
+
+// historyRates отдает курсы на даты из rates, для остальных дат — ошибку
+type historyRates struct {
+	services.ExchangeService
+	rates map[string]float64
+	err   error
+}
+
+func (r historyRates) GetRateAt(_ context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	rate, ok := r.rates[date.Format(time.DateOnly)]
+	if !ok {
+		return entities.ExchangeRate{}, r.err
+	}
+	return entities.NewExchangeRate(from, to, rate, "cbr", date), nil
+}
+
+func TestRatesExport_SkipsMissingDays(t *testing.T) {
+	h := &BotHandler{exchangeService: historyRates{
+		rates: map[string]float64{"2025-03-01": 92.5, "2025-03-03": 91.25},
+		err:   errors.New("timeout"),
+	}}
+	job := h.ratesExport(ratesExportQuery{
+		From: "USD", To: "RUB",
+		Since: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+	})
+	assert.Equal(t, "USD_RUB_2025-03-01_2025-03-03", job.filename)
+
+	var buf bytes.Buffer
+	w, err := export.NewCSVWriter(&buf)
+	require.NoError(t, err)
+	n, err := job.rows(context.Background(), w)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "\uFEFF2025-03-01,USD,RUB,92.5,2025-03-01,cbr\n2025-03-03,USD,RUB,91.25,2025-03-03,cbr\n", buf.String())
+}
+
+func TestRatesExport_NoHistory(t *testing.T) {
+	h := &BotHandler{exchangeService: historyRates{err: services.ErrNoHistory}}
+	job := h.ratesExport(ratesExportQuery{
+		From: "USD", To: "RUB",
+		Since: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+	})
+
+	var buf bytes.Buffer
+	w, err := export.NewCSVWriter(&buf)
+	require.NoError(t, err)
+	_, err = job.rows(context.Background(), w)
+	assert.ErrorIs(t, err, services.ErrNoHistory)
+}
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxFavoriteLabel ограничивает подпись пары, как и колонка label
+	maxFavoriteLabel = 64
+
+	stateFavoriteRename = "fav_rename"
+	stateFavoriteAmount = "fav_amount"
+
+	// maxFavoriteFetches ограничивает параллельные запросы курсов для списка избранного
+	maxFavoriteFetches = 8
+)
+
+// handleFavorites показывает избранное пользователя; в группе — личное избранное написавшего
+func (h *BotHandler) handleFavorites(ctx context.Context, message *tgbotapi.Message) {
+	h.showFavorites(ctx, message.Chat.ID, message.From.ID, 0)
+}
+
+// showFavorites отправляет список избранного userID в чат или, если messageID задан, обновляет его на месте
+func (h *BotHandler) showFavorites(ctx context.Context, chatID, userID int64, messageID int) {
+	favorites, err := h.favoritesRepo.GetUserFavorites(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get favorites", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить список избранного."))
+		return
+	}
+
+	if len(favorites) == 0 {
+		text := "🌟 У вас пока нет избранных пар.\n\nЧтобы добавить, отправьте команду: `/fav_USD_RUB` или воспользуйтесь кнопкой «В избранное» после конвертации."
+		if messageID != 0 {
+			edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+			edit.ParseMode = "Markdown"
+			_, _ = h.sender.Send(ctx, edit)
+			return
+		}
+
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "Markdown"
+		h.sendMessage(ctx, msg)
+		return
+	}
+
+	text := h.favoritesText(ctx, favorites)
+	markup := h.favoritesKeyboard(ctx, userID, favorites)
+
+	if messageID != 0 {
+		edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup)
+		edit.ParseMode = "Markdown"
+		_, _ = h.sender.Send(ctx, edit)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = markup
+	h.sendMessage(ctx, msg)
+}
+
+// favoriteRate — курс избранной пары для списка
+type favoriteRate struct {
+	change entities.RateChange
+	err    error
+}
+
+// favoritesText формирует список избранного с текущими курсами и изменением за день
+func (h *BotHandler) favoritesText(ctx context.Context, favorites []entities.UserFavorite) string {
+	rates := h.favoriteRates(ctx, favorites)
+
+	var sb strings.Builder
+	sb.WriteString("⭐ *Ваши избранные пары:*\n\n")
+
+	var current []entities.ExchangeRate
+	stale := false
+	for i, fav := range favorites {
+		title := "*" + fav.Pair() + "*"
+		if fav.Label != "" {
+			title = fmt.Sprintf("*%s* (%s)", escapeMarkdown(fav.Label), fav.Pair())
+		}
+		if fav.Pinned {
+			title = "📌 " + title
+		}
+
+		if rates[i].err != nil {
+			sb.WriteString(fmt.Sprintf("%s: курс недоступен\n", title))
+			continue
+		}
+
+		rate := rates[i].change.Current
+		current = append(current, rate)
+		sb.WriteString(fmt.Sprintf("%s: %.4f%s", title, rate.Rate, formatChange(rates[i].change)))
+		if rate.Stale {
+			sb.WriteString(" ⚠️")
+			stale = true
+		}
+		sb.WriteString("\n")
+	}
+
+	if stale {
+		sb.WriteString("\n" + staleWarning + "\n")
+	}
+	if len(current) > 0 {
+		sb.WriteString("\n" + formatRateSources(current) + "\n")
+	}
+	sb.WriteString(fmt.Sprintf("🕒 Обновлено в %s UTC\n\n", time.Now().UTC().Format("15:04:05")))
+	sb.WriteString("Нажмите на пару для пересчета суммы по умолчанию, ⬆️⬇️ — порядок, ✏️ — настройки.")
+
+	return sb.String()
+}
+
+// favoriteRates запрашивает курсы всех пар параллельно, не более maxFavoriteFetches одновременно
+func (h *BotHandler) favoriteRates(ctx context.Context, favorites []entities.UserFavorite) []favoriteRate {
+	rates := make([]favoriteRate, len(favorites))
+	sem := make(chan struct{}, maxFavoriteFetches)
+
+	var wg sync.WaitGroup
+	for i, fav := range favorites {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			change, err := h.exchangeService.GetDailyChange(ctx, fav.FromCurrency, fav.ToCurrency)
+			if err != nil {
+				logger.FromContext(ctx).Warn("Failed to get favorite rate",
+					zap.String("from", fav.FromCurrency), zap.String("to", fav.ToCurrency), zap.Error(err))
+			}
+			rates[i] = favoriteRate{change: change, err: err}
+		}()
+	}
+	wg.Wait()
+
+	return rates
+}
+
+// formatChange печатает изменение курса за день, например " ▲ +0.35%"
+func formatChange(change entities.RateChange) string {
+	percent, ok := change.Percent()
+	if !ok {
+		return ""
+	}
+	return formatPercentChange(percent)
+}
+
+// formatPercentChange печатает изменение в процентах со стрелкой направления
+func formatPercentChange(percent float64) string {
+	switch {
+	case percent > 0.005:
+		return fmt.Sprintf(" ▲ +%.2f%%", percent)
+	case percent < -0.005:
+		return fmt.Sprintf(" ▼ %.2f%%", percent)
+	default:
+		return " ＝ 0.00%"
+	}
+}
+
+// escapeMarkdown экранирует символы разметки Markdown в пользовательском тексте
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+var markdownEscaper = strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+
+// favoritesKeyboard строит кнопки списка; владелец списка передается в данных кнопок,
+// чтобы в группе другие участники не меняли чужое избранное
+func (h *BotHandler) favoritesKeyboard(ctx context.Context, userID int64, favorites []entities.UserFavorite) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, fav := range favorites {
+		title := fmt.Sprintf("%s · %s %s", fav.Title(), formatAmount(fav.DefaultAmount), fav.FromCurrency)
+		if fav.Pinned {
+			title = "📌 " + title
+		}
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			h.button(ctx, title, callbackFavorite, "conv", fav.ID, fav.UserID),
+			h.button(ctx, "⬆️", callbackFavorite, "up", fav.ID, fav.UserID),
+			h.button(ctx, "⬇️", callbackFavorite, "down", fav.ID, fav.UserID),
+			h.button(ctx, "✏️", callbackFavorite, "edit", fav.ID, fav.UserID),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		h.button(ctx, "🔄 Обновить", callbackFavorite, "refresh", 0, userID),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// showFavoriteSettings заменяет список меню настроек одной пары
+func (h *BotHandler) showFavoriteSettings(ctx context.Context, chatID int64, messageID int, fav entities.UserFavorite) {
+	label := fav.Label
+	if label == "" {
+		label = "нет"
+	}
+	pinned, pinText := "нет", "📌 Закрепить"
+	if fav.Pinned {
+		pinned, pinText = "да", "📍 Открепить"
+	}
+
+	// Подпись задает пользователь, поэтому текст без разметки
+	text := fmt.Sprintf("✏️ %s\n\nПодпись: %s\nСумма по умолчанию: %s %s\nЗакреплена: %s",
+		fav.Pair(), label, formatAmount(fav.DefaultAmount), fav.FromCurrency, pinned)
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			h.button(ctx, "✏️ Подпись", callbackFavorite, "rename", fav.ID, fav.UserID),
+			h.button(ctx, "💰 Сумма", callbackFavorite, "amount", fav.ID, fav.UserID),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			h.button(ctx, pinText, callbackFavorite, "pin", fav.ID, fav.UserID),
+			h.button(ctx, "🗑️ Удалить", callbackFavorite, "del", fav.ID, fav.UserID),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			h.button(ctx, "⬅️ К списку", callbackFavorite, "list", 0, fav.UserID),
+		),
+	)
+
+	_, _ = h.sender.Send(ctx, tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, markup))
+}
+
+// handleFavoriteCallback обрабатывает кнопки списка избранного: действие и ID пары
+func (h *BotHandler) handleFavoriteCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data telegram.CallbackData) {
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+
+	action := data.Arg(0)
+	id, err := data.Int64(1)
+	if err != nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+	userID, err := data.Int64(2)
+	if err != nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+	if userID != callback.From.ID {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "Это чужое избранное: откройте свое через /favorites"))
+		return
+	}
+
+	switch action {
+	case "list":
+		h.showFavorites(ctx, chatID, userID, messageID)
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	case "refresh":
+		h.showFavorites(ctx, chatID, userID, messageID)
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "🔄 Курсы обновлены"))
+		return
+	}
+
+	fav, err := h.favoritesRepo.GetFavorite(ctx, userID, id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.FromContext(ctx).Error("Failed to get favorite", zap.Error(err))
+		}
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "❌ Пара не найдена"))
+		return
+	}
+
+	answer := ""
+	switch action {
+	case "conv":
+		h.convertFavorite(ctx, chatID, fav)
+	case "up", "down":
+		delta := -1
+		if action == "down" {
+			delta = 1
+		}
+		if err = h.favoritesRepo.MoveFavorite(ctx, userID, id, delta); err == nil {
+			h.showFavorites(ctx, chatID, userID, messageID)
+		}
+	case "edit":
+		h.showFavoriteSettings(ctx, chatID, messageID, fav)
+	case "pin":
+		if err = h.favoritesRepo.SetPinned(ctx, userID, id, !fav.Pinned); err == nil {
+			fav.Pinned = !fav.Pinned
+			h.showFavoriteSettings(ctx, chatID, messageID, fav)
+		}
+	case "del":
+		if err = h.favoritesRepo.RemoveFavorite(ctx, userID, fav.FromCurrency, fav.ToCurrency); err == nil {
+			answer = fmt.Sprintf("🗑️ %s удалено из избранного", fav.Pair())
+			h.showFavorites(ctx, chatID, userID, messageID)
+		}
+	case "rename":
+		h.userStates.set(stateKey{chatID, userID}, fmt.Sprintf("%s:%d", stateFavoriteRename, id))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"Введите подпись для %s (до %d символов) или «-», чтобы убрать ее. /cancel — отмена.",
+			fav.Pair(), maxFavoriteLabel)))
+	case "amount":
+		h.userStates.set(stateKey{chatID, userID}, fmt.Sprintf("%s:%d", stateFavoriteAmount, id))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"Введите сумму в %s для пересчета %s в одно нажатие. /cancel — отмена.",
+			fav.FromCurrency, fav.Pair())))
+	}
+
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to update favorite",
+			zap.String("action", action), zap.Error(err))
+		answer = "❌ Не удалось изменить избранное"
+	}
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, answer))
+}
+
+// convertFavorite пересчитывает сумму по умолчанию новым сообщением
+func (h *BotHandler) convertFavorite(ctx context.Context, chatID int64, fav entities.UserFavorite) {
+	query := conversionQuery{Amount: fav.DefaultAmount, From: fav.FromCurrency, To: fav.ToCurrency}
+
+	conversion, err := h.convertQuery(ctx, query)
+	if err != nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatConversion(query, conversion))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = h.createConversionKeyboard(ctx, fav.FromCurrency, fav.ToCurrency)
+	h.sendMessage(ctx, msg)
+}
+
+// handleFavoriteInput принимает подпись или сумму, запрошенную из меню настроек пары
+func (h *BotHandler) handleFavoriteInput(ctx context.Context, message *tgbotapi.Message, state string) {
+	chatID, userID := message.Chat.ID, message.From.ID
+	key := stateKey{chatID, userID}
+
+	name, rawID, _ := strings.Cut(state, ":")
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	text := strings.TrimSpace(message.Text)
+	var reply string
+
+	switch name {
+	case stateFavoriteRename:
+		if text == "-" {
+			text = ""
+		}
+		if utf8.RuneCountInString(text) > maxFavoriteLabel {
+			h.userStates.set(key, state)
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+				fmt.Sprintf("❌ Подпись длиннее %d символов, попробуйте короче.", maxFavoriteLabel)))
+			return
+		}
+		err = h.favoritesRepo.RenameFavorite(ctx, userID, id, text)
+		reply = "✅ Подпись сохранена."
+	case stateFavoriteAmount:
+		amount, parseErr := strconv.ParseFloat(strings.ReplaceAll(text, ",", "."), 64)
+		if parseErr != nil || amount <= 0 {
+			h.userStates.set(key, state)
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Введите положительное число, например 150000."))
+			return
+		}
+		err = h.favoritesRepo.SetDefaultAmount(ctx, userID, id, amount)
+		reply = "✅ Сумма по умолчанию сохранена."
+	default:
+		return
+	}
+
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to update favorite", zap.String("state", name), zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось изменить избранное."))
+		return
+	}
+
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, reply))
+	h.showFavorites(ctx, chatID, userID, 0)
+}
+
+// formatAmount печатает сумму без лишних нулей: 1, 150000, 12.5
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
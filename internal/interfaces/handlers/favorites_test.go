@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFavoritesKeyboard(t *testing.T) {
+	favorites := []entities.UserFavorite{
+		{ID: 7, UserID: 42, FromCurrency: "KZT", ToCurrency: "RUB", Label: "Аренда в Алматы", DefaultAmount: 150000, Pinned: true},
+		{ID: 3, UserID: 42, FromCurrency: "USD", ToCurrency: "RUB", DefaultAmount: 12.5},
+	}
+
+	h := &BotHandler{callbacks: telegram.NewCallbackCodec([]byte("secret"))}
+	ctx := context.Background()
+	markup := h.favoritesKeyboard(ctx, 42, favorites)
+
+	assert.Len(t, markup.InlineKeyboard, 3)
+
+	decode := func(button tgbotapi.InlineKeyboardButton) telegram.CallbackData {
+		data, err := h.callbacks.Decode(ctx, *button.CallbackData)
+		require.NoError(t, err)
+		return data
+	}
+
+	pinned := markup.InlineKeyboard[0]
+	assert.Equal(t, "📌 Аренда в Алматы · 150000 KZT", pinned[0].Text)
+	for i, action := range []string{"conv", "up", "down", "edit"} {
+		data := decode(pinned[i])
+		assert.Equal(t, callbackFavorite, data.Action)
+		assert.Equal(t, []string{action, "7", "42"}, data.Args)
+	}
+
+	assert.Equal(t, "USD/RUB · 12.5 USD", markup.InlineKeyboard[1][0].Text)
+	assert.Equal(t, []string{"refresh", "0", "42"}, decode(markup.InlineKeyboard[2][0]).Args)
+}
+
+func TestCallbackCommand(t *testing.T) {
+	codec := telegram.NewCallbackCodec([]byte("secret"))
+
+	data, err := codec.Encode(context.Background(), telegram.NewCallbackData(callbackConvert, 10, "USD", "RUB"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "conv", callbackCommand(data))
+	assert.Equal(t, "unknown", callbackCommand("conv_10_USD_RUB"))
+	assert.Equal(t, "unknown", callbackCommand("USD/RUB"))
+}
+
+func TestFormatChange(t *testing.T) {
+	change := func(current, previous float64) entities.RateChange {
+		return entities.RateChange{
+			Current:  entities.ExchangeRate{Rate: current},
+			Previous: entities.ExchangeRate{Rate: previous},
+		}
+	}
+
+	assert.Equal(t, " ▲ +1.00%", formatChange(change(101, 100)))
+	assert.Equal(t, " ▼ -0.50%", formatChange(change(99.5, 100)))
+	assert.Equal(t, " ＝ 0.00%", formatChange(change(100, 100)))
+	assert.Equal(t, "", formatChange(change(100, 0)))
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// chatSettingsTTL — сколько настройки чата живут в памяти; другие реплики увидят изменения не позже
+	chatSettingsTTL = time.Minute
+	// maxCachedChats ограничивает кэш настроек, при переполнении он очищается
+	maxCachedChats = 10000
+	// maxTriggerPattern совпадает с размером колонки trigger_pattern
+	maxTriggerPattern = 200
+)
+
+// stateKey — состояние диалога ведется для пользователя в конкретном чате
+type stateKey struct {
+	chatID int64
+	userID int64
+}
+
+// dialogStates хранит ожидаемый ввод; обновления разных чатов обрабатываются параллельно
+type dialogStates struct {
+	mu     sync.Mutex
+	states map[stateKey]string
+}
+
+func newDialogStates() dialogStates {
+	return dialogStates{states: make(map[stateKey]string)}
+}
+
+func (s *dialogStates) set(key stateKey, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[key] = state
+}
+
+func (s *dialogStates) has(key stateKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.states[key]
+	return ok
+}
+
+// take возвращает состояние и сбрасывает его
+func (s *dialogStates) take(key stateKey) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[key]
+	delete(s.states, key)
+	return state, ok
+}
+
+// forgetUser сбрасывает состояния пользователя во всех чатах
+func (s *dialogStates) forgetUser(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.states {
+		if key.userID == userID {
+			delete(s.states, key)
+		}
+	}
+}
+
+// WithChatSettings включает настройки чатов: валюты по умолчанию и шаблон вызова бота в группе
+func WithChatSettings(repo services.ChatSettingsRepository) Option {
+	return func(h *BotHandler) {
+		h.chats = repo
+	}
+}
+
+type chatSettingsEntry struct {
+	settings  entities.ChatSettings
+	trigger   *regexp.Regexp
+	expiresAt time.Time
+}
+
+type chatSettingsCache struct {
+	mu      sync.Mutex
+	entries map[int64]chatSettingsEntry
+}
+
+// chatSettings возвращает настройки чата и скомпилированный шаблон вызова (nil, если не задан);
+// при ошибке базы чат работает без настроек
+func (h *BotHandler) chatSettings(ctx context.Context, chatID int64) (entities.ChatSettings, *regexp.Regexp) {
+	if h.chats == nil {
+		return entities.ChatSettings{ChatID: chatID}, nil
+	}
+
+	h.settingsCache.mu.Lock()
+	entry, ok := h.settingsCache.entries[chatID]
+	h.settingsCache.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.settings, entry.trigger
+	}
+
+	settings, err := h.chats.GetChatSettings(ctx, chatID)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get chat settings", zap.Int64("chat_id", chatID), zap.Error(err))
+		return entities.ChatSettings{ChatID: chatID}, nil
+	}
+
+	entry = chatSettingsEntry{settings: settings, expiresAt: time.Now().Add(chatSettingsTTL)}
+	if settings.TriggerPattern != "" {
+		// Шаблон проверяется при сохранении, ошибка здесь возможна только при ручной правке базы
+		if entry.trigger, err = compileTrigger(settings.TriggerPattern); err != nil {
+			logger.FromContext(ctx).Warn("Invalid chat trigger pattern", zap.Int64("chat_id", chatID), zap.Error(err))
+		}
+	}
+
+	h.settingsCache.mu.Lock()
+	if len(h.settingsCache.entries) >= maxCachedChats {
+		clear(h.settingsCache.entries)
+	}
+	h.settingsCache.entries[chatID] = entry
+	h.settingsCache.mu.Unlock()
+
+	return entry.settings, entry.trigger
+}
+
+func (h *BotHandler) saveChatSettings(ctx context.Context, settings entities.ChatSettings) error {
+	if err := h.chats.SaveChatSettings(ctx, settings); err != nil {
+		return err
+	}
+
+	h.settingsCache.mu.Lock()
+	delete(h.settingsCache.entries, settings.ChatID)
+	h.settingsCache.mu.Unlock()
+	return nil
+}
+
+// compileTrigger компилирует шаблон вызова без учета регистра
+func compileTrigger(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func isGroup(chat *tgbotapi.Chat) bool {
+	return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+}
+
+// addressedMessage решает, обращено ли сообщение группы к боту: команда без адресата или
+// с нашим именем, упоминание, ответ на сообщение бота, ожидаемый ботом ввод или совпадение
+// с шаблоном вызова чата. Возвращает сообщение без @имени бота в команде или упоминании.
+func (h *BotHandler) addressedMessage(ctx context.Context, message *tgbotapi.Message) (*tgbotapi.Message, bool) {
+	if message.IsCommand() {
+		return h.stripCommandTarget(message)
+	}
+
+	if !isGroup(message.Chat) {
+		return message, true
+	}
+
+	if h.mention != nil && h.mention.MatchString(message.Text) {
+		cleaned := *message
+		cleaned.Text = strings.TrimSpace(h.mention.ReplaceAllString(message.Text, ""))
+		cleaned.Entities = nil
+		return &cleaned, true
+	}
+
+	if reply := message.ReplyToMessage; reply != nil && reply.From != nil && reply.From.ID == h.self.ID {
+		return message, true
+	}
+
+	if message.From != nil {
+		if h.userStates.has(stateKey{message.Chat.ID, message.From.ID}) {
+			return message, true
+		}
+	}
+
+	if _, trigger := h.chatSettings(ctx, message.Chat.ID); trigger != nil && trigger.MatchString(message.Text) {
+		return message, true
+	}
+
+	return nil, false
+}
+
+// stripCommandTarget убирает @имя бота из команды вида /start@bot; команды другим ботам пропускаются
+func (h *BotHandler) stripCommandTarget(message *tgbotapi.Message) (*tgbotapi.Message, bool) {
+	command, target, found := strings.Cut(message.CommandWithAt(), "@")
+	if !found {
+		return message, true
+	}
+	if !strings.EqualFold(target, h.self.UserName) {
+		return nil, false
+	}
+
+	// Имя бота — ASCII, поэтому смещения в UTF-16 сдвигаются на его длину в байтах
+	suffix := len(target) + 1
+	cleaned := *message
+	cleaned.Text = message.Text[:1+len(command)] + message.Text[1+len(command)+suffix:]
+	cleaned.Entities = append([]tgbotapi.MessageEntity(nil), message.Entities...)
+	cleaned.Entities[0].Length -= suffix
+	for i := 1; i < len(cleaned.Entities); i++ {
+		cleaned.Entities[i].Offset -= suffix
+	}
+	return &cleaned, true
+}
+
+// canConfigureChat разрешает менять настройки группы ее администраторам, анонимным
+// администраторам и операторам бота; в личном чате пользователь настраивает его сам
+func (h *BotHandler) canConfigureChat(ctx context.Context, message *tgbotapi.Message) bool {
+	if !isGroup(message.Chat) {
+		return true
+	}
+	if message.SenderChat != nil && message.SenderChat.ID == message.Chat.ID {
+		return true
+	}
+	if message.From == nil {
+		return false
+	}
+	if h.isAdmin(message.From) {
+		return true
+	}
+
+	resp, err := h.sender.Request(ctx, tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: message.Chat.ID, UserID: message.From.ID},
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get chat member", zap.Error(err))
+		return false
+	}
+
+	var member tgbotapi.ChatMember
+	if err := json.Unmarshal(resp.Result, &member); err != nil {
+		logger.FromContext(ctx).Warn("Failed to decode chat member", zap.Error(err))
+		return false
+	}
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// handleDefaultsCommand показывает или меняет валюты по умолчанию: /defaults [FROM TO|off]
+func (h *BotHandler) handleDefaultsCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.chats == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Настройки чата недоступны."))
+		return
+	}
+
+	settings, _ := h.chatSettings(ctx, chatID)
+	args := strings.Fields(strings.ToUpper(message.CommandArguments()))
+
+	if len(args) == 0 {
+		text := "Валюты по умолчанию не заданы.\nИспользование: /defaults USD RUB или /defaults off"
+		if settings.HasDefaults() {
+			text = fmt.Sprintf("Валюты по умолчанию: %s → %s\nЗапрос «100» считается как %s в %s, «100 %s» — как %s в %s.",
+				settings.DefaultFrom, settings.DefaultTo, settings.DefaultFrom, settings.DefaultTo,
+				settings.DefaultTo, settings.DefaultTo, settings.DefaultFrom)
+		}
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, text))
+		return
+	}
+
+	if !h.canConfigureChat(ctx, message) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Менять настройки группы могут только ее администраторы."))
+		return
+	}
+
+	var reply string
+	switch {
+	case len(args) == 1 && args[0] == "OFF":
+		settings.DefaultFrom, settings.DefaultTo = "", ""
+		reply = "✅ Валюты по умолчанию сброшены."
+	case len(args) == 2 && isCurrencyCode(args[0]) && isCurrencyCode(args[1]) && args[0] != args[1]:
+		settings.DefaultFrom, settings.DefaultTo = args[0], args[1]
+		reply = fmt.Sprintf("✅ Валюты по умолчанию: %s → %s", args[0], args[1])
+	default:
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Использование: /defaults USD RUB или /defaults off"))
+		return
+	}
+
+	settings.UpdatedBy = message.From.ID
+	if err := h.saveChatSettings(ctx, settings); err != nil {
+		logger.FromContext(ctx).Error("Failed to save chat defaults", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось сохранить настройки."))
+		return
+	}
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, reply))
+}
+
+// handleTriggerCommand показывает или меняет шаблон вызова бота в группе: /trigger [шаблон|off]
+func (h *BotHandler) handleTriggerCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.chats == nil || !isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Шаблон вызова настраивается только в группах."))
+		return
+	}
+
+	settings, _ := h.chatSettings(ctx, chatID)
+	pattern := strings.TrimSpace(message.CommandArguments())
+
+	if pattern == "" {
+		text := "Шаблон вызова не задан: бот отвечает на команды, упоминания и ответы на свои сообщения.\n" +
+			"Использование: /trigger <регулярное выражение> или /trigger off"
+		if settings.TriggerPattern != "" {
+			text = "Шаблон вызова: " + settings.TriggerPattern
+		}
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, text))
+		return
+	}
+
+	if !h.canConfigureChat(ctx, message) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Менять настройки группы могут только ее администраторы."))
+		return
+	}
+
+	reply := "✅ Шаблон вызова сброшен."
+	if strings.EqualFold(pattern, "off") {
+		pattern = ""
+	} else {
+		if len(pattern) > maxTriggerPattern {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+				fmt.Sprintf("❌ Шаблон длиннее %d символов.", maxTriggerPattern)))
+			return
+		}
+		if _, err := compileTrigger(pattern); err != nil {
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Некорректное регулярное выражение: "+err.Error()))
+			return
+		}
+		// Без отключенного режима приватности Telegram не присылает боту остальные сообщения группы
+		reply = "✅ Шаблон вызова сохранен. Бот увидит обычные сообщения группы, только если у него отключен режим приватности."
+	}
+
+	settings.TriggerPattern = pattern
+	settings.UpdatedBy = message.From.ID
+	if err := h.saveChatSettings(ctx, settings); err != nil {
+		logger.FromContext(ctx).Error("Failed to save chat trigger", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось сохранить настройки."))
+		return
+	}
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, reply))
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
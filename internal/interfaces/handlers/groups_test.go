@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/pkg/money"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeChatSettings struct {
+	settings map[int64]entities.ChatSettings
+}
+
+func (f *fakeChatSettings) GetChatSettings(_ context.Context, chatID int64) (entities.ChatSettings, error) {
+	if s, ok := f.settings[chatID]; ok {
+		return s, nil
+	}
+	return entities.ChatSettings{ChatID: chatID}, nil
+}
+
+func (f *fakeChatSettings) SaveChatSettings(_ context.Context, s entities.ChatSettings) error {
+	f.settings[s.ChatID] = s
+	return nil
+}
+
+func newGroupHandler(settings ...entities.ChatSettings) *BotHandler {
+	repo := &fakeChatSettings{settings: make(map[int64]entities.ChatSettings)}
+	for _, s := range settings {
+		repo.settings[s.ChatID] = s
+	}
+
+	return &BotHandler{
+		userStates:    newDialogStates(),
+		self:          tgbotapi.User{ID: 100, UserName: "RatesBot", IsBot: true},
+		mention:       regexp.MustCompile(`(?i)@RatesBot\b`),
+		chats:         repo,
+		settingsCache: chatSettingsCache{entries: make(map[int64]chatSettingsEntry)},
+	}
+}
+
+func groupMessage(text string, entities ...tgbotapi.MessageEntity) *tgbotapi.Message {
+	return &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: 7},
+		Chat:      &tgbotapi.Chat{ID: -500, Type: "supergroup"},
+		Text:      text,
+		Entities:  entities,
+	}
+}
+
+func TestAddressedMessage(t *testing.T) {
+	ctx := context.Background()
+	h := newGroupHandler(entities.ChatSettings{ChatID: -500, TriggerPattern: `^курс`})
+
+	command := func(text string, length int) *tgbotapi.Message {
+		return groupMessage(text, tgbotapi.MessageEntity{Type: "bot_command", Offset: 0, Length: length})
+	}
+
+	message, ok := h.addressedMessage(ctx, command("/fav_USD_RUB@ratesbot", 21))
+	require.True(t, ok)
+	assert.Equal(t, "/fav_USD_RUB", message.Text)
+	assert.Equal(t, "fav_USD_RUB", message.Command())
+
+	message, ok = h.addressedMessage(ctx, command("/defaults@RatesBot usd rub", 18))
+	require.True(t, ok)
+	assert.Equal(t, "defaults", message.Command())
+	assert.Equal(t, "usd rub", message.CommandArguments())
+
+	_, ok = h.addressedMessage(ctx, command("/start@OtherBot", 15))
+	assert.False(t, ok)
+
+	_, ok = h.addressedMessage(ctx, command("/help", 5))
+	assert.True(t, ok)
+
+	message, ok = h.addressedMessage(ctx, groupMessage("@ratesbot 100 usd rub"))
+	require.True(t, ok)
+	assert.Equal(t, "100 usd rub", message.Text)
+
+	reply := groupMessage("50 eur")
+	reply.ReplyToMessage = &tgbotapi.Message{From: &h.self}
+	_, ok = h.addressedMessage(ctx, reply)
+	assert.True(t, ok)
+
+	_, ok = h.addressedMessage(ctx, groupMessage("Курс доллара какой?"))
+	assert.True(t, ok)
+
+	_, ok = h.addressedMessage(ctx, groupMessage("100 usd rub, кто идет обедать?"))
+	assert.False(t, ok)
+
+	h.userStates.set(stateKey{-500, 7}, "fav_rename:3")
+	_, ok = h.addressedMessage(ctx, groupMessage("Аренда"))
+	assert.True(t, ok)
+
+	private := &tgbotapi.Message{From: &tgbotapi.User{ID: 7}, Chat: &tgbotapi.Chat{ID: 7, Type: "private"}, Text: "100 usd"}
+	message, ok = h.addressedMessage(ctx, private)
+	assert.True(t, ok)
+	assert.Same(t, private, message)
+}
+
+func TestParseQuery_ChatDefaults(t *testing.T) {
+	defaults := entities.ChatSettings{DefaultFrom: "USD", DefaultTo: "RUB"}
+
+	cases := []struct {
+		text     string
+		settings entities.ChatSettings
+		want     conversionQuery
+	}{
+		{"100 EUR to USD", defaults, conversionQuery{Amount: 100, From: "EUR", To: "USD"}},
+		{"150", defaults, conversionQuery{Amount: 150, From: "USD", To: "RUB"}},
+		{"20 eur", defaults, conversionQuery{Amount: 20, From: "EUR", To: "RUB"}},
+		{"5000 rub", defaults, conversionQuery{Amount: 5000, From: "RUB", To: "USD"}},
+		{"12,5 EUR/USD", entities.ChatSettings{}, conversionQuery{Amount: 12.5, From: "EUR", To: "USD"}},
+	}
+	for _, tc := range cases {
+		got, err := parseQuery(tc.text, tc.settings)
+		require.NoError(t, err, tc.text)
+		assert.Equal(t, tc.want, got, tc.text)
+	}
+
+	_, err := parseQuery("100 EUR", entities.ChatSettings{})
+	assert.Error(t, err)
+}
+
+func TestChatSettingsCacheInvalidatedOnSave(t *testing.T) {
+	ctx := context.Background()
+	h := newGroupHandler()
+
+	_, trigger := h.chatSettings(ctx, -500)
+	assert.Nil(t, trigger)
+
+	require.NoError(t, h.saveChatSettings(ctx, entities.ChatSettings{ChatID: -500, TriggerPattern: "курс"}))
+
+	_, trigger = h.chatSettings(ctx, -500)
+	require.NotNil(t, trigger)
+	assert.True(t, trigger.MatchString("КУРС евро"))
+}
+
+func TestAutoConvertState_CooldownAndRepeat(t *testing.T) {
+	state := newAutoConvertState()
+	now := time.Now()
+	tenge := money.Mention{Amount: 4500, Currency: "KZT"}
+	dollars := money.Mention{Amount: 20, Currency: "USD"}
+
+	fresh, skipped := state.reserve(-500, []money.Mention{tenge}, now)
+	assert.Empty(t, skipped)
+	assert.Equal(t, []money.Mention{tenge}, fresh)
+
+	_, skipped = state.reserve(-500, []money.Mention{tenge}, now.Add(time.Minute))
+	assert.Equal(t, "repeat", skipped)
+
+	_, skipped = state.reserve(-500, []money.Mention{dollars}, now.Add(time.Second))
+	assert.Equal(t, "cooldown", skipped)
+
+	fresh, skipped = state.reserve(-600, []money.Mention{dollars}, now.Add(time.Second))
+	assert.Empty(t, skipped)
+	assert.Len(t, fresh, 1)
+
+	fresh, skipped = state.reserve(-500, []money.Mention{tenge, dollars}, now.Add(time.Minute))
+	assert.Empty(t, skipped)
+	assert.Equal(t, []money.Mention{dollars}, fresh)
+
+	_, skipped = state.reserve(-500, []money.Mention{tenge}, now.Add(autoConvertRepeat+time.Minute))
+	assert.Empty(t, skipped)
+}
+
+func TestFormatMoney(t *testing.T) {
+	assert.Equal(t, "4 500", formatMoney(4500))
+	assert.Equal(t, "812.35", formatMoney(812.349))
+	assert.Equal(t, "1 234 567.50", formatMoney(1234567.5))
+	assert.Equal(t, "0.05", formatMoney(0.05))
+}
+
+func TestChatSettings_AutoTargets(t *testing.T) {
+	settings := entities.ChatSettings{DefaultFrom: "USD", DefaultTo: "RUB"}
+	assert.Equal(t, []string{"RUB", "USD"}, settings.AutoTargets())
+
+	settings.AutoCurrencies = "EUR,KZT"
+	assert.Equal(t, []string{"EUR", "KZT"}, settings.AutoTargets())
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	historyPageSize = 8
+	// maxHistoryInput ограничивает сохраняемый текст запроса, как колонка input_text
+	maxHistoryInput = 256
+	historyRowSize  = 4
+)
+
+// Действия кнопок истории
+const (
+	historyPage   = "page"
+	historyRepeat = "repeat"
+	historyClear  = "clear"
+	historyKeep   = "keep"
+)
+
+// WithConversions включает историю конвертаций; retention — срок хранения, который видит пользователь
+func WithConversions(repo services.ConversionsRepository, retention time.Duration) Option {
+	return func(h *BotHandler) {
+		h.conversions = repo
+		h.historyRetention = retention
+	}
+}
+
+// recordConversion сохраняет конвертацию в историю пользователя; ошибка не мешает ответу
+func (h *BotHandler) recordConversion(ctx context.Context, userID, chatID int64, input string, query conversionQuery, conversion entities.Conversion) {
+	if h.conversions == nil {
+		return
+	}
+
+	err := h.conversions.AddConversion(ctx, entities.ConversionRecord{
+		UserID:   userID,
+		ChatID:   chatID,
+		Input:    truncateRunes(strings.TrimSpace(input), maxHistoryInput),
+		Amount:   query.Amount,
+		From:     query.From,
+		To:       query.To,
+		Rate:     conversion.Rate.Rate,
+		Result:   conversion.Result,
+		Provider: conversion.Rate.Provider,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to record conversion", zap.Error(err))
+	}
+}
+
+// handleHistoryCommand показывает историю конвертаций: /history [clear]
+func (h *BotHandler) handleHistoryCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.conversions == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ История недоступна."))
+		return
+	}
+	// История личная, в группе ее увидели бы все участники
+	if isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "🔒 История доступна только в личном чате с ботом."))
+		return
+	}
+
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+		h.showHistory(ctx, chatID, message.From.ID, 0, 0)
+	case "clear":
+		msg := tgbotapi.NewMessage(chatID, "Удалить всю историю конвертаций? Это нельзя отменить.")
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			h.button(ctx, "🗑 Удалить", callbackHistory, historyClear),
+			h.button(ctx, "Отмена", callbackHistory, historyKeep),
+		))
+		h.sendMessage(ctx, msg)
+	default:
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Использование:\n/history — последние конвертации\n/history clear — удалить историю"))
+	}
+}
+
+// showHistory отправляет страницу истории или, если messageID задан, обновляет ее на месте
+func (h *BotHandler) showHistory(ctx context.Context, chatID, userID int64, page, messageID int) {
+	total, err := h.conversions.CountConversions(ctx, userID)
+	var records []entities.ConversionRecord
+	if err == nil {
+		page = min(max(page, 0), max(pages(total, historyPageSize)-1, 0))
+		records, err = h.conversions.UserConversions(ctx, userID, page*historyPageSize, historyPageSize)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get conversion history", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить историю."))
+		return
+	}
+
+	text := "🕘 История пуста: здесь появятся ваши конвертации, например «100 USD to RUB»."
+	var markup tgbotapi.InlineKeyboardMarkup
+	if len(records) > 0 {
+		text = formatHistory(records, page, pages(total, historyPageSize), h.historyRetention)
+		markup = h.historyKeyboard(ctx, records, page, pages(total, historyPageSize))
+	}
+
+	if messageID != 0 {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+		if len(records) > 0 {
+			edit.ReplyMarkup = &markup
+		}
+		_, _ = h.sender.Send(ctx, edit)
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	if len(records) > 0 {
+		msg.ReplyMarkup = markup
+	}
+	h.sendMessage(ctx, msg)
+}
+
+// pages — число страниц по size записей
+func pages(total, size int) int {
+	return (total + size - 1) / size
+}
+
+// formatHistory печатает страницу истории; записи нумеруются с начала истории,
+// например «3. 14.03.2025 12:30 — 100 USD → 9 250 RUB по 92.5 (cbr)»
+func formatHistory(records []entities.ConversionRecord, page, pages int, retention time.Duration) string {
+	var sb strings.Builder
+	sb.WriteString("🕘 История конвертаций")
+	if pages > 1 {
+		fmt.Fprintf(&sb, " (стр. %d из %d)", page+1, pages)
+	}
+	sb.WriteString("\n\n")
+
+	for i, record := range records {
+		fmt.Fprintf(&sb, "%d. %s — %s %s → %s %s по %s", page*historyPageSize+i+1,
+			record.CreatedAt.UTC().Format("02.01.2006 15:04"),
+			formatMoney(record.Amount), record.From, formatMoney(record.Result), record.To,
+			formatRate(record.Rate))
+		if record.Provider != "" {
+			fmt.Fprintf(&sb, " (%s)", record.Provider)
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\nНажмите номер, чтобы пересчитать по сегодняшнему курсу. Время UTC.")
+	if days := int(retention.Hours() / 24); days > 0 {
+		fmt.Fprintf(&sb, "\nИстория хранится %d дн. /history clear — удалить ее сейчас.", days)
+	} else {
+		sb.WriteString("\n/history clear — удалить историю.")
+	}
+	return sb.String()
+}
+
+// formatRate печатает курс без лишних нулей, сохраняя значащие цифры у малых курсов
+func formatRate(rate float64) string {
+	text := fmt.Sprintf("%.6f", rate)
+	if rate >= 1 {
+		text = fmt.Sprintf("%.4f", rate)
+	}
+	return strings.TrimRight(strings.TrimRight(text, "0"), ".")
+}
+
+func (h *BotHandler) historyKeyboard(ctx context.Context, records []entities.ConversionRecord, page, pages int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for i, record := range records {
+		label := fmt.Sprintf("🔁 %d", page*historyPageSize+i+1)
+		row = append(row, h.button(ctx, label, callbackHistory, historyRepeat, record.ID))
+		if len(row) == historyRowSize {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		nav = append(nav, h.button(ctx, "◀️ Новее", callbackHistory, historyPage, page-1))
+	}
+	if page+1 < pages {
+		nav = append(nav, h.button(ctx, "Старше ▶️", callbackHistory, historyPage, page+1))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handleHistoryCallback листает историю, повторяет конвертацию или удаляет историю
+func (h *BotHandler) handleHistoryCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data telegram.CallbackData) {
+	if h.conversions == nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+	chatID, messageID := callback.Message.Chat.ID, callback.Message.MessageID
+	userID := callback.From.ID
+
+	switch data.Arg(0) {
+	case historyPage:
+		page, err := data.Int64(1)
+		if err != nil {
+			break
+		}
+		h.showHistory(ctx, chatID, userID, int(page), messageID)
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	case historyRepeat:
+		id, err := data.Int64(1)
+		if err != nil {
+			break
+		}
+		answer := h.repeatConversion(ctx, chatID, userID, id)
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, answer))
+		return
+	case historyClear:
+		text, answer := "🗑 История конвертаций удалена.", ""
+		if _, err := h.conversions.DeleteConversions(ctx, userID); err != nil {
+			logger.FromContext(ctx).Error("Failed to delete conversion history", zap.Error(err))
+			text, answer = callback.Message.Text, "❌ Не удалось удалить историю"
+		}
+		_, _ = h.sender.Send(ctx, tgbotapi.NewEditMessageText(chatID, messageID, text))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, answer))
+		return
+	case historyKeep:
+		_, _ = h.sender.Send(ctx, tgbotapi.NewEditMessageText(chatID, messageID, "История сохранена."))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	}
+
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+}
+
+// repeatConversion пересчитывает запись истории по текущему курсу и сравнивает с прошлым результатом;
+// возвращает текст ответа на нажатие кнопки
+func (h *BotHandler) repeatConversion(ctx context.Context, chatID, userID, id int64) string {
+	record, err := h.conversions.GetConversion(ctx, userID, id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "Запись уже удалена из истории"
+	case err != nil:
+		logger.FromContext(ctx).Error("Failed to get conversion", zap.Error(err))
+		return "❌ Ошибка"
+	}
+
+	query := conversionQuery{Amount: record.Amount, From: record.From, To: record.To}
+	conversion, err := h.convertQuery(ctx, query)
+	if err != nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ "+err.Error()))
+		return ""
+	}
+
+	text := formatConversion(query, conversion) + fmt.Sprintf("\n\n🕘 %s было %s %s по %s",
+		record.CreatedAt.UTC().Format("02.01.2006"), formatMoney(record.Result), record.To, formatRate(record.Rate))
+	if record.Result != 0 {
+		text += formatPercentChange((conversion.Result - record.Result) / record.Result * 100)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = h.createConversionKeyboard(ctx, query.From, query.To)
+	h.sendMessage(ctx, msg)
+
+	h.recordConversion(ctx, userID, chatID, record.Input, query, conversion)
+	return ""
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatHistory(t *testing.T) {
+	records := []entities.ConversionRecord{
+		{Amount: 100, From: "USD", To: "RUB", Rate: 92.5, Result: 9250, Provider: "cbr",
+			CreatedAt: time.Date(2025, 3, 14, 12, 30, 0, 0, time.UTC)},
+		{Amount: 4500, From: "KZT", To: "RUB", Rate: 0.18123, Result: 815.54,
+			CreatedAt: time.Date(2025, 3, 13, 9, 5, 0, 0, time.UTC)},
+	}
+
+	text := formatHistory(records, 1, 3, 90*24*time.Hour)
+	lines := strings.Split(text, "\n")
+
+	assert.Equal(t, "🕘 История конвертаций (стр. 2 из 3)", lines[0])
+	assert.Equal(t, "9. 14.03.2025 12:30 — 100 USD → 9 250 RUB по 92.5 (cbr)", lines[2])
+	assert.Equal(t, "10. 13.03.2025 09:05 — 4 500 KZT → 815.54 RUB по 0.18123", lines[3])
+	assert.Contains(t, text, "История хранится 90 дн.")
+}
+
+func TestPages(t *testing.T) {
+	assert.Equal(t, 0, pages(0, historyPageSize))
+	assert.Equal(t, 1, pages(historyPageSize, historyPageSize))
+	assert.Equal(t, 2, pages(historyPageSize+1, historyPageSize))
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// buttonCommands сопоставляет кнопки основной клавиатуры с командами для меток метрик
+var buttonCommands = map[string]string{
+	"ℹ️ Помощь":        "help",
+	"💱 Конвертировать": "convert",
+	"⭐ Избранное":      "favorites",
+	"📊 Курсы валют":    "rates",
+}
+
+// knownCommands ограничивает набор значений метки command
+var knownCommands = map[string]bool{
+	"start":  true,
+	"help":   true,
+	"fav":    true,
+	"cancel": true,
+
+	"favorites":   true,
+	"rates":       true,
+	"defaults":    true,
+	"trigger":     true,
+	"autoconvert": true,
+
+	"trip":     true,
+	"spent":    true,
+	"expenses": true,
+	"report":   true,
+	"split":    true,
+	"settle":   true,
+	"currency": true,
+
+	"portfolio": true,
+	"history":   true,
+	"export":    true,
+	"mydata":    true,
+	"forgetme":  true,
+
+	"stats":     true,
+	"providers": true,
+	"cache":     true,
+	"broadcast": true,
+	"bans":      true,
+	"unban":     true,
+}
+
+// callbackActions — известные действия инлайн-кнопок
+var callbackActions = map[string]bool{
+	callbackConvert:        true,
+	callbackAddFavorite:    true,
+	callbackRemoveFavorite: true,
+	callbackBroadcast:      true,
+	callbackFavorite:       true,
+	callbackDeleteExpense:  true,
+	callbackSplit:          true,
+	callbackSettle:         true,
+	callbackHistory:        true,
+	callbackForget:         true,
+}
+
+// updateLabels возвращает тип обновления и команду с ограниченным набором значений,
+// чтобы пользовательский текст не раздувал число временных рядов
+func updateLabels(update tgbotapi.Update) (string, string) {
+	switch {
+	case update.Message != nil:
+		return "message", messageCommand(update.Message)
+	case update.CallbackQuery != nil:
+		return "callback", callbackCommand(update.CallbackQuery.Data)
+	default:
+		return "other", "none"
+	}
+}
+
+func messageCommand(message *tgbotapi.Message) string {
+	if command, ok := buttonCommands[message.Text]; ok {
+		return command
+	}
+
+	if strings.HasPrefix(message.Text, "/fav_") {
+		return "fav"
+	}
+
+	if message.IsCommand() {
+		if command := message.Command(); knownCommands[command] {
+			return command
+		}
+		return "unknown"
+	}
+
+	return "text"
+}
+
+// callbackCommand берет действие без проверки подписи: поддельные данные
+// отклоняются при обработке и не выходят за известный набор меток
+func callbackCommand(data string) string {
+	if action := telegram.CallbackAction(data); callbackActions[action] {
+		return action
+	}
+	return "unknown"
+}
+
+// updateFields возвращает поля логгера, общие для всех записей об обновлении
+func updateFields(update tgbotapi.Update, updateType, command string) []zap.Field {
+	fields := []zap.Field{
+		zap.Int("update_id", update.UpdateID),
+		zap.String("update_type", updateType),
+		zap.String("command", command),
+	}
+
+	switch {
+	case update.Message != nil:
+		fields = append(fields,
+			zap.Int64("chat_id", update.Message.Chat.ID),
+			logger.UserText("text", update.Message.Text),
+		)
+		if update.Message.From != nil {
+			fields = append(fields, zap.Int64("user_id", update.Message.From.ID))
+		}
+	case update.CallbackQuery != nil:
+		if update.CallbackQuery.From != nil {
+			fields = append(fields, zap.Int64("user_id", update.CallbackQuery.From.ID))
+		}
+		if update.CallbackQuery.Message != nil {
+			fields = append(fields, zap.Int64("chat_id", update.CallbackQuery.Message.Chat.ID))
+		}
+	}
+
+	return fields
+}
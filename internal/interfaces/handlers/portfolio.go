@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// defaultPortfolioCurrency — валюта оценки, если пользователь не выбрал другую
+const defaultPortfolioCurrency = "RUB"
+
+const portfolioUsage = "Использование:\n/portfolio add 1000 USD — добавить\n/portfolio remove 200 USD — уменьшить, /portfolio remove USD — убрать валюту\n/portfolio EUR — оценить в другой валюте"
+
+// WithHoldings включает портфель валют пользователя
+func WithHoldings(repo services.HoldingsRepository) Option {
+	return func(h *BotHandler) {
+		h.holdings = repo
+	}
+}
+
+// handlePortfolioCommand показывает и меняет портфель: /portfolio [валюта|add сумма|remove [сумма] валюта]
+func (h *BotHandler) handlePortfolioCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.holdings == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Портфель недоступен."))
+		return
+	}
+	// Портфель — личные данные, в группе его увидели бы все участники
+	if isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "🔒 Портфель доступен только в личном чате с ботом."))
+		return
+	}
+
+	action, args, _ := strings.Cut(strings.TrimSpace(message.CommandArguments()), " ")
+	switch strings.ToLower(action) {
+	case "add":
+		h.addHolding(ctx, message, args)
+	case "remove":
+		h.removeHolding(ctx, message, args)
+	case "":
+		h.showPortfolio(ctx, message, "")
+	default:
+		if code := strings.ToUpper(action); isCurrencyCode(code) && args == "" {
+			h.showPortfolio(ctx, message, code)
+			return
+		}
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, portfolioUsage))
+	}
+}
+
+func (h *BotHandler) addHolding(ctx context.Context, message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	amount, currency, rest, ok := parseMoneyPrefix(args)
+	if !ok || strings.TrimSpace(rest) != "" {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, portfolioUsage))
+		return
+	}
+
+	holding, err := h.holdings.AddHolding(ctx, message.From.ID, currency, amount)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to add holding", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось сохранить позицию."))
+		return
+	}
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+		fmt.Sprintf("✅ В портфеле %s %s. Оценка: /portfolio", formatMoney(holding.Amount), holding.Currency)))
+}
+
+// removeHolding уменьшает позицию на сумму или, если сумма не указана или не меньше позиции, убирает валюту
+func (h *BotHandler) removeHolding(ctx context.Context, message *tgbotapi.Message, args string) {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	amount, currency, rest, ok := parseMoneyPrefix(args)
+	if !ok {
+		currency, amount = strings.ToUpper(strings.TrimSpace(args)), math.Inf(1)
+	} else if strings.TrimSpace(rest) != "" {
+		currency = ""
+	}
+	if !isCurrencyCode(currency) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, portfolioUsage))
+		return
+	}
+
+	holdings, err := h.holdings.GetHoldings(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get holdings", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить портфель."))
+		return
+	}
+	var held float64
+	for _, holding := range holdings {
+		if holding.Currency == currency {
+			held = holding.Amount
+		}
+	}
+
+	reply := fmt.Sprintf("✅ %s убрана из портфеля.", currency)
+	if amount < held {
+		holding, err := h.holdings.AddHolding(ctx, userID, currency, -amount)
+		if err == nil {
+			reply = fmt.Sprintf("✅ В портфеле %s %s.", formatMoney(holding.Amount), holding.Currency)
+		}
+	} else {
+		err = h.holdings.RemoveHolding(ctx, userID, currency)
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		reply = fmt.Sprintf("В портфеле нет %s.", currency)
+	case err != nil:
+		logger.FromContext(ctx).Error("Failed to remove holding", zap.Error(err))
+		reply = "❌ Не удалось изменить портфель."
+	}
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, reply))
+}
+
+// showPortfolio оценивает портфель в currency, а без нее — в валюте взаиморасчетов
+// пользователя или в валюте по умолчанию личного чата
+func (h *BotHandler) showPortfolio(ctx context.Context, message *tgbotapi.Message, currency string) {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+
+	holdings, err := h.holdings.GetHoldings(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get holdings", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить портфель."))
+		return
+	}
+	if len(holdings) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "💼 Портфель пуст.\n\n"+portfolioUsage))
+		return
+	}
+
+	if currency == "" && h.users != nil {
+		if preferred, err := h.users.PreferredCurrencies(ctx, []int64{userID}); err == nil {
+			currency = preferred[userID]
+		}
+	}
+	if settings, _ := h.chatSettings(ctx, chatID); currency == "" {
+		currency = settings.DefaultTo
+	}
+	if currency == "" {
+		currency = defaultPortfolioCurrency
+	}
+
+	valuation := services.ValuePortfolio(ctx, h.exchangeService, holdings, currency)
+	h.sendMessage(ctx, tgbotapi.NewMessage(chatID, formatPortfolio(valuation)))
+}
+
+// formatPortfolio печатает оценку портфеля:
+//
+//	USD: 1 000 → 100 000 RUB · 50.0% ▲ +2.04%
+func formatPortfolio(valuation entities.PortfolioValuation) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "💼 Портфель: %s %s\n", formatMoney(valuation.Total), valuation.Currency)
+	if diff, percent, ok := valuation.Change(); ok {
+		sign := "+"
+		if diff < 0 {
+			sign = "−"
+		}
+		fmt.Fprintf(&sb, "За день: %s%s %s%s\n", sign, formatMoney(math.Abs(diff)), valuation.Currency, formatPercentChange(percent))
+	}
+	sb.WriteString("\n")
+
+	for _, position := range valuation.Positions {
+		if position.Currency == valuation.Currency {
+			fmt.Fprintf(&sb, "%s: %s", position.Currency, formatMoney(position.Amount))
+		} else {
+			fmt.Fprintf(&sb, "%s: %s → %s %s", position.Currency, formatMoney(position.Amount),
+				formatMoney(position.Value), valuation.Currency)
+		}
+		fmt.Fprintf(&sb, " · %.1f%%", position.Share)
+		if change, ok := position.Change(); ok && position.Currency != valuation.Currency {
+			sb.WriteString(formatPercentChange(change))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(valuation.Unpriced) > 0 {
+		fmt.Fprintf(&sb, "\n⚠️ Нет курса для %s: в итог не вошло.", strings.Join(valuation.Unpriced, ", "))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
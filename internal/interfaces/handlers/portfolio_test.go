@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatPortfolio(t *testing.T) {
+	valuation := entities.PortfolioValuation{
+		Currency: "RUB",
+		Total:    200000,
+		Previous: 198000,
+		Positions: []entities.PositionValue{
+			{Currency: "USD", Amount: 1000, Value: 100000, Previous: 98000, Share: 50},
+			{Currency: "RUB", Amount: 80000, Value: 80000, Previous: 80000, Share: 40},
+			{Currency: "KZT", Amount: 100000, Value: 20000, Share: 10},
+		},
+		Unpriced: []string{"XAU"},
+	}
+
+	text := formatPortfolio(valuation)
+	assert.Contains(t, text, "💼 Портфель: 200 000 RUB\nЗа день: +2 000 RUB ▲ +1.01%")
+	assert.Contains(t, text, "USD: 1 000 → 100 000 RUB · 50.0% ▲ +2.04%")
+	assert.Contains(t, text, "RUB: 80 000 · 40.0%\n")
+	assert.Contains(t, text, "KZT: 100 000 → 20 000 RUB · 10.0%\n")
+	assert.Contains(t, text, "Нет курса для XAU")
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// Действия кнопок подтверждения /forgetme
+const (
+	forgetConfirm = "confirm"
+	forgetCancel  = "cancel"
+)
+
+// WithUserData включает /mydata и /forgetme по реестру хранилищ с данными пользователей
+func WithUserData(registry *services.UserDataRegistry) Option {
+	return func(h *BotHandler) {
+		h.userData = registry
+	}
+}
+
+// userDataArchive — содержимое файла /mydata
+type userDataArchive struct {
+	UserID     int64             `json:"user_id"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Tables     services.UserRows `json:"tables"`
+}
+
+// handleMyDataCommand отправляет пользователю JSON со всеми данными о нем
+func (h *BotHandler) handleMyDataCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID, userID := message.Chat.ID, message.From.ID
+	if !h.privateUserData(ctx, message) {
+		return
+	}
+
+	rows, err := h.userData.Export(ctx, userID)
+	var archive []byte
+	if err == nil {
+		archive, err = json.MarshalIndent(userDataArchive{UserID: userID, ExportedAt: time.Now().UTC(), Tables: rows}, "", "  ")
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to export user data", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось собрать данные, попробуйте позже."))
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: "mydata.json", Bytes: archive})
+	doc.Caption = "📦 Все, что бот хранит о вас. Удалить эти данные: /forgetme"
+	if _, err := h.sender.Send(ctx, doc); err != nil {
+		logger.FromContext(ctx).Error("Failed to send user data", zap.Error(err))
+	}
+}
+
+// handleForgetMeCommand просит подтвердить удаление всех данных пользователя
+func (h *BotHandler) handleForgetMeCommand(ctx context.Context, message *tgbotapi.Message) {
+	if !h.privateUserData(ctx, message) {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(message.Chat.ID, "Удалить все ваши данные: избранное, настройки, портфель, историю, "+
+		"расходы поездок и общие счета? Ваши записи в группах останутся без вашего имени и ID, "+
+		"чтобы у остальных участников не изменились долги. Это нельзя отменить.")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		h.button(ctx, "🗑 Удалить все", callbackForget, forgetConfirm),
+		h.button(ctx, "Отмена", callbackForget, forgetCancel),
+	))
+	h.sendMessage(ctx, msg)
+}
+
+// privateUserData проверяет, что команды данных пользователя доступны и вызваны в личном чате
+func (h *BotHandler) privateUserData(ctx context.Context, message *tgbotapi.Message) bool {
+	chatID := message.Chat.ID
+	if h.userData == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Команда недоступна."))
+		return false
+	}
+	if isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "🔒 Команда доступна только в личном чате с ботом."))
+		return false
+	}
+	return true
+}
+
+// handleForgetCallback удаляет данные пользователя, нажавшего кнопку, или отменяет удаление
+func (h *BotHandler) handleForgetCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data telegram.CallbackData) {
+	if h.userData == nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+	chatID, messageID := callback.Message.Chat.ID, callback.Message.MessageID
+	userID := callback.From.ID
+
+	switch data.Arg(0) {
+	case forgetCancel:
+		_, _ = h.sender.Send(ctx, tgbotapi.NewEditMessageText(chatID, messageID, "Удаление отменено, данные сохранены."))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+		return
+	case forgetConfirm:
+	default:
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+
+	deleted, err := h.userData.Delete(ctx, userID)
+	h.forgetCached(userID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to delete user data", zap.Int64("deleted", deleted), zap.Error(err))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "❌ Удалилось не все, нажмите еще раз"))
+		return
+	}
+
+	logger.FromContext(ctx).Info("User data deleted", zap.Int64("deleted", deleted))
+	_, _ = h.sender.Send(ctx, tgbotapi.NewEditMessageText(chatID, messageID,
+		"🗑 Ваши данные удалены. Если снова напишете боту, он начнет с чистого листа."))
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+}
+
+// forgetCached сбрасывает то, что хранится о пользователе в памяти: настройки личного чата и ожидаемый ввод
+func (h *BotHandler) forgetCached(userID int64) {
+	h.settingsCache.mu.Lock()
+	delete(h.settingsCache.entries, userID)
+	h.settingsCache.mu.Unlock()
+
+	h.userStates.forgetUser(userID)
+}
@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSplitParticipants ограничивает число участников одного счета вместе с заплатившим
+	maxSplitParticipants = 20
+	// minSettleCents — переводы меньше одной единицы валюты не предлагаются:
+	// такие остатки появляются из-за округления и колебаний курса
+	minSettleCents = 100
+
+	splitConfirm = "confirm"
+	splitCancel  = "cancel"
+)
+
+// WithDebts включает общие счета и взаиморасчеты в группах
+func WithDebts(repo services.DebtsRepository) Option {
+	return func(h *BotHandler) {
+		h.debts = repo
+	}
+}
+
+// splitMention — участник счета, упомянутый в команде: @имя или упоминание без имени пользователя
+type splitMention struct {
+	username string
+	user     *tgbotapi.User
+}
+
+// splitArguments отделяет упоминания участников от остального текста команды /split
+func splitArguments(message *tgbotapi.Message) (string, []splitMention) {
+	units := utf16.Encode([]rune(message.Text))
+	text := func(from, to int) string { return string(utf16.Decode(units[from:to])) }
+
+	var rest strings.Builder
+	var mentions []splitMention
+	pos := 0
+	for _, entity := range message.Entities {
+		start, end := entity.Offset, entity.Offset+entity.Length
+		if start < pos || end > len(units) {
+			continue
+		}
+
+		switch {
+		case entity.Type == "bot_command" && start == 0:
+		case entity.Type == "mention":
+			mentions = append(mentions, splitMention{username: strings.TrimPrefix(text(start, end), "@")})
+		case entity.Type == "text_mention" && entity.User != nil:
+			mentions = append(mentions, splitMention{user: entity.User})
+		default:
+			continue
+		}
+
+		rest.WriteString(text(pos, start))
+		pos = end
+	}
+	rest.WriteString(text(pos, len(units)))
+
+	return strings.Join(strings.Fields(rest.String()), " "), mentions
+}
+
+// handleSplitCommand делит счет поровну между заплатившим и упомянутыми: /split 12000 KZT @a @b ужин
+func (h *BotHandler) handleSplitCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.debts == nil || !isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Счета делятся только в группах."))
+		return
+	}
+
+	rest, mentions := splitArguments(message)
+	input, err := parseExpense(rest, message.Time())
+	if err != nil || len(mentions) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+			"Использование: /split 12000 KZT @anna @boris ужин\nСумма делится поровну между вами и упомянутыми участниками."))
+		return
+	}
+
+	participants := []entities.SplitShare{{UserID: message.From.ID, Name: memberName(message.From)}}
+	var unknown []string
+	for _, mention := range mentions {
+		if strings.EqualFold(mention.username, h.self.UserName) {
+			continue
+		}
+		share, ok := h.resolveMention(ctx, mention)
+		if !ok {
+			unknown = append(unknown, "@"+mention.username)
+			continue
+		}
+		if !slices.ContainsFunc(participants, func(s entities.SplitShare) bool { return s.UserID == share.UserID }) {
+			participants = append(participants, share)
+		}
+	}
+	if len(unknown) > 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"❌ Не знаю %s: пусть сначала отправит боту любую команду, например /start.", strings.Join(unknown, ", "))))
+		return
+	}
+	if len(participants) < 2 || len(participants) > maxSplitParticipants {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID,
+			fmt.Sprintf("❌ В счете должно быть от 2 до %d участников вместе с вами.", maxSplitParticipants)))
+		return
+	}
+
+	for i, cents := range services.SplitEqually(services.ToCents(input.Amount), len(participants)) {
+		participants[i].Amount = float64(cents) / 100
+	}
+
+	split, err := h.debts.CreateSplit(ctx, entities.Split{
+		ChatID:   chatID,
+		PaidBy:   message.From.ID,
+		Amount:   input.Amount,
+		Currency: input.Currency,
+		Note:     input.Note,
+		Shares:   participants,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to create split", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось сохранить счет."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatSplit(split)+"\n\nЗаплативший подтверждает счет, после этого он учитывается в /settle.")
+	msg.ReplyToMessageID = message.MessageID
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		h.button(ctx, "✅ Подтвердить", callbackSplit, splitConfirm, split.ID),
+		h.button(ctx, "✖️ Отменить", callbackSplit, splitCancel, split.ID),
+	))
+	h.sendMessage(ctx, msg)
+}
+
+// resolveMention находит пользователя по упоминанию; по @имени — только если он уже писал боту
+func (h *BotHandler) resolveMention(ctx context.Context, mention splitMention) (entities.SplitShare, bool) {
+	if mention.user != nil {
+		return entities.SplitShare{UserID: mention.user.ID, Name: memberName(mention.user)}, true
+	}
+	if h.users == nil {
+		return entities.SplitShare{}, false
+	}
+
+	user, err := h.users.FindByUsername(ctx, mention.username)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			logger.FromContext(ctx).Error("Failed to find user by username", zap.Error(err))
+		}
+		return entities.SplitShare{}, false
+	}
+	return entities.SplitShare{UserID: user.ID, Name: "@" + user.Username}, true
+}
+
+// formatSplit печатает счет и доли участников
+func formatSplit(split entities.Split) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧾 Счет: %s %s", formatMoney(split.Amount), split.Currency)
+	if split.Note != "" {
+		sb.WriteString(" · " + split.Note)
+	}
+	for _, share := range split.Shares {
+		fmt.Fprintf(&sb, "\n• %s — %s %s", share.Name, formatMoney(share.Amount), split.Currency)
+		if share.UserID == split.PaidBy {
+			sb.WriteString(" (заплатил)")
+		}
+	}
+	return sb.String()
+}
+
+// handleSplitCallback подтверждает или отменяет счет; нажать может только заплативший
+func (h *BotHandler) handleSplitCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data telegram.CallbackData) {
+	id, err := data.Int64(1)
+	action := data.Arg(0)
+	if err != nil || h.debts == nil || (action != splitConfirm && action != splitCancel) {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+
+	status, result := entities.SplitConfirmed, "✅ Счет подтвержден и учитывается в /settle."
+	if action == splitCancel {
+		status, result = entities.SplitCancelled, "✖️ Счет отменен."
+	}
+
+	err = h.debts.SetSplitStatus(ctx, id, callback.From.ID, status)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "Подтвердить или отменить счет может только заплативший"))
+		return
+	case err != nil:
+		logger.FromContext(ctx).Error("Failed to set split status", zap.Error(err))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "❌ Не удалось сохранить"))
+		return
+	}
+
+	text, _, _ := strings.Cut(callback.Message.Text, "\n\n")
+	_, _ = h.sender.Send(ctx, tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, text+"\n\n"+result))
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, ""))
+}
+
+// handleSettleCommand показывает наименьший набор переводов, который закрывает долги группы,
+// в валютах, выбранных участниками: /settle [валюта расчета]
+func (h *BotHandler) handleSettleCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.debts == nil || !isGroup(message.Chat) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Взаиморасчеты доступны только в группах."))
+		return
+	}
+
+	splits, err := h.debts.ChatSplits(ctx, chatID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get chat splits", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить счета."))
+		return
+	}
+	settlements, err := h.debts.ChatSettlements(ctx, chatID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to get chat settlements", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить счета."))
+		return
+	}
+	if len(splits) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Общих счетов пока нет. Разделите первый: /split 12000 KZT @anna @boris"))
+		return
+	}
+
+	base := strings.ToUpper(strings.TrimSpace(message.CommandArguments()))
+	if base != "" && !isCurrencyCode(base) {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Использование: /settle или /settle USD"))
+		return
+	}
+	if settings, _ := h.chatSettings(ctx, chatID); base == "" && settings.DefaultTo != "" {
+		base = settings.DefaultTo
+	}
+	if base == "" {
+		base = splits[len(splits)-1].Currency
+	}
+
+	balances, err := services.DebtBalances(ctx, h.exchangeService, splits, settlements, base)
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to compute debt balances", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось пересчитать долги: курс недоступен."))
+		return
+	}
+
+	var transfers []entities.Transfer
+	for _, transfer := range services.SimplifyDebts(balances) {
+		if transfer.Amount >= minSettleCents {
+			transfers = append(transfers, transfer)
+		}
+	}
+	if len(transfers) == 0 {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "🤝 Все в расчете."))
+		return
+	}
+
+	names := make(memberNames)
+	for _, split := range splits {
+		for _, share := range split.Shares {
+			names[share.UserID] = share.Name
+		}
+	}
+	preferred := h.preferredCurrencies(ctx, transfers)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "💸 Чтобы рассчитаться, достаточно %d %s:\n", len(transfers), pluralTransfers(len(transfers)))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, transfer := range transfers {
+		sb.WriteString("\n" + h.formatTransfer(ctx, transfer, base, names, preferred))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(h.button(ctx,
+			fmt.Sprintf("✅ Получено: %s → %s", names.of(transfer.From), names.of(transfer.To)),
+			callbackSettle, transfer.From, transfer.To, transfer.Amount, base)))
+	}
+	sb.WriteString("\n\nПолучатель отмечает перевод кнопкой, и он вычитается из долгов. Валюта для расчетов: /currency USD")
+
+	msg := tgbotapi.NewMessage(chatID, sb.String())
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	h.sendMessage(ctx, msg)
+}
+
+// preferredCurrencies загружает валюты расчетов участников переводов
+func (h *BotHandler) preferredCurrencies(ctx context.Context, transfers []entities.Transfer) map[int64]string {
+	if h.users == nil {
+		return nil
+	}
+
+	var ids []int64
+	for _, transfer := range transfers {
+		ids = append(ids, transfer.From, transfer.To)
+	}
+	currencies, err := h.users.PreferredCurrencies(ctx, ids)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to get preferred currencies", zap.Error(err))
+	}
+	return currencies
+}
+
+// formatTransfer печатает перевод в валюте должника и, если она другая, в валюте получателя:
+// «Аня → Борис: 1 000 RUB (= 5 000 KZT)»
+func (h *BotHandler) formatTransfer(ctx context.Context, transfer entities.Transfer, base string,
+	names memberNames, preferred map[int64]string) string {
+	amount := float64(transfer.Amount) / 100
+
+	var parts []string
+	for _, userID := range []int64{transfer.From, transfer.To} {
+		currency := cmp.Or(preferred[userID], base)
+		text := formatMoney(amount) + " " + base
+		if currency != base {
+			conversion, err := h.exchangeService.ConvertAmount(ctx, amount, base, currency)
+			if err != nil {
+				logger.FromContext(ctx).Warn("Failed to convert transfer", zap.String("to", currency), zap.Error(err))
+			} else {
+				text = formatMoney(conversion.Result) + " " + currency
+			}
+		}
+		if !slices.Contains(parts, text) {
+			parts = append(parts, text)
+		}
+	}
+
+	line := fmt.Sprintf("%s → %s: %s", names.of(transfer.From), names.of(transfer.To), parts[0])
+	if len(parts) > 1 {
+		line += " (= " + parts[1] + ")"
+	}
+	return line
+}
+
+func pluralTransfers(n int) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return "перевода"
+	default:
+		return "переводов"
+	}
+}
+
+// handleSettleCallback записывает перевод, который отметил получатель
+func (h *BotHandler) handleSettleCallback(ctx context.Context, callback *tgbotapi.CallbackQuery, data telegram.CallbackData) {
+	from, errFrom := data.Int64(0)
+	to, errTo := data.Int64(1)
+	cents, errAmount := data.Int64(2)
+	currency := data.Arg(3)
+	if errFrom != nil || errTo != nil || errAmount != nil || !isCurrencyCode(currency) ||
+		h.debts == nil {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, staleButton))
+		return
+	}
+	if callback.From.ID != to {
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "Отметить перевод может только получатель"))
+		return
+	}
+
+	// Ключ нажатия: та же кнопка того же сообщения учитывается один раз, даже если
+	// второе нажатие уже в очереди или кнопку нажали в сообщении, которое не успело обновиться
+	added, err := h.debts.AddSettlement(ctx, entities.Settlement{
+		ChatID:   callback.Message.Chat.ID,
+		FromUser: from,
+		ToUser:   to,
+		Amount:   float64(cents) / 100,
+		Currency: currency,
+		Key:      settleKey(callback),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Failed to add settlement", zap.Error(err))
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, "❌ Не удалось сохранить перевод"))
+		return
+	}
+
+	// Кнопка убирается, чтобы по ней больше не нажимали
+	if markup := callback.Message.ReplyMarkup; markup != nil {
+		rows := [][]tgbotapi.InlineKeyboardButton{}
+		for _, row := range markup.InlineKeyboard {
+			row = slices.DeleteFunc(slices.Clone(row), func(b tgbotapi.InlineKeyboardButton) bool {
+				return b.CallbackData != nil && *b.CallbackData == callback.Data
+			})
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+		_, _ = h.sender.Send(ctx, tgbotapi.NewEditMessageReplyMarkup(callback.Message.Chat.ID, callback.Message.MessageID,
+			tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}))
+	}
+
+	answer := fmt.Sprintf("✅ Перевод %s %s учтен", formatMoney(float64(cents)/100), currency)
+	if !added {
+		answer = "Этот перевод уже учтен"
+	}
+	_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(callback.ID, answer))
+}
+
+// settleKey — ключ нажатия кнопки перевода: хеш чата, сообщения и подписанных данных кнопки.
+// Данные кнопки содержат ID участников, поэтому в базе хранится только хеш: после /forgetme
+// обезличенный перевод не выдает пользователя.
+func settleKey(callback *tgbotapi.CallbackQuery) string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%d:%d:%s", callback.Message.Chat.ID, callback.Message.MessageID, callback.Data))
+	return hex.EncodeToString(sum[:])
+}
+
+// handleCurrencyCommand показывает или задает валюту взаиморасчетов: /currency [код|off]
+func (h *BotHandler) handleCurrencyCommand(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	if h.users == nil {
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Настройка недоступна."))
+		return
+	}
+
+	arg := strings.ToUpper(strings.TrimSpace(message.CommandArguments()))
+	if arg == "" {
+		currencies, err := h.users.PreferredCurrencies(ctx, []int64{message.From.ID})
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to get preferred currency", zap.Error(err))
+			h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось загрузить настройку."))
+			return
+		}
+		text := "Валюта взаиморасчетов не выбрана: суммы в /settle показываются в валюте расчета.\nИспользование: /currency USD"
+		if currency := currencies[message.From.ID]; currency != "" {
+			text = "Валюта взаиморасчетов: " + currency + "\nСбросить: /currency off"
+		}
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, text))
+		return
+	}
+
+	currency, reply := arg, "✅ Суммы в /settle будут показываться вам в "+arg+"."
+	switch {
+	case arg == "OFF":
+		currency, reply = "", "✅ Валюта взаиморасчетов сброшена."
+	case !isCurrencyCode(arg):
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "Использование: /currency USD или /currency off"))
+		return
+	}
+
+	if err := h.users.SetPreferredCurrency(ctx, message.From.ID, currency); err != nil {
+		logger.FromContext(ctx).Error("Failed to set preferred currency", zap.Error(err))
+		h.sendMessage(ctx, tgbotapi.NewMessage(chatID, "❌ Не удалось сохранить настройку."))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, reply)
+	h.replyInGroup(&msg, message)
+	h.sendMessage(ctx, msg)
+}
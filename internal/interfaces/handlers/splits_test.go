@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/telegram"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitArguments(t *testing.T) {
+	boris := &tgbotapi.User{ID: 8, FirstName: "Борис"}
+	// Смещения сущностей Telegram считаются в UTF-16: эмодзи занимает две единицы
+	message := groupMessage("/split 12 000 KZT 🍕 @anna Борис ужин",
+		tgbotapi.MessageEntity{Type: "bot_command", Offset: 0, Length: 6},
+		tgbotapi.MessageEntity{Type: "mention", Offset: 21, Length: 5},
+		tgbotapi.MessageEntity{Type: "text_mention", Offset: 27, Length: 5, User: boris},
+	)
+
+	rest, mentions := splitArguments(message)
+	assert.Equal(t, "12 000 KZT 🍕 ужин", rest)
+	require.Len(t, mentions, 2)
+	assert.Equal(t, "anna", mentions[0].username)
+	assert.Same(t, boris, mentions[1].user)
+
+	input, err := parseExpense(rest, message.Time())
+	require.NoError(t, err)
+	assert.Equal(t, 12000.0, input.Amount)
+	assert.Equal(t, "KZT", input.Currency)
+	assert.Equal(t, "🍕 ужин", input.Note)
+}
+
+// fakeSettlements записывает переводы один раз на ключ, как уникальный индекс settle_key
+type fakeSettlements struct {
+	services.DebtsRepository
+	added []entities.Settlement
+}
+
+func (f *fakeSettlements) AddSettlement(_ context.Context, settlement entities.Settlement) (bool, error) {
+	for _, added := range f.added {
+		if added.Key == settlement.Key {
+			return false, nil
+		}
+	}
+	f.added = append(f.added, settlement)
+	return true, nil
+}
+
+func TestSettleCallback_RecordsTransferOnce(t *testing.T) {
+	ctx := context.Background()
+	bot, _ := newTestBot(t, nil)
+	debts := &fakeSettlements{}
+	codec := telegram.NewCallbackCodec([]byte("secret"))
+	h := &BotHandler{debts: debts, callbacks: codec, sender: telegram.NewSender(bot)}
+
+	raw, err := codec.Encode(ctx, telegram.NewCallbackData(callbackSettle, 2, 1, 50000, "RUB"))
+	require.NoError(t, err)
+	callback := &tgbotapi.CallbackQuery{
+		ID:      "1",
+		From:    &tgbotapi.User{ID: 1},
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: -100}},
+		Data:    raw,
+	}
+
+	// Второе нажатие той же кнопки уже стоит в очереди, пока обрабатывается первое
+	h.HandleUpdate(ctx, tgbotapi.Update{UpdateID: 1, CallbackQuery: callback})
+	h.HandleUpdate(ctx, tgbotapi.Update{UpdateID: 2, CallbackQuery: callback})
+
+	require.Len(t, debts.added, 1)
+	assert.Equal(t, entities.Settlement{
+		ChatID: -100, FromUser: 2, ToUser: 1, Amount: 500, Currency: "RUB",
+		Key: settleKey(callback),
+	}, debts.added[0])
+
+	// Кнопка без сообщения (inline-режим или слишком старое) не роняет обработчик
+	callback.Message = nil
+	assert.NotPanics(t, func() {
+		h.HandleUpdate(ctx, tgbotapi.Update{UpdateID: 3, CallbackQuery: callback})
+	})
+	assert.Len(t, debts.added, 1)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// WithThrottler ограничивает частоту запросов пользователей; администраторы не ограничиваются
+func WithThrottler(throttler *services.Throttler) Option {
+	return func(h *BotHandler) {
+		h.throttler = throttler
+	}
+}
+
+// allowUpdate проверяет лимит пользователя; на первое отклонение подряд бот отвечает,
+// остальные отбрасываются без обращения к провайдерам и базе
+func (h *BotHandler) allowUpdate(ctx context.Context, update tgbotapi.Update) bool {
+	if h.throttler == nil {
+		return true
+	}
+
+	var user *tgbotapi.User
+	switch {
+	case update.Message != nil:
+		user = update.Message.From
+	case update.CallbackQuery != nil:
+		user = update.CallbackQuery.From
+	}
+	if user == nil || h.isAdmin(user) {
+		return true
+	}
+
+	verdict := h.throttler.Check(ctx, user.ID)
+	if verdict.Allowed {
+		return true
+	}
+
+	reason := "limited"
+	if !verdict.BannedUntil.IsZero() {
+		reason = "banned"
+	}
+	metrics.UpdatesThrottledTotal.WithLabelValues(reason).Inc()
+
+	text := ""
+	if verdict.Notify {
+		text = h.throttleNotice(verdict)
+	}
+
+	switch {
+	case update.CallbackQuery != nil:
+		// На нажатие нужно ответить всегда, иначе кнопка останется в состоянии загрузки
+		_, _ = h.sender.Request(ctx, tgbotapi.NewCallback(update.CallbackQuery.ID, text))
+	case text != "":
+		h.sendMessage(ctx, tgbotapi.NewMessage(update.Message.Chat.ID, text))
+	}
+
+	return false
+}
+
+func (h *BotHandler) throttleNotice(verdict services.ThrottleVerdict) string {
+	if !verdict.BannedUntil.IsZero() {
+		return fmt.Sprintf("🚫 Слишком много запросов. Бот не будет отвечать вам до %s UTC.",
+			verdict.BannedUntil.UTC().Format("15:04 02.01.2006"))
+	}
+
+	seconds := int(math.Ceil(h.throttler.Cooldown().Seconds()))
+	return fmt.Sprintf("⏳ Слишком много запросов. Пожалуйста, подождите %d сек. и повторите.", seconds)
+}
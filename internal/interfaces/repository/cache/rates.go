@@ -1,70 +1,256 @@
 package cache
 
 import (
+	"container/list"
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
 )
 
+// DefaultCapacity ограничивает число пар в кэше, если емкость не задана явно
+const DefaultCapacity = 10000
+
 type cachedRate struct {
-	rate      float64
+	key       string
+	rate      entities.ExchangeRate
 	expiresAt time.Time
 }
 
+// RatesCache — хранилище курсов в памяти процесса с вытеснением давно не использованных пар
 type RatesCache struct {
-	mu    sync.RWMutex
-	rates map[string]cachedRate
-	ttl   time.Duration
+	mu       sync.Mutex
+	rates    map[string]*list.Element
+	lru      *list.List // начало — недавно использованные записи
+	locks    map[string]time.Time
+	capacity int
+	ttl      services.CacheTTLPolicy
+	maxStale time.Duration // сколько истекшая запись хранится как резервная
+
+	hits      atomic.Uint64
+	staleHits atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Option настраивает кэш курсов
+type Option func(*RatesCache)
+
+// WithMaxStale задает максимальный возраст истекшей записи, которую еще можно отдать
+// как устаревший курс, когда все провайдеры недоступны
+func WithMaxStale(maxStale time.Duration) Option {
+	return func(c *RatesCache) {
+		if maxStale > 0 {
+			c.maxStale = maxStale
+		}
+	}
 }
 
-func NewRatesCache(ttlMinutes int) *RatesCache {
-	return &RatesCache{
-		rates: make(map[string]cachedRate),
-		ttl:   time.Duration(ttlMinutes) * time.Minute,
+// WithCapacity ограничивает число пар; при переполнении вытесняется давно не использованная
+func WithCapacity(capacity int) Option {
+	return func(c *RatesCache) {
+		if capacity > 0 {
+			c.capacity = capacity
+		}
 	}
 }
 
-func (c *RatesCache) Get(from, to string) (float64, bool) {
+// WithTTLPolicy заменяет единый срок жизни из ttlMinutes политикой с переопределениями
+func WithTTLPolicy(policy services.CacheTTLPolicy) Option {
+	return func(c *RatesCache) {
+		c.ttl = policy
+	}
+}
+
+func NewRatesCache(ttlMinutes int, opts ...Option) *RatesCache {
+	c := &RatesCache{
+		rates:    make(map[string]*list.Element),
+		lru:      list.New(),
+		locks:    make(map[string]time.Time),
+		capacity: DefaultCapacity,
+		ttl:      services.NewCacheTTLPolicy(time.Duration(ttlMinutes)*time.Minute, nil, nil),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *RatesCache) Get(_ context.Context, from, to string) (entities.ExchangeRate, bool) {
 	key := c.buildKey(from, to)
 
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	cached, exists := c.rates[key]
+	elem, exists := c.rates[key]
 	if !exists {
-		return 0, false
+		c.misses.Add(1)
+		return entities.ExchangeRate{}, false
 	}
 
+	cached := elem.Value.(*cachedRate)
 	if time.Now().After(cached.expiresAt) {
-		return 0, false
+		c.misses.Add(1)
+		return entities.ExchangeRate{}, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.hits.Add(1)
+
+	rate := cached.rate
+	rate.FromCache = true
+	return rate, true
+}
+
+// GetStale возвращает запись даже после истечения TTL, но не старше maxStale.
+// Истекшие записи помечаются флагом Stale.
+func (c *RatesCache) GetStale(_ context.Context, from, to string) (entities.ExchangeRate, bool) {
+	key := c.buildKey(from, to)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.rates[key]
+	if !exists {
+		return entities.ExchangeRate{}, false
 	}
 
-	return cached.rate, true
+	cached := elem.Value.(*cachedRate)
+	now := time.Now()
+	if now.After(cached.expiresAt.Add(c.maxStale)) {
+		return entities.ExchangeRate{}, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	rate := cached.rate
+	rate.FromCache = true
+	rate.Stale = now.After(cached.expiresAt)
+	if rate.Stale {
+		c.staleHits.Add(1)
+	}
+	return rate, true
 }
 
-func (c *RatesCache) Set(from, to string, rate float64) {
+// Remaining возвращает оставшееся время жизни записи
+func (c *RatesCache) Remaining(_ context.Context, from, to string) (time.Duration, bool) {
 	key := c.buildKey(from, to)
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.rates[key] = cachedRate{
+	elem, exists := c.rates[key]
+	if !exists {
+		return 0, false
+	}
+
+	return time.Until(elem.Value.(*cachedRate).expiresAt), true
+}
+
+func (c *RatesCache) Set(_ context.Context, rate entities.ExchangeRate) {
+	key := c.buildKey(rate.From.Code, rate.To.Code)
+	rate.FromCache = false
+	rate.Stale = false
+	expiresAt := time.Now().Add(c.ttl.TTL(rate))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.rates[key]; exists {
+		cached := elem.Value.(*cachedRate)
+		cached.rate = rate
+		cached.expiresAt = expiresAt
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.rates[key] = c.lru.PushFront(&cachedRate{
+		key:       key,
 		rate:      rate,
-		expiresAt: time.Now().Add(c.ttl),
+		expiresAt: expiresAt,
+	})
+
+	for c.lru.Len() > c.capacity {
+		c.removeElement(c.lru.Back())
+		c.evictions.Add(1)
 	}
 }
 
+// Lock захватывает блокировку в пределах процесса: в памяти кэш не разделяется между репликами
+func (c *RatesCache) Lock(_ context.Context, key string, ttl time.Duration) (func(), bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if until, held := c.locks[key]; held && now.Before(until) {
+		return func() {}, false
+	}
+
+	until := now.Add(ttl)
+	c.locks[key] = until
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.locks[key].Equal(until) {
+			delete(c.locks, key)
+		}
+	}, true
+}
+
+// Stats возвращает снимок счетчиков кэша
+func (c *RatesCache) Stats() services.CacheStats {
+	c.mu.Lock()
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	return services.CacheStats{
+		Hits:      c.hits.Load(),
+		StaleHits: c.staleHits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Size:      size,
+		Capacity:  c.capacity,
+	}
+}
+
+// Flush удаляет все курсы; счетчики обращений сохраняются
+func (c *RatesCache) Flush(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rates = make(map[string]*list.Element)
+	c.lru.Init()
+	return nil
+}
+
 func (c *RatesCache) buildKey(from, to string) string {
 	return from + "_" + to
 }
 
+func (c *RatesCache) removeElement(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.rates, elem.Value.(*cachedRate).key)
+}
+
+// Cleanup удаляет записи, которые нельзя отдать даже как устаревшие
 func (c *RatesCache) Cleanup() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	now := time.Now()
-	for key, cached := range c.rates {
-		if now.After(cached.expiresAt) {
-			delete(c.rates, key)
+	for _, elem := range c.rates {
+		if now.After(elem.Value.(*cachedRate).expiresAt.Add(c.maxStale)) {
+			c.removeElement(elem)
+		}
+	}
+	for key, until := range c.locks {
+		if now.After(until) {
+			delete(c.locks, key)
 		}
 	}
 }
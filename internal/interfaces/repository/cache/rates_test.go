@@ -1,38 +1,202 @@
 package cache
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
 )
 
 func TestRatesCache(t *testing.T) {
+	ctx := context.Background()
 	cache := NewRatesCache(1) // TTL 1 minute
 
 	// Test Set and Get
-	cache.Set("USD", "EUR", 0.85)
-	rate, found := cache.Get("USD", "EUR")
+	cache.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.85, "test", time.Time{}))
+	rate, found := cache.Get(ctx, "USD", "EUR")
 
 	if !found {
 		t.Error("Expected to find rate in cache")
 	}
-	if rate != 0.85 {
-		t.Errorf("Expected rate 0.85, got %f", rate)
+	if rate.Rate != 0.85 {
+		t.Errorf("Expected rate 0.85, got %f", rate.Rate)
+	}
+	if !rate.FromCache {
+		t.Error("Expected cached rate to be marked as FromCache")
 	}
 
 	// Test non-existent rate
-	_, found = cache.Get("USD", "GBP")
+	_, found = cache.Get(ctx, "USD", "GBP")
 	if found {
 		t.Error("Expected not to find non-existent rate")
 	}
 }
 
 func TestRatesCacheExpiration(t *testing.T) {
+	ctx := context.Background()
 	cache := NewRatesCache(1) // TTL 1 minute
 
-	cache.Set("USD", "EUR", 0.85)
+	cache.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.85, "test", time.Time{}))
 
 	// Rate should be available immediately
-	_, found := cache.Get("USD", "EUR")
+	_, found := cache.Get(ctx, "USD", "EUR")
 	if !found {
 		t.Error("Rate should be available before expiration")
 	}
 }
+
+func TestRatesCacheStaleFallback(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRatesCache(0, WithMaxStale(time.Hour))
+
+	cache.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.85, "test", time.Time{}))
+	time.Sleep(time.Millisecond)
+
+	if _, found := cache.Get(ctx, "USD", "EUR"); found {
+		t.Error("Expired rate should not be returned by Get")
+	}
+
+	rate, found := cache.GetStale(ctx, "USD", "EUR")
+	if !found {
+		t.Fatal("Expired rate should be returned by GetStale within max stale age")
+	}
+	if !rate.Stale {
+		t.Error("Expected expired rate to be marked as stale")
+	}
+
+	cache.Cleanup()
+	if _, found := cache.GetStale(ctx, "USD", "EUR"); !found {
+		t.Error("Cleanup should keep entries younger than max stale age")
+	}
+}
+
+func TestRatesCacheStaleLimit(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRatesCache(0)
+
+	cache.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.85, "test", time.Time{}))
+	time.Sleep(time.Millisecond)
+
+	if _, found := cache.GetStale(ctx, "USD", "EUR"); found {
+		t.Error("Rate older than max stale age should not be returned")
+	}
+}
+
+func TestRatesCacheLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRatesCache(1, WithCapacity(2))
+
+	cache.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.85, "test", time.Time{}))
+	cache.Set(ctx, entities.NewExchangeRate("USD", "RUB", 81.5, "test", time.Time{}))
+
+	// Обращение делает USD/EUR недавно использованной, вытесняться должна USD/RUB
+	cache.Get(ctx, "USD", "EUR")
+	cache.Set(ctx, entities.NewExchangeRate("EUR", "RUB", 95.1, "test", time.Time{}))
+
+	if _, found := cache.Get(ctx, "USD", "RUB"); found {
+		t.Error("Least recently used pair should be evicted")
+	}
+	if _, found := cache.Get(ctx, "USD", "EUR"); !found {
+		t.Error("Recently used pair should stay in cache")
+	}
+	if _, found := cache.Get(ctx, "EUR", "RUB"); !found {
+		t.Error("New pair should be stored")
+	}
+
+	stats := cache.Stats()
+	if stats.Size != 2 || stats.Capacity != 2 {
+		t.Errorf("Expected size 2 of capacity 2, got %d of %d", stats.Size, stats.Capacity)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", stats.Evictions)
+	}
+	if stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("Expected 3 hits and 1 miss, got %d and %d", stats.Hits, stats.Misses)
+	}
+}
+
+func TestRatesCacheTTLOverrides(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRatesCache(0, WithTTLPolicy(services.NewCacheTTLPolicy(5*time.Minute,
+		map[string]time.Duration{"CBR": 12 * time.Hour},
+		map[string]time.Duration{"BTC": time.Minute},
+	)))
+
+	cache.Set(ctx, entities.NewExchangeRate("USD", "RUB", 81.5, "CBR", time.Time{}))
+	cache.Set(ctx, entities.NewExchangeRate("BTC", "USD", 65000, "Crypto", time.Time{}))
+	cache.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.85, "Frankfurter", time.Time{}))
+
+	cases := []struct {
+		from, to string
+		want     time.Duration
+	}{
+		{"USD", "RUB", 12 * time.Hour},
+		{"BTC", "USD", time.Minute},
+		{"USD", "EUR", 5 * time.Minute},
+	}
+
+	for _, tc := range cases {
+		remaining, found := cache.Remaining(ctx, tc.from, tc.to)
+		if !found {
+			t.Fatalf("Expected %s/%s in cache", tc.from, tc.to)
+		}
+		if remaining > tc.want || remaining < tc.want-time.Second {
+			t.Errorf("Expected TTL about %s for %s/%s, got %s", tc.want, tc.from, tc.to, remaining)
+		}
+	}
+}
+
+func BenchmarkRatesCacheConcurrentGetSet(b *testing.B) {
+	ctx := context.Background()
+	currencies := []string{"USD", "EUR", "RUB", "GBP", "JPY", "CNY", "CAD", "CHF", "KZT", "TRY"}
+	cache := NewRatesCache(5, WithCapacity(50))
+
+	b.ReportAllocs()
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			from := currencies[i%len(currencies)]
+			to := currencies[(i/len(currencies))%len(currencies)]
+			if i%4 == 0 {
+				cache.Set(ctx, entities.NewExchangeRate(from, to, 1.5, "bench", time.Time{}))
+			} else {
+				cache.Get(ctx, from, to)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkRatesCacheConcurrentGet(b *testing.B) {
+	ctx := context.Background()
+	cache := NewRatesCache(5)
+	cache.Set(ctx, entities.NewExchangeRate("USD", "RUB", 81.5, "bench", time.Time{}))
+
+	b.ReportAllocs()
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cache.Get(ctx, "USD", "RUB")
+		}
+	})
+}
+
+func TestRatesCacheFlush(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRatesCache(1, WithMaxStale(time.Hour))
+
+	cache.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.85, "test", time.Time{}))
+	if err := cache.Flush(ctx); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	if _, found := cache.GetStale(ctx, "USD", "EUR"); found {
+		t.Error("Flush should drop stale fallback entries too")
+	}
+	if size := cache.Stats().Size; size != 0 {
+		t.Errorf("Expected empty cache after flush, got %d entries", size)
+	}
+}
@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AuditRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditRepository(db *pgxpool.Pool) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Record(ctx context.Context, entry entities.AuditEntry) error {
+	query := `
+		INSERT INTO admin_audit_log (admin_id, action, details)
+		VALUES ($1, $2, $3)
+	`
+
+	ctx, done := startQuery(ctx, "record_audit")
+	_, err := r.db.Exec(ctx, query, entry.AdminID, entry.Action, entry.Details)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
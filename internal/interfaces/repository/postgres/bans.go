@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BanRepository struct {
+	db *pgxpool.Pool
+	userData
+}
+
+func NewBanRepository(db *pgxpool.Pool) *BanRepository {
+	return &BanRepository{db: db, userData: newUserData(db, userTable{name: "user_bans", where: "user_id = $1"})}
+}
+
+func (r *BanRepository) Ban(ctx context.Context, ban entities.Ban) error {
+	query := `
+		INSERT INTO user_bans (user_id, reason, banned_until)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET reason = EXCLUDED.reason, banned_until = EXCLUDED.banned_until, created_at = NOW()
+	`
+
+	ctx, done := startQuery(ctx, "ban_user")
+	_, err := r.db.Exec(ctx, query, ban.UserID, ban.Reason, ban.BannedUntil)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to ban user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *BanRepository) Unban(ctx context.Context, userID int64) error {
+	query := `DELETE FROM user_bans WHERE user_id = $1`
+
+	ctx, done := startQuery(ctx, "unban_user")
+	_, err := r.db.Exec(ctx, query, userID)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to unban user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *BanRepository) Active(ctx context.Context) ([]entities.Ban, error) {
+	query := `
+		SELECT user_id, reason, banned_until, created_at
+		FROM user_bans
+		WHERE banned_until > NOW()
+		ORDER BY banned_until DESC
+	`
+
+	ctx, done := startQuery(ctx, "active_bans")
+	bans, err := selectAll[entities.Ban](ctx, r.db, query)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active bans: %w", err)
+	}
+
+	return bans, nil
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type BroadcastRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewBroadcastRepository(db *pgxpool.Pool) *BroadcastRepository {
+	return &BroadcastRepository{db: db}
+}
+
+const broadcastColumns = `id, admin_id, text, status, last_user_id, sent, failed, created_at, started_at, finished_at`
+
+func (r *BroadcastRepository) Create(ctx context.Context, adminID int64, text string) (entities.Broadcast, error) {
+	query := `
+		INSERT INTO broadcasts (admin_id, text)
+		VALUES ($1, $2)
+		RETURNING ` + broadcastColumns
+
+	ctx, done := startQuery(ctx, "create_broadcast")
+	broadcast, err := selectOne[entities.Broadcast](ctx, r.db, query, adminID, text)
+	done(err)
+	if err != nil {
+		return broadcast, fmt.Errorf("failed to create broadcast: %w", err)
+	}
+
+	return broadcast, nil
+}
+
+func (r *BroadcastRepository) Get(ctx context.Context, id int64) (entities.Broadcast, error) {
+	query := `SELECT ` + broadcastColumns + ` FROM broadcasts WHERE id = $1`
+
+	ctx, done := startQuery(ctx, "get_broadcast")
+	broadcast, err := selectOne[entities.Broadcast](ctx, r.db, query, id)
+	done(err)
+	if err != nil {
+		return broadcast, fmt.Errorf("failed to get broadcast: %w", err)
+	}
+
+	return broadcast, nil
+}
+
+func (r *BroadcastRepository) SetStatus(ctx context.Context, id int64, status string, from ...string) (bool, error) {
+	query := `
+		UPDATE broadcasts
+		SET status = $2,
+			started_at = CASE WHEN $2 = 'sending' THEN NOW() ELSE started_at END,
+			finished_at = CASE WHEN $2 IN ('done', 'cancelled') THEN NOW() ELSE finished_at END
+		WHERE id = $1 AND status = ANY($3)
+	`
+
+	ctx, done := startQuery(ctx, "set_broadcast_status")
+	tag, err := r.db.Exec(ctx, query, id, status, from)
+	done(err)
+	if err != nil {
+		return false, fmt.Errorf("failed to set broadcast status: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *BroadcastRepository) Progress(ctx context.Context, id, lastUserID int64, sent, failed int) error {
+	query := `
+		UPDATE broadcasts
+		SET last_user_id = $2, sent = $3, failed = $4
+		WHERE id = $1
+	`
+
+	ctx, done := startQuery(ctx, "broadcast_progress")
+	_, err := r.db.Exec(ctx, query, id, lastUserID, sent, failed)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to save broadcast progress: %w", err)
+	}
+
+	return nil
+}
+
+func (r *BroadcastRepository) Sending(ctx context.Context) ([]entities.Broadcast, error) {
+	query := `SELECT ` + broadcastColumns + ` FROM broadcasts WHERE status = 'sending' ORDER BY id`
+
+	ctx, done := startQuery(ctx, "sending_broadcasts")
+	broadcasts, err := selectAll[entities.Broadcast](ctx, r.db, query)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sending broadcasts: %w", err)
+	}
+
+	return broadcasts, nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ChatSettingsRepository struct {
+	db *pgxpool.Pool
+	userData
+}
+
+func NewChatSettingsRepository(db *pgxpool.Pool) *ChatSettingsRepository {
+	return &ChatSettingsRepository{db: db, userData: newUserData(db,
+		// ID личного чата совпадает с ID пользователя
+		userTable{name: "chat_settings", where: "chat_id = $1"},
+		// В группах остаются настройки, но не тот, кто их менял
+		userTable{name: "chat_settings", where: "updated_by = $1 AND chat_id <> $1", anonymize: "updated_by = 0"},
+	)}
+}
+
+// getChatSettingsQuery выполняется на каждое сообщение, поэтому подготовлен заранее
+const getChatSettingsQuery = `
+	SELECT chat_id, default_from, default_to, trigger_pattern, auto_convert, auto_currencies,
+	       updated_by, updated_at
+	FROM chat_settings
+	WHERE chat_id = $1
+`
+
+// GetChatSettings возвращает настройки чата или пустые, если они не задавались
+func (r *ChatSettingsRepository) GetChatSettings(ctx context.Context, chatID int64) (entities.ChatSettings, error) {
+	ctx, done := startQuery(ctx, "get_chat_settings")
+	settings, err := selectOne[entities.ChatSettings](ctx, r.db, "get_chat_settings", chatID)
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entities.ChatSettings{ChatID: chatID}, nil
+	}
+	if err != nil {
+		return entities.ChatSettings{}, fmt.Errorf("failed to get chat settings: %w", err)
+	}
+
+	return settings, nil
+}
+
+func (r *ChatSettingsRepository) SaveChatSettings(ctx context.Context, settings entities.ChatSettings) error {
+	query := `
+		INSERT INTO chat_settings (chat_id, default_from, default_to, trigger_pattern,
+		                           auto_convert, auto_currencies, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (chat_id) DO UPDATE
+		SET default_from = EXCLUDED.default_from,
+		    default_to = EXCLUDED.default_to,
+		    trigger_pattern = EXCLUDED.trigger_pattern,
+		    auto_convert = EXCLUDED.auto_convert,
+		    auto_currencies = EXCLUDED.auto_currencies,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = NOW()
+	`
+
+	ctx, done := startQuery(ctx, "save_chat_settings")
+	_, err := r.db.Exec(ctx, query,
+		settings.ChatID, settings.DefaultFrom, settings.DefaultTo, settings.TriggerPattern,
+		settings.AutoConvert, settings.AutoCurrencies, settings.UpdatedBy)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to save chat settings: %w", err)
+	}
+
+	return nil
+}
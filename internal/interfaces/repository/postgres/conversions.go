@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	conversionColumns = `id, user_id, chat_id, input_text, amount, from_currency, to_currency, rate, result, provider, created_at`
+
+	// addConversionQuery выполняется на каждую конвертацию, поэтому подготовлен заранее
+	addConversionQuery = `
+		INSERT INTO conversions (user_id, chat_id, input_text, amount, from_currency, to_currency, rate, result, provider)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+)
+
+type ConversionsRepository struct {
+	db *pgxpool.Pool
+	userData
+}
+
+func NewConversionsRepository(db *pgxpool.Pool) *ConversionsRepository {
+	return &ConversionsRepository{db: db, userData: newUserData(db, userTable{name: "conversions", where: "user_id = $1"})}
+}
+
+func (r *ConversionsRepository) AddConversion(ctx context.Context, record entities.ConversionRecord) error {
+	ctx, done := startQuery(ctx, "add_conversion")
+	_, err := r.db.Exec(ctx, "add_conversion", record.UserID, record.ChatID, record.Input, record.Amount,
+		record.From, record.To, record.Rate, record.Result, record.Provider)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to add conversion: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ConversionsRepository) UserConversions(ctx context.Context, userID int64, offset, limit int) ([]entities.ConversionRecord, error) {
+	query := `
+		SELECT ` + conversionColumns + `
+		FROM conversions
+		WHERE user_id = $1
+		ORDER BY id DESC
+		OFFSET $2 LIMIT $3
+	`
+
+	ctx, done := startQuery(ctx, "user_conversions")
+	records, err := selectAll[entities.ConversionRecord](ctx, r.db, query, userID, offset, limit)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversions: %w", err)
+	}
+
+	return records, nil
+}
+
+func (r *ConversionsRepository) CountConversions(ctx context.Context, userID int64) (int, error) {
+	var count int
+
+	ctx, done := startQuery(ctx, "count_conversions")
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM conversions WHERE user_id = $1`, userID).Scan(&count)
+	done(err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count conversions: %w", err)
+	}
+
+	return count, nil
+}
+
+func (r *ConversionsRepository) GetConversion(ctx context.Context, userID, id int64) (entities.ConversionRecord, error) {
+	query := `SELECT ` + conversionColumns + ` FROM conversions WHERE id = $1 AND user_id = $2`
+
+	ctx, done := startQuery(ctx, "get_conversion")
+	record, err := selectOne[entities.ConversionRecord](ctx, r.db, query, id, userID)
+	done(err)
+	if err != nil {
+		return record, fmt.Errorf("failed to get conversion: %w", err)
+	}
+
+	return record, nil
+}
+
+func (r *ConversionsRepository) EachConversion(ctx context.Context, userID int64, fn func(entities.ConversionRecord) error) error {
+	query := `
+		SELECT ` + conversionColumns + `
+		FROM conversions
+		WHERE user_id = $1
+		ORDER BY id
+	`
+
+	ctx, done := startQuery(ctx, "each_conversion")
+	err := func() error {
+		rows, err := r.db.Query(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			record, err := pgx.RowToStructByNameLax[entities.ConversionRecord](rows)
+			if err != nil {
+				return err
+			}
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}()
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to read conversions: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ConversionsRepository) DeleteConversions(ctx context.Context, userID int64) (int64, error) {
+	ctx, done := startQuery(ctx, "delete_conversions")
+	tag, err := r.db.Exec(ctx, `DELETE FROM conversions WHERE user_id = $1`, userID)
+	done(err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete conversions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (r *ConversionsRepository) DeleteConversionsBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	query := `
+		DELETE FROM conversions
+		WHERE id IN (SELECT id FROM conversions WHERE created_at < $1 LIMIT $2)
+	`
+
+	ctx, done := startQuery(ctx, "delete_old_conversions")
+	tag, err := r.db.Exec(ctx, query, before, limit)
+	done(err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old conversions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const splitColumns = `id, chat_id, paid_by, amount, currency, note, status, created_at`
+
+type DebtsRepository struct {
+	db *pgxpool.Pool
+	userData
+}
+
+func NewDebtsRepository(db *pgxpool.Pool) *DebtsRepository {
+	return &DebtsRepository{db: db, userData: newUserData(db,
+		// Счета и переводы в группах остаются под псевдонимом, чтобы не изменились
+		// долги остальных участников; записи личного чата удаляются
+		userTable{name: "split_shares", where: "user_id = $1 AND split_id IN (SELECT id FROM splits WHERE chat_id <> $1)",
+			anonymize: "user_id = $2, name = '" + forgottenName + "'"},
+		userTable{name: "split_shares", where: "user_id = $1 AND split_id IN (SELECT id FROM splits WHERE chat_id = $1)"},
+		userTable{name: "splits", where: "paid_by = $1 AND chat_id <> $1", anonymize: "paid_by = $2"},
+		userTable{name: "splits", where: "chat_id = $1"},
+		userTable{name: "settlements", where: "(from_user = $1 OR to_user = $1) AND chat_id <> $1",
+			anonymize: "from_user = CASE WHEN from_user = $1 THEN $2 ELSE from_user END, " +
+				"to_user = CASE WHEN to_user = $1 THEN $2 ELSE to_user END"},
+		userTable{name: "settlements", where: "chat_id = $1"},
+	)}
+}
+
+func (r *DebtsRepository) CreateSplit(ctx context.Context, split entities.Split) (entities.Split, error) {
+	ctx, done := startQuery(ctx, "create_split")
+	created, err := r.createSplit(ctx, split)
+	done(err)
+	if err != nil {
+		return entities.Split{}, fmt.Errorf("failed to create split: %w", err)
+	}
+
+	return created, nil
+}
+
+// createSplit сохраняет счет и доли в одной транзакции
+func (r *DebtsRepository) createSplit(ctx context.Context, split entities.Split) (entities.Split, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return entities.Split{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO splits (chat_id, paid_by, amount, currency, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + splitColumns
+	created, err := selectOne[entities.Split](ctx, tx, query,
+		split.ChatID, split.PaidBy, split.Amount, split.Currency, split.Note)
+	if err != nil {
+		return entities.Split{}, err
+	}
+
+	for _, share := range split.Shares {
+		share.SplitID = created.ID
+		query := `INSERT INTO split_shares (split_id, user_id, name, amount) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(ctx, query, share.SplitID, share.UserID, share.Name, share.Amount); err != nil {
+			return entities.Split{}, err
+		}
+		created.Shares = append(created.Shares, share)
+	}
+
+	return created, tx.Commit(ctx)
+}
+
+func (r *DebtsRepository) SetSplitStatus(ctx context.Context, id, paidBy int64, status string) error {
+	query := `
+		UPDATE splits SET status = $3
+		WHERE id = $1 AND paid_by = $2 AND status = 'pending'
+	`
+
+	ctx, done := startQuery(ctx, "set_split_status")
+	tag, err := r.db.Exec(ctx, query, id, paidBy, status)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to set split status: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *DebtsRepository) ChatSplits(ctx context.Context, chatID int64) ([]entities.Split, error) {
+	ctx, done := startQuery(ctx, "get_chat_splits")
+	splits, err := r.chatSplits(ctx, chatID)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat splits: %w", err)
+	}
+
+	return splits, nil
+}
+
+func (r *DebtsRepository) chatSplits(ctx context.Context, chatID int64) ([]entities.Split, error) {
+	query := `
+		SELECT ` + splitColumns + `
+		FROM splits
+		WHERE chat_id = $1 AND status = 'confirmed'
+		ORDER BY id
+	`
+	splits, err := selectAll[entities.Split](ctx, r.db, query, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+		SELECT s.split_id, s.user_id, s.name, s.amount
+		FROM split_shares s
+		JOIN splits ON splits.id = s.split_id
+		WHERE splits.chat_id = $1 AND splits.status = 'confirmed'
+	`
+	shares, err := selectAll[entities.SplitShare](ctx, r.db, query, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[int64]int, len(splits))
+	for i, split := range splits {
+		index[split.ID] = i
+	}
+	for _, share := range shares {
+		if i, ok := index[share.SplitID]; ok {
+			splits[i].Shares = append(splits[i].Shares, share)
+		}
+	}
+
+	return splits, nil
+}
+
+func (r *DebtsRepository) AddSettlement(ctx context.Context, settlement entities.Settlement) (bool, error) {
+	query := `
+		INSERT INTO settlements (chat_id, from_user, to_user, amount, currency, settle_key)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		ON CONFLICT (settle_key) DO NOTHING
+	`
+
+	ctx, done := startQuery(ctx, "add_settlement")
+	tag, err := r.db.Exec(ctx, query, settlement.ChatID, settlement.FromUser, settlement.ToUser,
+		settlement.Amount, settlement.Currency, settlement.Key)
+	done(err)
+	if err != nil {
+		return false, fmt.Errorf("failed to add settlement: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *DebtsRepository) ChatSettlements(ctx context.Context, chatID int64) ([]entities.Settlement, error) {
+	query := `
+		SELECT id, chat_id, from_user, to_user, amount, currency, created_at
+		FROM settlements
+		WHERE chat_id = $1
+		ORDER BY id
+	`
+
+	ctx, done := startQuery(ctx, "get_chat_settlements")
+	settlements, err := selectAll[entities.Settlement](ctx, r.db, query, chatID)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat settlements: %w", err)
+	}
+
+	return settlements, nil
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	tripColumns    = `id, chat_id, name, base_currency, created_by, active, created_at`
+	expenseColumns = `id, trip_id, paid_by, amount, currency, category, note, spent_on, created_at`
+)
+
+type ExpensesRepository struct {
+	db *pgxpool.Pool
+	userData
+}
+
+func NewExpensesRepository(db *pgxpool.Pool) *ExpensesRepository {
+	return &ExpensesRepository{db: db, userData: newUserData(db,
+		// В групповых поездках расходы и участие остаются под псевдонимом, чтобы не изменились
+		// доли остальных; поездки личного чата удаляются целиком
+		userTable{name: "expenses", where: "paid_by = $1 AND trip_id IN (SELECT id FROM trips WHERE chat_id <> $1)",
+			anonymize: "paid_by = $2"},
+		userTable{name: "expenses", where: "paid_by = $1 AND trip_id IN (SELECT id FROM trips WHERE chat_id = $1)"},
+		userTable{name: "trip_members", where: "user_id = $1 AND trip_id IN (SELECT id FROM trips WHERE chat_id <> $1)",
+			anonymize: "user_id = $2, name = '" + forgottenName + "'"},
+		userTable{name: "trip_members", where: "user_id = $1 AND trip_id IN (SELECT id FROM trips WHERE chat_id = $1)"},
+		userTable{name: "trips", where: "chat_id = $1"},
+		userTable{name: "trips", where: "created_by = $1 AND chat_id <> $1", anonymize: "created_by = 0"},
+	)}
+}
+
+func (r *ExpensesRepository) CreateTrip(ctx context.Context, trip entities.Trip) (entities.Trip, error) {
+	ctx, done := startQuery(ctx, "create_trip")
+	created, err := r.createTrip(ctx, trip)
+	done(err)
+	if err != nil {
+		return entities.Trip{}, fmt.Errorf("failed to create trip: %w", err)
+	}
+
+	return created, nil
+}
+
+// createTrip закрывает активную поездку чата и создает новую в одной транзакции
+func (r *ExpensesRepository) createTrip(ctx context.Context, trip entities.Trip) (entities.Trip, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return entities.Trip{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE trips SET active = FALSE WHERE chat_id = $1 AND active`, trip.ChatID); err != nil {
+		return entities.Trip{}, err
+	}
+
+	query := `
+		INSERT INTO trips (chat_id, name, base_currency, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + tripColumns
+	created, err := selectOne[entities.Trip](ctx, tx, query, trip.ChatID, trip.Name, trip.BaseCurrency, trip.CreatedBy)
+	if err != nil {
+		return entities.Trip{}, err
+	}
+
+	return created, tx.Commit(ctx)
+}
+
+func (r *ExpensesRepository) ActiveTrip(ctx context.Context, chatID int64) (entities.Trip, error) {
+	query := `SELECT ` + tripColumns + ` FROM trips WHERE chat_id = $1 AND active`
+
+	ctx, done := startQuery(ctx, "get_active_trip")
+	trip, err := selectOne[entities.Trip](ctx, r.db, query, chatID)
+	done(err)
+	if err != nil {
+		return entities.Trip{}, fmt.Errorf("failed to get active trip: %w", err)
+	}
+
+	return trip, nil
+}
+
+func (r *ExpensesRepository) CloseTrip(ctx context.Context, tripID int64) error {
+	ctx, done := startQuery(ctx, "close_trip")
+	_, err := r.db.Exec(ctx, `UPDATE trips SET active = FALSE WHERE id = $1`, tripID)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to close trip: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ExpensesRepository) AddTripMember(ctx context.Context, member entities.TripMember) error {
+	query := `
+		INSERT INTO trip_members (trip_id, user_id, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (trip_id, user_id) DO UPDATE SET name = EXCLUDED.name
+	`
+
+	ctx, done := startQuery(ctx, "add_trip_member")
+	_, err := r.db.Exec(ctx, query, member.TripID, member.UserID, member.Name)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to add trip member: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ExpensesRepository) GetTripMembers(ctx context.Context, tripID int64) ([]entities.TripMember, error) {
+	query := `
+		SELECT trip_id, user_id, name, joined_at
+		FROM trip_members
+		WHERE trip_id = $1
+		ORDER BY joined_at
+	`
+
+	ctx, done := startQuery(ctx, "get_trip_members")
+	members, err := selectAll[entities.TripMember](ctx, r.db, query, tripID)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip members: %w", err)
+	}
+
+	return members, nil
+}
+
+func (r *ExpensesRepository) AddExpense(ctx context.Context, expense entities.Expense) (entities.Expense, error) {
+	query := `
+		INSERT INTO expenses (trip_id, paid_by, amount, currency, category, note, spent_on)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + expenseColumns
+
+	ctx, done := startQuery(ctx, "add_expense")
+	created, err := selectOne[entities.Expense](ctx, r.db, query, expense.TripID, expense.PaidBy, expense.Amount,
+		expense.Currency, expense.Category, expense.Note, expense.SpentOn)
+	done(err)
+	if err != nil {
+		return entities.Expense{}, fmt.Errorf("failed to add expense: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *ExpensesRepository) GetTripExpenses(ctx context.Context, tripID int64) ([]entities.Expense, error) {
+	query := `
+		SELECT ` + expenseColumns + `
+		FROM expenses
+		WHERE trip_id = $1
+		ORDER BY spent_on, id
+	`
+
+	ctx, done := startQuery(ctx, "get_trip_expenses")
+	expenses, err := selectAll[entities.Expense](ctx, r.db, query, tripID)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trip expenses: %w", err)
+	}
+
+	return expenses, nil
+}
+
+func (r *ExpensesRepository) DeleteExpense(ctx context.Context, tripID, id, userID int64) error {
+	query := `DELETE FROM expenses WHERE trip_id = $1 AND id = $2 AND paid_by = $3`
+
+	ctx, done := startQuery(ctx, "delete_expense")
+	tag, err := r.db.Exec(ctx, query, tripID, id, userID)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to delete expense: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
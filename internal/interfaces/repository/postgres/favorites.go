@@ -4,27 +4,45 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 
 	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
-	"github.com/jmoiron/sqlx"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	favoriteColumns = `id, user_id, from_currency, to_currency, position, label, default_amount, pinned, created_at`
+	favoritesOrder  = `pinned DESC, position, created_at DESC`
+
+	// getUserFavoritesQuery выполняется на каждое открытие избранного, поэтому подготовлен заранее
+	getUserFavoritesQuery = `
+		SELECT ` + favoriteColumns + `
+		FROM user_favorites
+		WHERE user_id = $1
+		ORDER BY ` + favoritesOrder
 )
 
 type FavoritesRepository struct {
-	db *sqlx.DB
+	db *pgxpool.Pool
+	userData
 }
 
-func NewFavoritesRepository(db *sqlx.DB) *FavoritesRepository {
-	return &FavoritesRepository{db: db}
+func NewFavoritesRepository(db *pgxpool.Pool) *FavoritesRepository {
+	return &FavoritesRepository{db: db, userData: newUserData(db, userTable{name: "user_favorites", where: "user_id = $1"})}
 }
 
 func (r *FavoritesRepository) AddFavorite(ctx context.Context, userID int64, fromCurrency, toCurrency string) error {
+	// Новая пара встает в начало списка
 	query := `
-		INSERT INTO user_favorites (user_id, from_currency, to_currency)
-		VALUES ($1, $2, $3)
+		INSERT INTO user_favorites (user_id, from_currency, to_currency, position)
+		SELECT $1, $2, $3, COALESCE(MIN(position), 0) - 1
+		FROM user_favorites WHERE user_id = $1
 		ON CONFLICT (user_id, from_currency, to_currency) DO NOTHING
 	`
 
-	_, err := r.db.ExecContext(ctx, query, userID, fromCurrency, toCurrency)
+	ctx, done := startQuery(ctx, "add_favorite")
+	_, err := r.db.Exec(ctx, query, userID, fromCurrency, toCurrency)
+	done(err)
 	if err != nil {
 		return fmt.Errorf("failed to add favorite: %w", err)
 	}
@@ -33,16 +51,9 @@ func (r *FavoritesRepository) AddFavorite(ctx context.Context, userID int64, fro
 }
 
 func (r *FavoritesRepository) GetUserFavorites(ctx context.Context, userID int64) ([]entities.UserFavorite, error) {
-	var favorites []entities.UserFavorite
-
-	query := `
-		SELECT id, user_id, from_currency, to_currency, created_at
-		FROM user_favorites 
-		WHERE user_id = $1
-		ORDER BY created_at DESC
-	`
-
-	err := r.db.SelectContext(ctx, &favorites, query, userID)
+	ctx, done := startQuery(ctx, "get_user_favorites")
+	favorites, err := selectAll[entities.UserFavorite](ctx, r.db, "get_user_favorites", userID)
+	done(err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user favorites: %w", err)
 	}
@@ -56,15 +67,138 @@ func (r *FavoritesRepository) RemoveFavorite(ctx context.Context, userID int64,
 		WHERE user_id = $1 AND from_currency = $2 AND to_currency = $3
 	`
 
-	result, err := r.db.ExecContext(ctx, query, userID, fromCurrency, toCurrency)
+	ctx, done := startQuery(ctx, "remove_favorite")
+	tag, err := r.db.Exec(ctx, query, userID, fromCurrency, toCurrency)
+	done(err)
 	if err != nil {
 		return fmt.Errorf("failed to remove favorite: %w", err)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *FavoritesRepository) GetFavorite(ctx context.Context, userID, id int64) (entities.UserFavorite, error) {
+	query := `
+		SELECT ` + favoriteColumns + `
+		FROM user_favorites
+		WHERE user_id = $1 AND id = $2
+	`
+
+	ctx, done := startQuery(ctx, "get_favorite")
+	favorite, err := selectOne[entities.UserFavorite](ctx, r.db, query, userID, id)
+	done(err)
+	if err != nil {
+		return favorite, fmt.Errorf("failed to get favorite: %w", err)
+	}
+
+	return favorite, nil
+}
+
+func (r *FavoritesRepository) MoveFavorite(ctx context.Context, userID, id int64, delta int) error {
+	ctx, done := startQuery(ctx, "move_favorite")
+	err := r.moveFavorite(ctx, userID, id, delta)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to move favorite: %w", err)
+	}
+
+	return nil
+}
+
+// moveFavorite меняет пару местами с соседней и перенумеровывает список пользователя
+func (r *FavoritesRepository) moveFavorite(ctx context.Context, userID, id int64, delta int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT id FROM user_favorites
+		WHERE user_id = $1
+		ORDER BY ` + favoritesOrder + `
+		FOR UPDATE
+	`
+	ids, err := selectValues[int64](ctx, tx, query, userID)
+	if err != nil {
+		return err
+	}
+
+	from := slices.Index(ids, id)
+	if from == -1 {
 		return sql.ErrNoRows
 	}
+	to := from + delta
+	if to < 0 || to >= len(ids) {
+		return nil
+	}
+	ids[from], ids[to] = ids[to], ids[from]
+
+	query = `
+		UPDATE user_favorites f
+		SET position = ordered.position - 1
+		FROM unnest($1::bigint[]) WITH ORDINALITY AS ordered(id, position)
+		WHERE f.id = ordered.id
+	`
+	if _, err := tx.Exec(ctx, query, ids); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *FavoritesRepository) RenameFavorite(ctx context.Context, userID, id int64, label string) error {
+	query := `UPDATE user_favorites SET label = $3 WHERE user_id = $1 AND id = $2`
+
+	ctx, done := startQuery(ctx, "rename_favorite")
+	err := r.updateFavorite(ctx, query, userID, id, label)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to rename favorite: %w", err)
+	}
 
 	return nil
 }
+
+func (r *FavoritesRepository) SetDefaultAmount(ctx context.Context, userID, id int64, amount float64) error {
+	query := `UPDATE user_favorites SET default_amount = $3 WHERE user_id = $1 AND id = $2`
+
+	ctx, done := startQuery(ctx, "set_favorite_amount")
+	err := r.updateFavorite(ctx, query, userID, id, amount)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to set default amount: %w", err)
+	}
+
+	return nil
+}
+
+func (r *FavoritesRepository) SetPinned(ctx context.Context, userID, id int64, pinned bool) error {
+	query := `UPDATE user_favorites SET pinned = $3 WHERE user_id = $1 AND id = $2`
+
+	ctx, done := startQuery(ctx, "pin_favorite")
+	err := r.updateFavorite(ctx, query, userID, id, pinned)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to pin favorite: %w", err)
+	}
+
+	return nil
+}
+
+// updateFavorite обновляет одну пару; sql.ErrNoRows — такой пары у пользователя нет
+func (r *FavoritesRepository) updateFavorite(ctx context.Context, query string, userID, id int64, value any) error {
+	tag, err := r.db.Exec(ctx, query, userID, id, value)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HoldingsRepository struct {
+	db *pgxpool.Pool
+	userData
+}
+
+func NewHoldingsRepository(db *pgxpool.Pool) *HoldingsRepository {
+	return &HoldingsRepository{db: db, userData: newUserData(db, userTable{name: "holdings", where: "user_id = $1"})}
+}
+
+func (r *HoldingsRepository) GetHoldings(ctx context.Context, userID int64) ([]entities.Holding, error) {
+	query := `
+		SELECT user_id, currency, amount, updated_at
+		FROM holdings
+		WHERE user_id = $1
+		ORDER BY currency
+	`
+
+	ctx, done := startQuery(ctx, "get_holdings")
+	holdings, err := selectAll[entities.Holding](ctx, r.db, query, userID)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get holdings: %w", err)
+	}
+
+	return holdings, nil
+}
+
+func (r *HoldingsRepository) AddHolding(ctx context.Context, userID int64, currency string, amount float64) (entities.Holding, error) {
+	query := `
+		INSERT INTO holdings (user_id, currency, amount)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, currency) DO UPDATE
+		SET amount = holdings.amount + EXCLUDED.amount, updated_at = NOW()
+		RETURNING user_id, currency, amount, updated_at
+	`
+
+	ctx, done := startQuery(ctx, "add_holding")
+	holding, err := selectOne[entities.Holding](ctx, r.db, query, userID, currency, amount)
+	done(err)
+	if err != nil {
+		return holding, fmt.Errorf("failed to add holding: %w", err)
+	}
+
+	return holding, nil
+}
+
+func (r *HoldingsRepository) RemoveHolding(ctx context.Context, userID int64, currency string) error {
+	query := `DELETE FROM holdings WHERE user_id = $1 AND currency = $2`
+
+	ctx, done := startQuery(ctx, "remove_holding")
+	tag, err := r.db.Exec(ctx, query, userID, currency)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to remove holding: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/metrics"
+	"github.com/crocxdued/currency-telegram-bot/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// startQuery открывает спан запроса к Postgres; возвращенная функция
+// завершает спан и записывает длительность запроса в метрики
+func startQuery(ctx context.Context, name string) (context.Context, func(error)) {
+	ctx, span := tracing.StartClient(ctx, "postgres."+name,
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", name),
+	)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		metrics.ObserveDBQuery(name, start, err)
+		tracing.Finish(span, err)
+	}
+}
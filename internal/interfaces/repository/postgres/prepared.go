@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// preparedStatements — горячие запросы, которые готовятся на каждом соединении пула
+// заранее; репозитории выполняют их по имени
+var preparedStatements = map[string]string{
+	"get_user_favorites": getUserFavoritesQuery,
+	"touch_user":         touchUserQuery,
+	"get_chat_settings":  getChatSettingsQuery,
+	"add_conversion":     addConversionQuery,
+}
+
+// PrepareStatements готовит горячие запросы на новом соединении; подходит для pgxpool.Config.AfterConnect
+func PrepareStatements(ctx context.Context, conn *pgx.Conn) error {
+	for name, query := range preparedStatements {
+		if _, err := conn.Prepare(ctx, name, query); err != nil {
+			return fmt.Errorf("failed to prepare %s: %w", name, err)
+		}
+	}
+	return nil
+}
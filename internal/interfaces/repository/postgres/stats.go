@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type StatsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewStatsRepository(db *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+func (r *StatsRepository) FavoritesStats(ctx context.Context, topPairs int) (entities.FavoritesStats, error) {
+	var stats entities.FavoritesStats
+
+	query := `
+		SELECT COUNT(DISTINCT user_id), COUNT(*)
+		FROM user_favorites
+	`
+
+	qctx, done := startQuery(ctx, "favorites_totals")
+	err := r.db.QueryRow(qctx, query).Scan(&stats.Users, &stats.Favorites)
+	done(err)
+	if err != nil {
+		return stats, fmt.Errorf("failed to count favorites: %w", err)
+	}
+
+	query = `
+		SELECT from_currency, to_currency, COUNT(*) AS count
+		FROM user_favorites
+		GROUP BY from_currency, to_currency
+		ORDER BY count DESC, from_currency, to_currency
+		LIMIT $1
+	`
+
+	qctx, done = startQuery(ctx, "favorites_top_pairs")
+	stats.TopPairs, err = selectAll[entities.PairCount](qctx, r.db, query, topPairs)
+	done(err)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get top pairs: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// systemTables — таблицы без персональных данных пользователей и причина, по которой
+// они не входят в /mydata и /forgetme. Новая таблица должна попасть либо сюда, либо
+// в userData своего репозитория, иначе тест покрытия миграций упадет.
+var systemTables = map[string]string{
+	"admin_audit_log": "журнал действий администраторов",
+	"broadcasts":      "тексты рассылок администраторов",
+}
+
+// forgottenName — имя участника группы в обезличенных записях
+const forgottenName = "Удаленный пользователь"
+
+// userTable — строки пользователя в таблице: where выбирает их по Telegram ID в $1.
+// Если задан anonymize, строки не удаляются, а обезличиваются этим SET-выражением:
+// так остаются общие записи группы и не меняются долги остальных участников.
+// В anonymize $2 — случайный отрицательный псевдоним, общий для всех таблиц источника,
+// чтобы записи забытого пользователя в группе сходились между собой.
+type userTable struct {
+	name      string
+	where     string
+	anonymize string
+}
+
+// userData выгружает и удаляет данные пользователя в таблицах репозитория; встраиваясь
+// в репозиторий, делает его источником services.UserDataRegistry
+type userData struct {
+	db     *pgxpool.Pool
+	tables []userTable // в порядке удаления: зависимые таблицы первыми
+}
+
+func newUserData(db *pgxpool.Pool, tables ...userTable) userData {
+	return userData{db: db, tables: tables}
+}
+
+func (d userData) UserDataTables() []string {
+	var names []string
+	for _, table := range d.tables {
+		if !slices.Contains(names, table.name) {
+			names = append(names, table.name)
+		}
+	}
+	return names
+}
+
+func (d userData) ExportUserData(ctx context.Context, userID int64) (services.UserRows, error) {
+	rows := make(services.UserRows)
+	for _, table := range d.tables {
+		query := `SELECT * FROM ` + table.name + ` WHERE ` + table.where
+
+		ctx, done := startQuery(ctx, "export_"+table.name)
+		tableRows, err := selectMaps(ctx, d.db, query, userID)
+		done(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table.name, err)
+		}
+		rows[table.name] = append(rows[table.name], tableRows...)
+	}
+	return rows, nil
+}
+
+func (d userData) DeleteUserData(ctx context.Context, userID int64) (int64, error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	pseudonym := -rand.Int64N(math.MaxInt64) - 1
+
+	var total int64
+	for _, table := range d.tables {
+		query := `DELETE FROM ` + table.name + ` WHERE ` + table.where
+		args := []any{userID}
+		if table.anonymize != "" {
+			query = `UPDATE ` + table.name + ` SET ` + table.anonymize + ` WHERE ` + table.where
+			if strings.Contains(table.anonymize, "$2") {
+				args = append(args, pseudonym)
+			}
+		}
+
+		ctx, done := startQuery(ctx, "forget_"+table.name)
+		tag, err := tx.Exec(ctx, query, args...)
+		done(err)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete user data from %s: %w", table.name, err)
+		}
+		total += tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit user data deletion: %w", err)
+	}
+	return total, nil
+}
+
+// selectMaps читает строки как колонка → значение; NUMERIC отдается строкой, чтобы не терять точность
+func selectMaps(ctx context.Context, db *pgxpool.Pool, query string, args ...any) ([]map[string]any, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := pgx.CollectRows(rows, pgx.RowToMap)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range result {
+		for column, value := range row {
+			if numeric, ok := value.(pgtype.Numeric); ok {
+				row[column], _ = numeric.Value()
+			}
+		}
+	}
+	return result, nil
+}
+
+// UserDataSources возвращает все репозитории с данными пользователей для services.UserDataRegistry
+func UserDataSources(db *pgxpool.Pool) []services.UserDataSource {
+	return []services.UserDataSource{
+		NewFavoritesRepository(db),
+		NewUsersRepository(db),
+		NewBanRepository(db),
+		NewChatSettingsRepository(db),
+		NewExpensesRepository(db),
+		NewDebtsRepository(db),
+		NewHoldingsRepository(db),
+		NewConversionsRepository(db),
+	}
+}
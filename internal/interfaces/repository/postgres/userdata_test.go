@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var createTable = regexp.MustCompile(`(?i)CREATE TABLE (?:IF NOT EXISTS )?(\w+)`)
+
+// Каждая таблица из миграций должна быть либо источником данных пользователя, либо системной
+func TestUserDataSourcesCoverMigrations(t *testing.T) {
+	files, err := filepath.Glob("../../../../migrations/*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	registered := services.NewUserDataRegistry(UserDataSources(nil)...).Tables()
+
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		require.NoError(t, err)
+
+		for _, match := range createTable.FindAllStringSubmatch(string(body), -1) {
+			table := match[1]
+			_, system := systemTables[table]
+			assert.True(t, slices.Contains(registered, table) || system,
+				"table %s from %s is neither registered in UserDataSources nor listed in systemTables",
+				table, filepath.Base(file))
+			assert.False(t, slices.Contains(registered, table) && system, "table %s is both user data and system", table)
+		}
+	}
+}
+
+// Записи, от которых зависят другие участники группы, при /forgetme обезличиваются, а не удаляются
+func TestGroupRowsAreAnonymized(t *testing.T) {
+	anonymized := make(map[string]bool)
+	for _, data := range []userData{
+		NewExpensesRepository(nil).userData,
+		NewDebtsRepository(nil).userData,
+		NewChatSettingsRepository(nil).userData,
+	} {
+		for _, table := range data.tables {
+			if table.anonymize != "" {
+				anonymized[table.name] = true
+			}
+		}
+	}
+
+	for _, table := range []string{"expenses", "trip_members", "trips", "split_shares", "splits", "settlements", "chat_settings"} {
+		assert.True(t, anonymized[table], "group rows of %s must be anonymized", table)
+	}
+}
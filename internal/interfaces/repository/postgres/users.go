@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UsersRepository struct {
+	db *pgxpool.Pool
+	userData
+}
+
+func NewUsersRepository(db *pgxpool.Pool) *UsersRepository {
+	return &UsersRepository{db: db, userData: newUserData(db, userTable{name: "users", where: "user_id = $1"})}
+}
+
+// touchUserQuery выполняется на каждое сообщение, поэтому подготовлен заранее
+const touchUserQuery = `
+	INSERT INTO users (user_id, username)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET username = EXCLUDED.username, last_seen_at = NOW(), blocked_at = NULL
+`
+
+func (r *UsersRepository) Touch(ctx context.Context, userID int64, username string) error {
+	ctx, done := startQuery(ctx, "touch_user")
+	_, err := r.db.Exec(ctx, "touch_user", userID, username)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to touch user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UsersRepository) MarkBlocked(ctx context.Context, userID int64) error {
+	query := `
+		UPDATE users SET blocked_at = NOW()
+		WHERE user_id = $1 AND blocked_at IS NULL
+	`
+
+	ctx, done := startQuery(ctx, "mark_user_blocked")
+	_, err := r.db.Exec(ctx, query, userID)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to mark user blocked: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UsersRepository) Counts(ctx context.Context) (entities.UserCounts, error) {
+	query := `
+		SELECT
+			COUNT(*) AS total,
+			COUNT(blocked_at) AS blocked,
+			COUNT(*) FILTER (WHERE last_seen_at > NOW() - INTERVAL '1 day') AS active
+		FROM users
+	`
+
+	ctx, done := startQuery(ctx, "count_users")
+	counts, err := selectOne[entities.UserCounts](ctx, r.db, query)
+	done(err)
+	if err != nil {
+		return counts, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *UsersRepository) ActiveIDsAfter(ctx context.Context, afterID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT user_id FROM users
+		WHERE user_id > $1 AND blocked_at IS NULL
+		ORDER BY user_id
+		LIMIT $2
+	`
+
+	ctx, done := startQuery(ctx, "active_user_ids")
+	ids, err := selectValues[int64](ctx, r.db, query, afterID, limit)
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active users: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *UsersRepository) FindByUsername(ctx context.Context, username string) (entities.User, error) {
+	query := `
+		SELECT user_id, username, first_seen_at, last_seen_at, blocked_at, preferred_currency
+		FROM users
+		WHERE LOWER(username) = LOWER($1) AND username <> ''
+		ORDER BY last_seen_at DESC
+		LIMIT 1
+	`
+
+	ctx, done := startQuery(ctx, "find_user_by_username")
+	user, err := selectOne[entities.User](ctx, r.db, query, username)
+	done(err)
+	if err != nil {
+		return user, fmt.Errorf("failed to find user by username: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *UsersRepository) SetPreferredCurrency(ctx context.Context, userID int64, currency string) error {
+	query := `
+		INSERT INTO users (user_id, preferred_currency)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET preferred_currency = EXCLUDED.preferred_currency
+	`
+
+	ctx, done := startQuery(ctx, "set_preferred_currency")
+	_, err := r.db.Exec(ctx, query, userID, currency)
+	done(err)
+	if err != nil {
+		return fmt.Errorf("failed to set preferred currency: %w", err)
+	}
+
+	return nil
+}
+
+func (r *UsersRepository) PreferredCurrencies(ctx context.Context, userIDs []int64) (map[int64]string, error) {
+	query := `
+		SELECT user_id, preferred_currency FROM users
+		WHERE user_id = ANY($1) AND preferred_currency <> ''
+	`
+
+	currencies := make(map[int64]string)
+	var userID int64
+	var currency string
+
+	ctx, done := startQuery(ctx, "get_preferred_currencies")
+	rows, err := r.db.Query(ctx, query, userIDs)
+	if err == nil {
+		_, err = pgx.ForEachRow(rows, []any{&userID, &currency}, func() error {
+			currencies[userID] = currency
+			return nil
+		})
+	}
+	done(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferred currencies: %w", err)
+	}
+
+	return currencies, nil
+}
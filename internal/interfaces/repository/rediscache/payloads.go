@@ -0,0 +1,38 @@
+package rediscache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// PayloadStore хранит длинные данные инлайн-кнопок в Redis, чтобы кнопку,
+// созданную одной репликой, могла обработать другая
+type PayloadStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewPayloadStore(client redis.UniversalClient, prefix string) *PayloadStore {
+	if prefix == "" {
+		prefix = DefaultKeyPrefix
+	}
+	return &PayloadStore{client: client, prefix: prefix}
+}
+
+func (s *PayloadStore) Save(ctx context.Context, key, payload string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+"callback:"+key, payload, ttl).Err()
+}
+
+func (s *PayloadStore) Load(ctx context.Context, key string) (string, bool, error) {
+	payload, err := s.client.Get(ctx, s.prefix+"callback:"+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return payload, true, nil
+}
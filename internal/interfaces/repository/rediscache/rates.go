@@ -0,0 +1,236 @@
+package rediscache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/crocxdued/currency-telegram-bot/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// DefaultKeyPrefix отделяет ключи бота от остальных данных в Redis
+const DefaultKeyPrefix = "currency-bot:"
+
+// releaseScript снимает блокировку, только если она все еще принадлежит нам
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type cachedRate struct {
+	Rate      entities.ExchangeRate `json:"rate"`
+	ExpiresAt time.Time             `json:"expires_at"`
+}
+
+// RatesStore хранит курсы в Redis, чтобы несколько реплик бота делили один кэш
+type RatesStore struct {
+	client   redis.UniversalClient
+	prefix   string
+	ttl      services.CacheTTLPolicy
+	maxStale time.Duration
+
+	// Счетчики ведутся в пределах реплики
+	hits      atomic.Uint64
+	staleHits atomic.Uint64
+	misses    atomic.Uint64
+}
+
+// Option настраивает хранилище
+type Option func(*RatesStore)
+
+// WithKeyPrefix задает префикс ключей
+func WithKeyPrefix(prefix string) Option {
+	return func(s *RatesStore) {
+		if prefix != "" {
+			s.prefix = prefix
+		}
+	}
+}
+
+// WithMaxStale задает, сколько истекшая запись хранится как резервная
+func WithMaxStale(maxStale time.Duration) Option {
+	return func(s *RatesStore) {
+		if maxStale > 0 {
+			s.maxStale = maxStale
+		}
+	}
+}
+
+// WithTTLPolicy заменяет единый срок свежести из ttlMinutes политикой с переопределениями
+func WithTTLPolicy(policy services.CacheTTLPolicy) Option {
+	return func(s *RatesStore) {
+		s.ttl = policy
+	}
+}
+
+func NewRatesStore(client redis.UniversalClient, ttlMinutes int, opts ...Option) *RatesStore {
+	s := &RatesStore{
+		client: client,
+		prefix: DefaultKeyPrefix,
+		ttl:    services.NewCacheTTLPolicy(time.Duration(ttlMinutes)*time.Minute, nil, nil),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *RatesStore) Get(ctx context.Context, from, to string) (entities.ExchangeRate, bool) {
+	cached, ok := s.load(ctx, from, to)
+	if !ok || time.Now().After(cached.ExpiresAt) {
+		s.misses.Add(1)
+		return entities.ExchangeRate{}, false
+	}
+	s.hits.Add(1)
+
+	rate := cached.Rate
+	rate.FromCache = true
+	return rate, true
+}
+
+// GetStale возвращает запись и после истечения срока жизни: Redis хранит ее еще maxStale
+func (s *RatesStore) GetStale(ctx context.Context, from, to string) (entities.ExchangeRate, bool) {
+	cached, ok := s.load(ctx, from, to)
+	if !ok {
+		return entities.ExchangeRate{}, false
+	}
+
+	rate := cached.Rate
+	rate.FromCache = true
+	rate.Stale = time.Now().After(cached.ExpiresAt)
+	if rate.Stale {
+		s.staleHits.Add(1)
+	}
+	return rate, true
+}
+
+func (s *RatesStore) Remaining(ctx context.Context, from, to string) (time.Duration, bool) {
+	cached, ok := s.load(ctx, from, to)
+	if !ok {
+		return 0, false
+	}
+	return time.Until(cached.ExpiresAt), true
+}
+
+func (s *RatesStore) Set(ctx context.Context, rate entities.ExchangeRate) {
+	rate.FromCache = false
+	rate.Stale = false
+
+	ttl := s.ttl.TTL(rate)
+	payload, err := json.Marshal(cachedRate{
+		Rate:      rate,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warn("Failed to encode rate", zap.String("from", rate.From.Code), zap.String("to", rate.To.Code), zap.Error(err))
+		return
+	}
+
+	// Ключ живет дольше срока свежести, чтобы служить резервом при сбое провайдеров
+	key := s.rateKey(rate.From.Code, rate.To.Code)
+	if err := s.client.Set(ctx, key, payload, ttl+s.maxStale).Err(); err != nil {
+		logger.FromContext(ctx).Warn("Failed to store rate in redis", zap.Error(err))
+	}
+}
+
+// Lock захватывает блокировку SET NX с уникальным токеном владельца
+func (s *RatesStore) Lock(ctx context.Context, key string, ttl time.Duration) (func(), bool) {
+	token, err := newToken()
+	if err != nil {
+		return func() {}, false
+	}
+
+	lockKey := s.prefix + "lock:" + key
+	acquired, err := s.client.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil {
+		// Без Redis координация невозможна — обновляем сами, чтобы не остаться без курса
+		logger.FromContext(ctx).Warn("Failed to acquire redis lock", zap.String("key", lockKey), zap.Error(err))
+		return func() {}, true
+	}
+	if !acquired {
+		return func() {}, false
+	}
+
+	return func() {
+		// Контекст запроса мог быть уже отменен, а блокировку нужно снять
+		if err := releaseScript.Run(context.Background(), s.client, []string{lockKey}, token).Err(); err != nil {
+			logger.FromContext(ctx).Warn("Failed to release redis lock", zap.String("key", lockKey), zap.Error(err))
+		}
+	}, true
+}
+
+// Stats возвращает счетчики обращений этой реплики; размер общего хранилища не считается
+func (s *RatesStore) Stats() services.CacheStats {
+	return services.CacheStats{
+		Hits:      s.hits.Load(),
+		StaleHits: s.staleHits.Load(),
+		Misses:    s.misses.Load(),
+		Size:      -1,
+	}
+}
+
+// Flush удаляет все курсы с префиксом бота, не трогая блокировки
+func (s *RatesStore) Flush(ctx context.Context) error {
+	iter := s.client.Scan(ctx, 0, s.prefix+"rate:*", 100).Iterator()
+
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) == 100 {
+			if err := s.client.Del(ctx, keys...).Err(); err != nil {
+				return fmt.Errorf("failed to delete cached rates: %w", err)
+			}
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to scan cached rates: %w", err)
+	}
+
+	if len(keys) > 0 {
+		if err := s.client.Del(ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("failed to delete cached rates: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *RatesStore) load(ctx context.Context, from, to string) (cachedRate, bool) {
+	payload, err := s.client.Get(ctx, s.rateKey(from, to)).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.FromContext(ctx).Warn("Failed to read rate from redis", zap.Error(err))
+		}
+		return cachedRate{}, false
+	}
+
+	var cached cachedRate
+	if err := json.Unmarshal(payload, &cached); err != nil {
+		logger.FromContext(ctx).Warn("Failed to decode cached rate", zap.String("from", from), zap.String("to", to), zap.Error(err))
+		return cachedRate{}, false
+	}
+	return cached, true
+}
+
+func (s *RatesStore) rateKey(from, to string) string {
+	return s.prefix + "rate:" + from + "_" + to
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
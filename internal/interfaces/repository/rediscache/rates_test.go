@@ -0,0 +1,165 @@
+package rediscache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, opts ...Option) (*RatesStore, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return NewRatesStore(client, 1, opts...), server
+}
+
+func TestRatesStore_SetGet(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t)
+
+	published := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	store.Set(ctx, entities.NewExchangeRate("USD", "RUB", 81.5, "CBR", published))
+
+	rate, found := store.Get(ctx, "USD", "RUB")
+	require.True(t, found)
+	assert.InDelta(t, 81.5, rate.Rate, 0.0001)
+	assert.Equal(t, "CBR", rate.Provider)
+	assert.True(t, rate.LastUpdated.Equal(published))
+	assert.True(t, rate.FromCache)
+	assert.False(t, rate.Stale)
+
+	assert.Equal(t, time.Minute, server.TTL(DefaultKeyPrefix+"rate:USD_RUB"))
+
+	_, found = store.Get(ctx, "USD", "EUR")
+	assert.False(t, found)
+}
+
+func TestRatesStore_TTLOverrides(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t, WithTTLPolicy(services.NewCacheTTLPolicy(time.Minute,
+		map[string]time.Duration{"cbr": 12 * time.Hour},
+		map[string]time.Duration{"BTC": 30 * time.Second},
+	)))
+
+	store.Set(ctx, entities.NewExchangeRate("USD", "RUB", 81.5, "CBR", time.Time{}))
+	store.Set(ctx, entities.NewExchangeRate("BTC", "RUB", 9e6, "CBR", time.Time{}))
+	store.Set(ctx, entities.NewExchangeRate("USD", "EUR", 0.9, "frankfurter", time.Time{}))
+
+	assert.Equal(t, 12*time.Hour, server.TTL(DefaultKeyPrefix+"rate:USD_RUB"))
+	assert.Equal(t, 30*time.Second, server.TTL(DefaultKeyPrefix+"rate:BTC_RUB"), "currency TTL wins over provider TTL")
+	assert.Equal(t, time.Minute, server.TTL(DefaultKeyPrefix+"rate:USD_EUR"))
+}
+
+func TestRatesStore_StaleFallback(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t, WithMaxStale(time.Hour))
+
+	store.Set(ctx, entities.NewExchangeRate("USD", "RUB", 81.5, "CBR", time.Time{}))
+	assert.Equal(t, time.Hour+time.Minute, server.TTL(DefaultKeyPrefix+"rate:USD_RUB"))
+
+	// Сдвигаем срок свежести в прошлое, ключ при этом еще жив
+	store.ttl = services.NewCacheTTLPolicy(-time.Second, nil, nil)
+	store.Set(ctx, entities.NewExchangeRate("USD", "RUB", 80.0, "CBR", time.Time{}))
+
+	_, found := store.Get(ctx, "USD", "RUB")
+	assert.False(t, found)
+
+	rate, found := store.GetStale(ctx, "USD", "RUB")
+	require.True(t, found)
+	assert.True(t, rate.Stale)
+	assert.InDelta(t, 80.0, rate.Rate, 0.0001)
+
+	server.FastForward(time.Hour)
+	_, found = store.GetStale(ctx, "USD", "RUB")
+	assert.False(t, found)
+}
+
+func TestRatesStore_Lock(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t)
+
+	release, ok := store.Lock(ctx, "refresh:USD_RUB", 10*time.Second)
+	require.True(t, ok)
+
+	// Вторая реплика не может захватить ту же блокировку
+	other := NewRatesStore(store.client, 1)
+	_, ok = other.Lock(ctx, "refresh:USD_RUB", 10*time.Second)
+	assert.False(t, ok)
+
+	release()
+	assert.False(t, server.Exists(DefaultKeyPrefix+"lock:refresh:USD_RUB"))
+
+	_, ok = other.Lock(ctx, "refresh:USD_RUB", 10*time.Second)
+	assert.True(t, ok)
+
+	// Блокировка истекает сама, если владелец не освободил ее
+	server.FastForward(11 * time.Second)
+	_, ok = store.Lock(ctx, "refresh:USD_RUB", 10*time.Second)
+	assert.True(t, ok)
+}
+
+func TestRatesStore_ReleaseDoesNotStealForeignLock(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t)
+
+	release, ok := store.Lock(ctx, "refresh:EUR_RUB", time.Second)
+	require.True(t, ok)
+
+	server.FastForward(2 * time.Second)
+	_, ok = store.Lock(ctx, "refresh:EUR_RUB", 10*time.Second)
+	require.True(t, ok)
+
+	// Старый владелец не должен снять чужую блокировку
+	release()
+	assert.True(t, server.Exists(DefaultKeyPrefix+"lock:refresh:EUR_RUB"))
+}
+
+func TestRatesStore_FlushAndStats(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestStore(t)
+
+	store.Set(ctx, entities.NewExchangeRate("USD", "RUB", 81.5, "CBR", time.Time{}))
+	store.Set(ctx, entities.NewExchangeRate("EUR", "RUB", 95.1, "CBR", time.Time{}))
+	require.NoError(t, server.Set("unrelated", "value"))
+
+	store.Get(ctx, "USD", "RUB")
+	store.Get(ctx, "GBP", "RUB")
+
+	stats := store.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+
+	require.NoError(t, store.Flush(ctx))
+
+	_, found := store.GetStale(ctx, "USD", "RUB")
+	assert.False(t, found)
+	assert.True(t, server.Exists("unrelated"))
+}
+
+func TestPayloadStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	store := NewPayloadStore(client, "")
+	require.NoError(t, store.Save(ctx, "k1", "conv:10:USD:RUB", time.Hour))
+	assert.Equal(t, time.Hour, server.TTL(DefaultKeyPrefix+"callback:k1"))
+
+	payload, found, err := store.Load(ctx, "k1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "conv:10:USD:RUB", payload)
+
+	_, found, err = store.Load(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// cacheCollector снимает счетчики кэша курсов в момент запроса метрик
+type cacheCollector struct {
+	stats func() services.CacheStats
+
+	hits      *prometheus.Desc
+	staleHits *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	size      *prometheus.Desc
+	hitRatio  *prometheus.Desc
+}
+
+// RegisterCache регистрирует метрики кэша курсов
+func RegisterCache(stats func() services.CacheStats) error {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "rates_cache", name), help, nil, nil)
+	}
+
+	return prometheus.Register(&cacheCollector{
+		stats:     stats,
+		hits:      desc("hits_total", "Rates cache hits."),
+		staleHits: desc("stale_hits_total", "Stale rates served when providers were unavailable."),
+		misses:    desc("misses_total", "Rates cache misses."),
+		evictions: desc("evictions_total", "Rates evicted to stay within capacity."),
+		size:      desc("entries", "Rates currently stored in the cache."),
+		hitRatio:  desc("hit_ratio", "Share of cache lookups served from the cache."),
+	})
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.staleHits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.size
+	ch <- c.hitRatio
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.stats()
+
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.staleHits, prometheus.CounterValue, float64(stats.StaleHits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	// Размер общего хранилища в Redis реплика не знает
+	if stats.Size >= 0 {
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	}
+	ch <- prometheus.MustNewConstMetric(c.hitRatio, prometheus.GaugeValue, stats.HitRatio())
+}
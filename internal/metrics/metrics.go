@@ -0,0 +1,106 @@
+// Package metrics описывает метрики Prometheus, которые бот отдает на /metrics
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "currency_bot"
+
+var (
+	// UpdatesTotal считает обработанные обновления Telegram по типу и команде
+	UpdatesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "updates_total",
+		Help:      "Telegram updates handled, by update type and command.",
+	}, []string{"type", "command"})
+
+	// HandlerDuration измеряет время обработки обновления
+	HandlerDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent handling a Telegram update.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type", "command"})
+
+	// ProviderRequestsTotal считает запросы к провайдерам курсов по результату
+	ProviderRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_requests_total",
+		Help:      "Exchange provider GetRate calls, by provider and result.",
+	}, []string{"provider", "result"})
+
+	// ProviderDuration измеряет время ответа провайдера
+	ProviderDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Exchange provider GetRate latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	// DBQueryDuration измеряет время запросов к Postgres
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Postgres query latency, by query name and result.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"query", "result"})
+
+	// TelegramSendFailuresTotal считает неудачные вызовы Bot API
+	TelegramSendFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_send_failures_total",
+		Help:      "Failed Telegram Bot API sends, by method.",
+	}, []string{"method"})
+
+	// UpdatesThrottledTotal считает обновления, отброшенные ограничением частоты
+	UpdatesThrottledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "updates_throttled_total",
+		Help:      "Updates dropped by per-user throttling, by reason (limited, banned).",
+	}, []string{"reason"})
+
+	// TelegramRetriesTotal считает повторы запросов к Bot API
+	TelegramRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_retries_total",
+		Help:      "Telegram Bot API request retries, by method and reason (flood, server, network).",
+	}, []string{"method", "reason"})
+
+	// AutoConversionsTotal считает найденные в группах упоминания сумм
+	AutoConversionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auto_conversions_total",
+		Help:      "Money mentions found in group messages, by result (sent, cooldown, repeat, error).",
+	}, []string{"result"})
+
+	// ExportsTotal считает выгрузки файлов
+	ExportsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "exports_total",
+		Help:      "File exports, by dataset (favorites, rates, history), format (csv, xlsx) and result (ok, empty, error).",
+	}, []string{"dataset", "format", "result"})
+)
+
+// Result возвращает значение метки result по ошибке
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// ObserveDBQuery фиксирует длительность запроса к базе, начатого в start
+func ObserveDBQuery(query string, start time.Time, err error) {
+	DBQueryDuration.WithLabelValues(query, Result(err)).Observe(time.Since(start).Seconds())
+}
+
+// Handler отдает метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+)
+
+// instrumentedProvider считает запросы, ошибки и время ответа провайдера
+type instrumentedProvider struct {
+	services.ExchangeProvider
+}
+
+// instrumentedHistoricalProvider сохраняет доступ к истории курсов обернутого провайдера
+type instrumentedHistoricalProvider struct {
+	instrumentedProvider
+	historical services.HistoricalProvider
+}
+
+// InstrumentProvider оборачивает провайдера сбором метрик
+func InstrumentProvider(provider services.ExchangeProvider) services.ExchangeProvider {
+	if historical, ok := provider.(services.HistoricalProvider); ok {
+		return &instrumentedHistoricalProvider{
+			instrumentedProvider: instrumentedProvider{ExchangeProvider: provider},
+			historical:           historical,
+		}
+	}
+	return &instrumentedProvider{ExchangeProvider: provider}
+}
+
+func (p *instrumentedHistoricalProvider) GetRateAt(ctx context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	name := p.GetName()
+	start := time.Now()
+
+	rate, err := p.historical.GetRateAt(ctx, from, to, date)
+
+	ProviderDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	ProviderRequestsTotal.WithLabelValues(name, Result(err)).Inc()
+
+	return rate, err
+}
+
+func (p *instrumentedProvider) GetRate(ctx context.Context, from, to string) (entities.ExchangeRate, error) {
+	name := p.GetName()
+	start := time.Now()
+
+	rate, err := p.ExchangeProvider.GetRate(ctx, from, to)
+
+	ProviderDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	ProviderRequestsTotal.WithLabelValues(name, Result(err)).Inc()
+
+	return rate, err
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/entities"
+	"github.com/crocxdued/currency-telegram-bot/internal/domain/services"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubProvider struct {
+	err error
+}
+
+func (p *stubProvider) GetRate(_ context.Context, from, to string) (entities.ExchangeRate, error) {
+	if p.err != nil {
+		return entities.ExchangeRate{}, p.err
+	}
+	return entities.NewExchangeRate(from, to, 1.5, p.GetName(), time.Time{}), nil
+}
+
+func (p *stubProvider) GetName() string   { return "stub" }
+func (p *stubProvider) IsAvailable() bool { return true }
+
+func TestInstrumentProvider(t *testing.T) {
+	ok := InstrumentProvider(&stubProvider{})
+	failing := InstrumentProvider(&stubProvider{err: errors.New("timeout")})
+
+	okBefore := testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("stub", "ok"))
+	errBefore := testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("stub", "error"))
+
+	rate, err := ok.GetRate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.5, rate.Rate, 0.001)
+
+	_, err = failing.GetRate(context.Background(), "USD", "EUR")
+	assert.Error(t, err)
+
+	assert.Equal(t, okBefore+1, testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("stub", "ok")))
+	assert.Equal(t, errBefore+1, testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("stub", "error")))
+	assert.Equal(t, "stub", ok.GetName())
+}
+
+type stubHistoricalProvider struct {
+	stubProvider
+}
+
+func (p *stubHistoricalProvider) GetRateAt(_ context.Context, from, to string, date time.Time) (entities.ExchangeRate, error) {
+	return entities.NewExchangeRate(from, to, 1.4, p.GetName(), date), nil
+}
+
+func TestInstrumentProvider_KeepsHistory(t *testing.T) {
+	_, plain := InstrumentProvider(&stubProvider{}).(services.HistoricalProvider)
+	assert.False(t, plain)
+
+	historical, ok := InstrumentProvider(&stubHistoricalProvider{}).(services.HistoricalProvider)
+	assert.True(t, ok)
+
+	before := testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("stub", "ok"))
+	rate, err := historical.GetRateAt(context.Background(), "USD", "EUR", time.Now().AddDate(0, 0, -1))
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.4, rate.Rate, 0.001)
+	assert.Equal(t, before+1, testutil.ToFloat64(ProviderRequestsTotal.WithLabelValues("stub", "ok")))
+}
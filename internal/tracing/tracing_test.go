@@ -0,0 +1,53 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInit(t *testing.T) {
+	for _, exporter := range []string{ExporterNone, ExporterStdout, ExporterOTLP} {
+		shutdown, err := Init(context.Background(), Config{
+			Exporter:    exporter,
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+			ServiceName: "currency-bot-test",
+		})
+		require.NoError(t, err, exporter)
+		assert.NoError(t, shutdown(context.Background()), exporter)
+	}
+
+	_, err := Init(context.Background(), Config{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
+
+func TestStartFinish(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	ctx, parent := Start(context.Background(), "parent")
+	assert.NotEmpty(t, TraceID(ctx))
+
+	_, child := StartClient(ctx, "child")
+	Finish(child, errors.New("provider down"))
+	Finish(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id BIGINT NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_audit_log_admin_id ON admin_audit_log(admin_id);
+CREATE INDEX idx_admin_audit_log_created_at ON admin_audit_log(created_at);
+
+-- +goose Down
+DROP TABLE admin_audit_log;
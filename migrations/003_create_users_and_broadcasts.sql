@@ -0,0 +1,37 @@
+-- +goose Up
+CREATE TABLE users (
+    user_id BIGINT PRIMARY KEY,
+    username VARCHAR(64) NOT NULL DEFAULT '',
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    blocked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_users_active ON users(user_id) WHERE blocked_at IS NULL;
+
+-- Пользователи, уже сохранившие избранное, получают рассылки сразу после миграции
+INSERT INTO users (user_id, first_seen_at, last_seen_at)
+SELECT user_id, MIN(created_at), MAX(created_at)
+FROM user_favorites
+GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
+
+CREATE TABLE broadcasts (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id BIGINT NOT NULL,
+    text TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'draft',
+    -- last_user_id — курсор доставки: рассылка идет по возрастанию user_id
+    last_user_id BIGINT NOT NULL DEFAULT 0,
+    sent INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_broadcasts_status ON broadcasts(status);
+
+-- +goose Down
+DROP TABLE broadcasts;
+DROP TABLE users;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE user_bans (
+    user_id BIGINT PRIMARY KEY,
+    reason VARCHAR(64) NOT NULL,
+    banned_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_bans_banned_until ON user_bans(banned_until);
+
+-- +goose Down
+DROP TABLE user_bans;
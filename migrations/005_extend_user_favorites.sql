@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE user_favorites
+    ADD COLUMN position INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN label VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN default_amount NUMERIC(20, 4) NOT NULL DEFAULT 1,
+    ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Сохраняем прежний порядок: новые пары сверху
+UPDATE user_favorites f
+SET position = ordered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at DESC) - 1 AS position
+    FROM user_favorites
+) ordered
+WHERE f.id = ordered.id;
+
+CREATE INDEX idx_user_favorites_order ON user_favorites(user_id, pinned DESC, position);
+
+-- +goose Down
+DROP INDEX idx_user_favorites_order;
+
+ALTER TABLE user_favorites
+    DROP COLUMN pinned,
+    DROP COLUMN default_amount,
+    DROP COLUMN label,
+    DROP COLUMN position;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Настройки чата: в личном чате — настройки пользователя, в группе — общие для участников
+CREATE TABLE chat_settings (
+    chat_id BIGINT PRIMARY KEY,
+    default_from VARCHAR(3) NOT NULL DEFAULT '',
+    default_to VARCHAR(3) NOT NULL DEFAULT '',
+    trigger_pattern VARCHAR(200) NOT NULL DEFAULT '',
+    updated_by BIGINT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE chat_settings;
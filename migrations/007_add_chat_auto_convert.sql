@@ -0,0 +1,10 @@
+-- +goose Up
+-- Автоматический пересчет сумм, упомянутых в сообщениях группы, в валюты auto_currencies
+ALTER TABLE chat_settings
+    ADD COLUMN auto_convert BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN auto_currencies VARCHAR(64) NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE chat_settings
+    DROP COLUMN auto_convert,
+    DROP COLUMN auto_currencies;
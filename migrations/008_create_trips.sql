@@ -0,0 +1,41 @@
+-- +goose Up
+-- Поездки ведутся в чате: в личном — для себя, в группе — на всех участников
+CREATE TABLE trips (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    base_currency VARCHAR(3) NOT NULL,
+    created_by BIGINT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- В чате одновременно ведется не больше одной поездки
+CREATE UNIQUE INDEX idx_trips_active_chat ON trips(chat_id) WHERE active;
+
+CREATE TABLE trip_members (
+    trip_id BIGINT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (trip_id, user_id)
+);
+
+CREATE TABLE expenses (
+    id BIGSERIAL PRIMARY KEY,
+    trip_id BIGINT NOT NULL REFERENCES trips(id) ON DELETE CASCADE,
+    paid_by BIGINT NOT NULL,
+    amount NUMERIC(20, 4) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    category VARCHAR(32) NOT NULL,
+    note VARCHAR(200) NOT NULL DEFAULT '',
+    spent_on DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_expenses_trip ON expenses(trip_id, spent_on);
+
+-- +goose Down
+DROP TABLE expenses;
+DROP TABLE trip_members;
+DROP TABLE trips;
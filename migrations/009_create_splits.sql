@@ -0,0 +1,49 @@
+-- +goose Up
+-- Валюта, в которой пользователь хочет видеть взаиморасчеты
+ALTER TABLE users ADD COLUMN preferred_currency VARCHAR(3) NOT NULL DEFAULT '';
+
+CREATE INDEX idx_users_username ON users(LOWER(username)) WHERE username <> '';
+
+-- Общий счет: paid_by заплатил за всех, участники должны ему свои доли
+CREATE TABLE splits (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    paid_by BIGINT NOT NULL,
+    amount NUMERIC(20, 2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    note VARCHAR(200) NOT NULL DEFAULT '',
+    -- pending ждет подтверждения заплатившего и в расчетах не участвует
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_splits_chat ON splits(chat_id, status);
+
+-- Доли участников, включая долю заплатившего; name запоминается для отчетов
+CREATE TABLE split_shares (
+    split_id BIGINT NOT NULL REFERENCES splits(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    amount NUMERIC(20, 2) NOT NULL,
+    PRIMARY KEY (split_id, user_id)
+);
+
+-- Подтвержденные получателем переводы в счет долга
+CREATE TABLE settlements (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    from_user BIGINT NOT NULL,
+    to_user BIGINT NOT NULL,
+    amount NUMERIC(20, 2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_settlements_chat ON settlements(chat_id);
+
+-- +goose Down
+DROP TABLE settlements;
+DROP TABLE split_shares;
+DROP TABLE splits;
+DROP INDEX idx_users_username;
+ALTER TABLE users DROP COLUMN preferred_currency;
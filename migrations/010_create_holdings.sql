@@ -0,0 +1,12 @@
+-- +goose Up
+-- Наличные пользователя в разных валютах: одна строка на валюту
+CREATE TABLE holdings (
+    user_id BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    amount NUMERIC(20, 4) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, currency)
+);
+
+-- +goose Down
+DROP TABLE holdings;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- История конвертаций пользователя; старые записи удаляются по HISTORY_RETENTION
+CREATE TABLE conversions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    input_text VARCHAR(256) NOT NULL DEFAULT '',
+    amount NUMERIC(20, 4) NOT NULL,
+    from_currency VARCHAR(3) NOT NULL,
+    to_currency VARCHAR(3) NOT NULL,
+    rate DOUBLE PRECISION NOT NULL,
+    result NUMERIC(24, 4) NOT NULL,
+    provider VARCHAR(32) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_conversions_user ON conversions(user_id, id DESC);
+CREATE INDEX idx_conversions_created_at ON conversions(created_at);
+
+-- +goose Down
+DROP TABLE conversions;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Ключ нажатия кнопки «перевод получен»: повторное нажатие той же кнопки не учитывает перевод дважды
+ALTER TABLE settlements ADD COLUMN settle_key VARCHAR(160);
+
+CREATE UNIQUE INDEX idx_settlements_key ON settlements(settle_key);
+
+-- +goose Down
+DROP INDEX idx_settlements_key;
+ALTER TABLE settlements DROP COLUMN settle_key;
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// utf8BOM помогает Excel узнать кодировку и правильно показать кириллицу
+const utf8BOM = "\uFEFF"
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+// NewCSVWriter создает писатель CSV с BOM в начале файла
+func NewCSVWriter(w io.Writer) (Writer, error) {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) Write(cells ...any) error {
+	c.record = c.record[:0]
+	for _, cell := range cells {
+		text, numeric := formatCell(cell)
+		if !numeric {
+			text = escapeFormula(text)
+		}
+		c.record = append(c.record, text)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula не дает табличным редакторам выполнить пользовательский текст
+// как формулу: такие ячейки начинаются с апострофа
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
@@ -0,0 +1,76 @@
+// Package export записывает таблицы в CSV и XLSX построчно, не держа их целиком в памяти.
+package export
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Format — формат файла выгрузки
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+// ErrUnknownFormat — формат не поддерживается
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// ParseFormat разбирает формат из аргумента команды; пустая строка — CSV
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", CSV:
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// Writer записывает строки таблицы. Ячейки — string, числа, bool или time.Time;
+// Close дописывает окончание файла, но не закрывает нижележащий io.Writer.
+type Writer interface {
+	Write(cells ...any) error
+	Close() error
+}
+
+// NewWriter создает писатель формата format; sheet — имя листа XLSX
+func NewWriter(format Format, w io.Writer, sheet string) (Writer, error) {
+	switch format {
+	case CSV:
+		return NewCSVWriter(w)
+	case XLSX:
+		return NewXLSXWriter(w, sheet)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// formatCell печатает ячейку так же в обоих форматах: числа без экспоненты, время в RFC 3339
+func formatCell(cell any) (string, bool) {
+	switch v := cell.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, false
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case bool:
+		return strconv.FormatBool(v), false
+	case time.Time:
+		if v.IsZero() {
+			return "", false
+		}
+		if v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+			return v.Format(time.DateOnly), false
+		}
+		return v.Format(time.RFC3339), false
+	}
+	return "", false
+}
@@ -0,0 +1,78 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(CSV, &buf, "")
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write("дата", "курс", "заметка"))
+	require.NoError(t, w.Write(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), 92.5, "=HYPERLINK(\"x\")"))
+	require.NoError(t, w.Write(nil, -1.25, "a,b"))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, utf8BOM+"дата,курс,заметка\n2025-03-01,92.5,\"'=HYPERLINK(\"\"x\"\")\"\n,-1.25,\"a,b\"\n", buf.String())
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(XLSX, &buf, "USD/RUB")
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write("pair", "rate"))
+	require.NoError(t, w.Write("USD<RUB>", 92.5))
+	require.NoError(t, w.Close())
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	assert.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, string(files["xl/workbook.xml"]), `name="USD_RUB"`)
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	require.NoError(t, xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &sheet))
+	require.Len(t, sheet.Rows, 2)
+	assert.Equal(t, "USD<RUB>", sheet.Rows[1].Cells[0].Inline)
+	assert.Equal(t, "inlineStr", sheet.Rows[1].Cells[0].Type)
+	assert.Equal(t, "92.5", sheet.Rows[1].Cells[1].Value)
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, CSV, f)
+
+	f, err = ParseFormat("xlsx")
+	require.NoError(t, err)
+	assert.Equal(t, XLSX, f)
+
+	_, err = ParseFormat("pdf")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Минимальная книга из одного листа: строки пишутся inline, без таблицы общих строк,
+// поэтому лист можно стримить прямо в zip
+var xlsxParts = []struct{ name, body string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+const (
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// maxSheetName — ограничение Excel на длину имени листа
+const maxSheetName = 31
+
+type xlsxWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+}
+
+// NewXLSXWriter создает писатель книги XLSX с одним листом sheet
+func NewXLSXWriter(w io.Writer, sheet string) (Writer, error) {
+	z := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		if err := writeZipPart(z, part.name, part.body); err != nil {
+			return nil, err
+		}
+	}
+	workbook := strings.Replace(xlsxWorkbook, "%s", escapeXML(sheetName(sheet)), 1)
+	if err := writeZipPart(z, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	part, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxWriter{zip: z, sheet: bufio.NewWriter(part)}
+	if _, err := x.sheet.WriteString(xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *xlsxWriter) Write(cells ...any) error {
+	x.sheet.WriteString("<row>")
+	for _, cell := range cells {
+		text, numeric := formatCell(cell)
+		switch {
+		case numeric:
+			x.sheet.WriteString("<c><v>" + text + "</v></c>")
+		case text == "":
+			x.sheet.WriteString("<c/>")
+		default:
+			x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">` + escapeXML(text) + "</t></is></c>")
+		}
+	}
+	_, err := x.sheet.WriteString("</row>")
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+func writeZipPart(z *zip.Writer, name, body string) error {
+	part, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, body)
+	return err
+}
+
+// escapeXML экранирует текст; недопустимые в XML символы заменяются на U+FFFD
+func escapeXML(text string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(text))
+	return sb.String()
+}
+
+// sheetName убирает запрещенные в имени листа символы и обрезает его до лимита Excel
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > maxSheetName {
+		name = string(runes[:maxSheetName])
+	}
+	if name == "" {
+		return "Sheet1"
+	}
+	return name
+}
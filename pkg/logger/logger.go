@@ -1,22 +1,53 @@
 package logger
 
 import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func New(level string) (*zap.Logger, error) {
+// Option настраивает создаваемый логгер
+type Option func(*options)
+
+type options struct {
+	secrets []string
+}
+
+// WithSecrets вырезает перечисленные значения (например, токен бота) из всех записей
+func WithSecrets(secrets ...string) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secrets...)
+	}
+}
+
+// Level — уровень глобального логгера; меняется во время работы через LevelHandler
+var Level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+func New(level string, opts ...Option) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
 		return nil, err
 	}
 
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	Level.SetLevel(zapLevel)
+
 	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Level = Level
 	config.EncoderConfig.TimeKey = "timestamp"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	logger, err := config.Build()
+	logger, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return newRedactingCore(core, o.secrets)
+	}))
 	if err != nil {
 		return nil, err
 	}
@@ -24,11 +55,12 @@ func New(level string) (*zap.Logger, error) {
 	return logger, nil
 }
 
-var L *zap.Logger
-var S *zap.SugaredLogger
+// L и S по умолчанию ничего не пишут, чтобы пакеты можно было использовать до InitGlobal (например, в тестах)
+var L = zap.NewNop()
+var S = L.Sugar()
 
-func InitGlobal(level string) error {
-	logger, err := New(level)
+func InitGlobal(level string, opts ...Option) error {
+	logger, err := New(level, opts...)
 	if err != nil {
 		return err
 	}
@@ -36,3 +68,42 @@ func InitGlobal(level string) error {
 	S = logger.Sugar()
 	return nil
 }
+
+// LevelHandler позволяет узнать (GET) и сменить (PUT {"level":"debug"}) уровень логирования.
+// Запрос должен нести заголовок Authorization: Bearer <token>; с пустым token обработчик
+// отклоняет все запросы, чтобы уровень не могли поменять без настройки доступа.
+func LevelHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "log level endpoint is disabled: set LOG_LEVEL_TOKEN", http.StatusForbidden)
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		Level.ServeHTTP(w, r)
+	})
+}
+
+type ctxKey struct{}
+
+// WithContext сохраняет логгер с полями запроса в контексте
+func WithContext(ctx context.Context, l *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext возвращает логгер из контекста или глобальный, если его там нет
+func FromContext(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return L
+}
+
+// With добавляет поля к логгеру из контекста и возвращает новый контекст
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	return WithContext(ctx, FromContext(ctx).With(fields...))
+}
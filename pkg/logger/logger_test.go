@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelHandler_RequiresToken(t *testing.T) {
+	Level.SetLevel(zapcore.InfoLevel)
+	t.Cleanup(func() { Level.SetLevel(zapcore.InfoLevel) })
+
+	put := func(handler http.Handler, auth string) int {
+		req := httptest.NewRequest(http.MethodPut, "/loglevel", strings.NewReader(`{"level":"debug"}`))
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	handler := LevelHandler("s3cret")
+	assert.Equal(t, http.StatusUnauthorized, put(handler, ""))
+	assert.Equal(t, http.StatusUnauthorized, put(handler, "Bearer wrong"))
+	assert.Equal(t, http.StatusForbidden, put(LevelHandler(""), "Bearer "))
+	assert.Equal(t, zapcore.InfoLevel, Level.Level())
+
+	assert.Equal(t, http.StatusOK, put(handler, "Bearer s3cret"))
+	assert.Equal(t, zapcore.DebugLevel, Level.Level())
+}
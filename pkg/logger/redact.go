@@ -0,0 +1,81 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const redacted = "[REDACTED]"
+
+// redactUserText включает скрытие текста сообщений пользователей в логах
+var redactUserText atomic.Bool
+
+// SetRedactUserText включает или выключает скрытие пользовательского текста
+func SetRedactUserText(enabled bool) {
+	redactUserText.Store(enabled)
+}
+
+// UserText возвращает поле с текстом пользователя или только его длину, если текст скрывается
+func UserText(key, text string) zap.Field {
+	if redactUserText.Load() {
+		return zap.String(key, fmt.Sprintf("[redacted %d chars]", utf8.RuneCountInString(text)))
+	}
+	return zap.String(key, text)
+}
+
+// redactingCore заменяет секреты в сообщении и строковых полях перед записью
+type redactingCore struct {
+	zapcore.Core
+	replacer *strings.Replacer
+}
+
+func newRedactingCore(core zapcore.Core, secrets []string) zapcore.Core {
+	var pairs []string
+	for _, secret := range secrets {
+		if secret != "" {
+			pairs = append(pairs, secret, redacted)
+		}
+	}
+	if len(pairs) == 0 {
+		return core
+	}
+
+	return &redactingCore{Core: core, replacer: strings.NewReplacer(pairs...)}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redactFields(fields)), replacer: c.replacer}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.replacer.Replace(entry.Message)
+	return c.Core.Write(entry, c.redactFields(fields))
+}
+
+func (c *redactingCore) redactFields(fields []zapcore.Field) []zapcore.Field {
+	result := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch {
+		case field.Type == zapcore.StringType:
+			field.String = c.replacer.Replace(field.String)
+		case field.Type == zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok {
+				field = zap.String(field.Key, c.replacer.Replace(err.Error()))
+			}
+		}
+		result[i] = field
+	}
+	return result
+}
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactingCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(newRedactingCore(core, []string{"123:SECRET"}))
+
+	log.With(zap.String("url", "https://api.telegram.org/bot123:SECRET/getMe")).
+		Error("request to bot123:SECRET failed", zap.Error(errors.New("Post bot123:SECRET: timeout")))
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+
+	entry := entries[0]
+	assert.Equal(t, "request to bot[REDACTED] failed", entry.Message)
+
+	fields := entry.ContextMap()
+	assert.Equal(t, "https://api.telegram.org/bot[REDACTED]/getMe", fields["url"])
+	assert.Equal(t, "Post bot[REDACTED]: timeout", fields["error"])
+}
+
+func TestUserText(t *testing.T) {
+	defer SetRedactUserText(false)
+
+	SetRedactUserText(false)
+	assert.Equal(t, "100 USD RUB", UserText("text", "100 USD RUB").String)
+
+	SetRedactUserText(true)
+	assert.Equal(t, "[redacted 11 chars]", UserText("text", "100 USD RUB").String)
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PrintfLogger направляет вывод библиотек с интерфейсом Println/Printf в zap
+type PrintfLogger struct {
+	name string
+}
+
+// NewPrintfLogger создает адаптер для библиотеки с указанным именем
+func NewPrintfLogger(name string) *PrintfLogger {
+	return &PrintfLogger{name: name}
+}
+
+func (l *PrintfLogger) Println(v ...interface{}) {
+	L.Named(l.name).Warn(strings.TrimSpace(fmt.Sprintln(v...)))
+}
+
+func (l *PrintfLogger) Printf(format string, v ...interface{}) {
+	L.Named(l.name).Warn(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
@@ -0,0 +1,111 @@
+package money
+
+// multipliers — сокращения порядков после суммы
+var multipliers = map[string]float64{
+	"k":      1e3,
+	"к":      1e3,
+	"тыс":    1e3,
+	"тыс.":   1e3,
+	"тысяча": 1e3,
+	"тысячи": 1e3,
+	"тысяч":  1e3,
+	"млн":    1e6,
+	"млн.":   1e6,
+	"mln":    1e6,
+}
+
+// abbreviations — слова, после которых точка считается частью сокращения
+var abbreviations = map[string]bool{
+	"тыс":  true,
+	"млн":  true,
+	"руб":  true,
+	"р":    true,
+	"долл": true,
+	"грн":  true,
+}
+
+// symbols — знаки валют; ¥ не распознается, так как обозначает и иену, и юань
+var symbols = map[rune]string{
+	'$': "USD",
+	'€': "EUR",
+	'£': "GBP",
+	'₽': "RUB",
+	'₸': "KZT",
+	'₺': "TRY",
+	'₴': "UAH",
+	'₾': "GEL",
+	'֏': "AMD",
+	'₹': "INR",
+	'฿': "THB",
+	'₩': "KRW",
+	'₫': "VND",
+	'₪': "ILS",
+	'₼': "AZN",
+}
+
+// words — названия валют во всех употребимых формах; однозначные сокращения включены с точкой
+var words = map[string]string{
+	"доллар": "USD", "доллара": "USD", "долларов": "USD", "доллары": "USD", "долларах": "USD", "долларам": "USD",
+	"долл.": "USD", "бакс": "USD", "бакса": "USD", "баксов": "USD", "баксы": "USD", "баксах": "USD",
+	"dollar": "USD", "dollars": "USD", "bucks": "USD",
+
+	"евро": "EUR", "euro": "EUR", "euros": "EUR",
+
+	"рубль": "RUB", "рубля": "RUB", "рублей": "RUB", "рубли": "RUB", "рублях": "RUB", "рублям": "RUB",
+	"руб": "RUB", "руб.": "RUB", "р.": "RUB", "ruble": "RUB", "rubles": "RUB", "rouble": "RUB", "roubles": "RUB",
+
+	"тенге": "KZT", "тг": "KZT", "tenge": "KZT",
+
+	"лира": "TRY", "лиры": "TRY", "лир": "TRY", "лирах": "TRY", "lira": "TRY", "liras": "TRY",
+
+	"фунт": "GBP", "фунта": "GBP", "фунтов": "GBP", "фунты": "GBP", "pound": "GBP", "pounds": "GBP",
+
+	"юань": "CNY", "юаня": "CNY", "юаней": "CNY", "юани": "CNY", "yuan": "CNY", "rmb": "CNY",
+
+	"иена": "JPY", "иены": "JPY", "иен": "JPY", "йена": "JPY", "йены": "JPY", "йен": "JPY", "yen": "JPY",
+
+	"гривна": "UAH", "гривны": "UAH", "гривен": "UAH", "гривень": "UAH", "грн": "UAH", "грн.": "UAH",
+
+	"лари": "GEL", "lari": "GEL",
+
+	"драм": "AMD", "драма": "AMD", "драмов": "AMD", "dram": "AMD",
+
+	"сом": "KGS", "сома": "KGS", "сомов": "KGS", "som": "KGS",
+
+	"сум": "UZS", "сума": "UZS", "сумов": "UZS",
+
+	"бат": "THB", "бата": "THB", "батов": "THB", "baht": "THB",
+
+	"дирхам": "AED", "дирхама": "AED", "дирхамов": "AED", "дирхамы": "AED", "dirham": "AED", "dirhams": "AED",
+
+	"рупия": "INR", "рупии": "INR", "рупий": "INR", "rupee": "INR", "rupees": "INR",
+
+	"донг": "VND", "донга": "VND", "донгов": "VND", "dong": "VND",
+
+	"шекель": "ILS", "шекеля": "ILS", "шекелей": "ILS", "шекели": "ILS", "shekel": "ILS", "shekels": "ILS",
+
+	"манат": "AZN", "маната": "AZN", "манатов": "AZN",
+
+	"злотый": "PLN", "злотых": "PLN", "злотого": "PLN", "zloty": "PLN",
+
+	"франк": "CHF", "франка": "CHF", "франков": "CHF", "франки": "CHF",
+}
+
+// isoCodes — коды ISO 4217, которые распознаются при написании заглавными
+var isoCodes = map[string]bool{
+	"USD": true, "EUR": true, "RUB": true, "KZT": true, "TRY": true, "GBP": true, "CNY": true, "JPY": true,
+	"UAH": true, "GEL": true, "AMD": true, "KGS": true, "UZS": true, "THB": true, "AED": true, "INR": true,
+	"VND": true, "ILS": true, "AZN": true, "PLN": true, "CHF": true, "BYN": true, "CAD": true, "AUD": true,
+	"KRW": true, "IDR": true, "MYR": true, "SGD": true, "HKD": true, "CZK": true, "HUF": true, "SEK": true,
+	"NOK": true, "DKK": true, "RSD": true, "EGP": true, "MXN": true, "BRL": true, "ARS": true, "ZAR": true,
+	"TJS": true, "MNT": true, "LKR": true, "NZD": true, "QAR": true, "SAR": true, "BGN": true, "RON": true,
+}
+
+// lowercaseCodes — коды, которые не совпадают с обычными словами и распознаются в любом регистре
+var lowercaseCodes = map[string]bool{
+	"usd": true, "eur": true, "rub": true, "kzt": true, "gbp": true, "cny": true, "jpy": true, "uah": true,
+	"kgs": true, "uzs": true, "thb": true, "aed": true, "inr": true, "vnd": true, "ils": true,
+	"azn": true, "pln": true, "chf": true, "byn": true, "cad": true, "aud": true, "krw": true, "idr": true,
+	"myr": true, "sgd": true, "hkd": true, "czk": true, "huf": true, "sek": true, "nok": true, "dkk": true,
+	"rsd": true, "egp": true, "mxn": true, "brl": true, "zar": true, "tjs": true, "mnt": true, "lkr": true,
+}
@@ -0,0 +1,250 @@
+// Package money находит упоминания сумм с валютой в свободном тексте:
+// «ужин стоил 4500 тенге», «$12.50», «1 200,5 руб.», «3,5 тыс. рублей», «EUR 40».
+package money
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Mention — сумма с валютой, найденная в тексте
+type Mention struct {
+	Amount   float64
+	Currency string // код ISO 4217
+	// Start и End — байтовые границы упоминания в исходном тексте
+	Start, End int
+}
+
+// Find возвращает упоминания в порядке появления. Число без валюты, например дата
+// или время, упоминанием не считается.
+func Find(text string) []Mention {
+	tokens := tokenize(text)
+
+	var mentions []Mention
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].kind != tokenNumber {
+			continue
+		}
+
+		// Сумма перед валютой: 4500 тенге, 3,5 тыс. руб., 20$
+		amount, next := tokens[i].value, i+1
+		if next < len(tokens) {
+			if multiplier, ok := multiplierOf(tokens[i], tokens[next]); ok {
+				amount *= multiplier
+				next++
+			}
+		}
+		if next < len(tokens) {
+			if code, ok := currencyOf(tokens[next]); ok {
+				mentions = append(mentions, Mention{
+					Amount:   amount,
+					Currency: code,
+					Start:    tokens[i].start,
+					End:      tokens[next].end,
+				})
+				i = next
+				continue
+			}
+		}
+
+		// Валюта перед суммой: $20, € 5, USD 100; словесные названия так не пишут
+		if i > 0 {
+			prev := tokens[i-1]
+			if code, ok := currencyOf(prev); ok && (prev.kind == tokenSymbol || isISOCode(prev.raw)) &&
+				!consumed(mentions, prev.start) {
+				end := tokens[i].end
+				if next > i+1 {
+					end = tokens[next-1].end
+				}
+				mentions = append(mentions, Mention{Amount: amount, Currency: code, Start: prev.start, End: end})
+				i = next - 1
+			}
+		}
+	}
+
+	return mentions
+}
+
+// multiplierOf распознает «тыс.», «млн» и «k»; однобуквенный множитель должен стоять
+// вплотную к числу, иначе «к» в «20$ к ужину» превратилась бы в тысячи
+func multiplierOf(number, word token) (float64, bool) {
+	multiplier, ok := multipliers[word.text]
+	if !ok || (utf8.RuneCountInString(word.text) == 1 && word.start != number.end) {
+		return 0, false
+	}
+	return multiplier, true
+}
+
+func consumed(mentions []Mention, offset int) bool {
+	return len(mentions) > 0 && mentions[len(mentions)-1].End > offset
+}
+
+type tokenKind int
+
+const (
+	tokenNumber tokenKind = iota
+	tokenWord
+	tokenSymbol
+	tokenOther
+)
+
+type token struct {
+	kind       tokenKind
+	text       string // слово в нижнем регистре
+	raw        string
+	value      float64
+	start, end int
+}
+
+// tokenize разбивает текст на числа, слова, символы валют и прочие знаки; пробелы пропускаются
+func tokenize(text string) []token {
+	var tokens []token
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+
+		switch {
+		case isDigit(r):
+			value, end, ok := scanNumber(text, i)
+			if ok {
+				tokens = append(tokens, token{kind: tokenNumber, value: value, raw: text[i:end], start: i, end: end})
+			} else {
+				tokens = append(tokens, token{kind: tokenOther, raw: text[i:end], start: i, end: end})
+			}
+			i = end
+		case unicode.IsLetter(r):
+			end := i
+			for end < len(text) {
+				r, size := utf8.DecodeRuneInString(text[end:])
+				if !unicode.IsLetter(r) {
+					break
+				}
+				end += size
+			}
+			word := strings.ToLower(text[i:end])
+			// Точка сокращения входит в слово: «тыс. руб.»
+			if end < len(text) && text[end] == '.' && abbreviations[word] {
+				word += "."
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: word, raw: text[i:end], start: i, end: end})
+			i = end
+		case unicode.IsSpace(r):
+			i += size
+		default:
+			kind := tokenOther
+			if _, ok := symbols[r]; ok {
+				kind = tokenSymbol
+			}
+			tokens = append(tokens, token{kind: kind, raw: text[i : i+size], start: i, end: i + size})
+			i += size
+		}
+	}
+
+	return tokens
+}
+
+// scanNumber читает число с разделителями разрядов и дробной частью: 4500, 4 500, 4,500.50,
+// 4.500,50, 12,5. Пробел считается разделителем разрядов, только если за ним ровно три цифры.
+func scanNumber(text string, start int) (float64, int, bool) {
+	groups := []string{}
+	var seps []rune
+
+	end := scanDigits(text, start)
+	groups = append(groups, text[start:end])
+
+	for end < len(text) {
+		sep, size := utf8.DecodeRuneInString(text[end:])
+		if !isSeparator(sep) {
+			break
+		}
+		digitsEnd := scanDigits(text, end+size)
+		if digitsEnd == end+size {
+			break
+		}
+		group := text[end+size : digitsEnd]
+		if unicode.IsSpace(sep) && (len(group) != 3 || len(groups[0]) > 3) {
+			break
+		}
+		seps = append(seps, sep)
+		groups = append(groups, group)
+		end = digitsEnd
+	}
+
+	// Последняя запятая или точка — дробная часть, если до нее были другие разделители разрядов
+	// или после нее не три цифры; иначе все разделители отделяют разряды
+	decimal := -1
+	if n := len(seps); n > 0 && (seps[n-1] == ',' || seps[n-1] == '.') {
+		last := seps[n-1]
+		repeated, other := false, false
+		for _, sep := range seps[:n-1] {
+			if sep == last {
+				repeated = true
+			} else {
+				other = true
+			}
+		}
+		if !repeated && (other || len(groups[n]) != 3) {
+			decimal = n
+		}
+	}
+
+	var digits strings.Builder
+	for i, group := range groups {
+		if i == decimal {
+			digits.WriteByte('.')
+		} else if i > 0 && len(group) != 3 {
+			return 0, end, false
+		}
+		digits.WriteString(group)
+	}
+
+	value, err := strconv.ParseFloat(digits.String(), 64)
+	if err != nil {
+		return 0, end, false
+	}
+	return value, end, true
+}
+
+func scanDigits(text string, i int) int {
+	for i < len(text) && isDigit(rune(text[i])) {
+		i++
+	}
+	return i
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isSeparator(r rune) bool {
+	switch r {
+	case ',', '.', '\'', ' ', '\u00a0', '\u2009', '\u202f':
+		return true
+	}
+	return false
+}
+
+func currencyOf(t token) (string, bool) {
+	switch t.kind {
+	case tokenSymbol:
+		r, _ := utf8.DecodeRuneInString(t.raw)
+		code, ok := symbols[r]
+		return code, ok
+	case tokenWord:
+		if code, ok := words[t.text]; ok {
+			return code, true
+		}
+		// Коды-слова английского (TRY, ALL) распознаются только заглавными
+		if isISOCode(t.raw) || lowercaseCodes[t.text] {
+			return strings.ToUpper(t.text), true
+		}
+	}
+	return "", false
+}
+
+func isISOCode(raw string) bool {
+	return isoCodes[raw]
+}
@@ -0,0 +1,55 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFind(t *testing.T) {
+	cases := []struct {
+		text string
+		want []Mention
+	}{
+		{"ужин стоил 4500 тенге", []Mention{{Amount: 4500, Currency: "KZT"}}},
+		{"такси 4 500₸, а отель $120.50", []Mention{{Amount: 4500, Currency: "KZT"}, {Amount: 120.5, Currency: "USD"}}},
+		{"билеты по 1 200,5 руб.", []Mention{{Amount: 1200.5, Currency: "RUB"}}},
+		{"бюджет 3,5 тыс. рублей", []Mention{{Amount: 3500, Currency: "RUB"}}},
+		{"EUR 40 за экскурсию", []Mention{{Amount: 40, Currency: "EUR"}}},
+		{"скинь 2.5k usd", []Mention{{Amount: 2500, Currency: "USD"}}},
+		{"кофе 1,500 лир", []Mention{{Amount: 1500, Currency: "TRY"}}},
+		{"аренда 1.234.567,89 €", []Mention{{Amount: 1234567.89, Currency: "EUR"}}},
+		{"$20 к ужину", []Mention{{Amount: 20, Currency: "USD"}}},
+		{"100 USD 200 EUR", []Mention{{Amount: 100, Currency: "USD"}, {Amount: 200, Currency: "EUR"}}},
+	}
+
+	for _, tc := range cases {
+		got := Find(tc.text)
+		if !assert.Len(t, got, len(tc.want), tc.text) {
+			continue
+		}
+		for i, want := range tc.want {
+			assert.InDelta(t, want.Amount, got[i].Amount, 1e-9, tc.text)
+			assert.Equal(t, want.Currency, got[i].Currency, tc.text)
+		}
+	}
+}
+
+func TestFind_IgnoresPlainNumbers(t *testing.T) {
+	for _, text := range []string{
+		"встречаемся в 10:30 у входа",
+		"нас будет 5 человек, 2024 год",
+		"let's try 3 times",
+		"купили 3 пиццы",
+	} {
+		assert.Empty(t, Find(text), text)
+	}
+}
+
+func TestFind_Offsets(t *testing.T) {
+	text := "ужин стоил 4500 тенге"
+	got := Find(text)
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, "4500 тенге", text[got[0].Start:got[0].End])
+}
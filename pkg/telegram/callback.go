@@ -0,0 +1,313 @@
+package telegram
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxCallbackData — ограничение Telegram на длину callback_data в байтах
+const MaxCallbackData = 64
+
+// callbackVersion — версия формата данных кнопок; меняется при несовместимых изменениях,
+// чтобы кнопки старых сообщений отклонялись, а не разбирались неверно
+const callbackVersion = '1'
+
+const (
+	// signatureLen — длина подписи в символах base64url (8 байт HMAC-SHA256)
+	signatureLen = 11
+	// headerLen — версия и подпись перед телом
+	headerLen = 1 + signatureLen
+
+	defaultPayloadTTL      = 7 * 24 * time.Hour
+	defaultPayloadCapacity = 10000
+)
+
+var (
+	ErrCallbackMalformed = errors.New("malformed callback data")
+	ErrCallbackVersion   = errors.New("unsupported callback data version")
+	ErrCallbackSignature = errors.New("invalid callback data signature")
+	ErrCallbackExpired   = errors.New("callback payload expired")
+	ErrCallbackTooLong   = errors.New("callback data exceeds 64 bytes")
+)
+
+var argEscaper = strings.NewReplacer("%", "%25", ":", "%3A", "#", "%23")
+
+// CallbackData — действие инлайн-кнопки и его аргументы
+type CallbackData struct {
+	Action string
+	Args   []string
+}
+
+// NewCallbackData собирает данные кнопки; числа записываются в кратчайшем виде
+func NewCallbackData(action string, args ...any) CallbackData {
+	data := CallbackData{Action: action, Args: make([]string, 0, len(args))}
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			s = fmt.Sprint(v)
+		}
+		data.Args = append(data.Args, s)
+	}
+	return data
+}
+
+// Arg возвращает i-й аргумент или пустую строку
+func (d CallbackData) Arg(i int) string {
+	if i < 0 || i >= len(d.Args) {
+		return ""
+	}
+	return d.Args[i]
+}
+
+// Int64 разбирает i-й аргумент как целое число
+func (d CallbackData) Int64(i int) (int64, error) {
+	return strconv.ParseInt(d.Arg(i), 10, 64)
+}
+
+// Float разбирает i-й аргумент как дробное число
+func (d CallbackData) Float(i int) (float64, error) {
+	return strconv.ParseFloat(d.Arg(i), 64)
+}
+
+// body записывает данные в виде action:arg1:arg2
+func (d CallbackData) body() string {
+	var sb strings.Builder
+	sb.WriteString(d.Action)
+	for _, arg := range d.Args {
+		sb.WriteByte(':')
+		sb.WriteString(argEscaper.Replace(arg))
+	}
+	return sb.String()
+}
+
+func parseBody(body string) (CallbackData, error) {
+	parts := strings.Split(body, ":")
+	data := CallbackData{Action: parts[0], Args: make([]string, 0, len(parts)-1)}
+	for _, part := range parts[1:] {
+		arg, err := url.PathUnescape(part)
+		if err != nil {
+			return CallbackData{}, ErrCallbackMalformed
+		}
+		data.Args = append(data.Args, arg)
+	}
+	return data, nil
+}
+
+// PayloadStore хранит данные кнопок, не помещающиеся в 64 байта
+type PayloadStore interface {
+	Save(ctx context.Context, key, payload string, ttl time.Duration) error
+	Load(ctx context.Context, key string) (string, bool, error)
+}
+
+// CallbackCodec кодирует данные кнопок в компактную строку с версией и HMAC-подписью:
+//
+//	<версия><подпись>action:arg1:arg2
+//
+// Если данные длиннее 64 байт, они сохраняются в PayloadStore, а в кнопку попадает
+// только действие и ключ: <версия><подпись>action#key
+type CallbackCodec struct {
+	secret []byte
+	store  PayloadStore
+	ttl    time.Duration
+}
+
+// CallbackOption настраивает CallbackCodec
+type CallbackOption func(*CallbackCodec)
+
+// WithPayloadStore задает хранилище длинных данных и срок их жизни
+func WithPayloadStore(store PayloadStore, ttl time.Duration) CallbackOption {
+	return func(c *CallbackCodec) {
+		c.store = store
+		if ttl > 0 {
+			c.ttl = ttl
+		}
+	}
+}
+
+// NewCallbackCodec создает кодек с ключом подписи; по умолчанию длинные данные хранятся в памяти процесса
+func NewCallbackCodec(secret []byte, opts ...CallbackOption) *CallbackCodec {
+	c := &CallbackCodec{
+		secret: secret,
+		store:  NewMemoryPayloadStore(defaultPayloadCapacity),
+		ttl:    defaultPayloadTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Encode возвращает строку для callback_data
+func (c *CallbackCodec) Encode(ctx context.Context, data CallbackData) (string, error) {
+	if data.Action == "" || strings.ContainsAny(data.Action, ":#") {
+		return "", ErrCallbackMalformed
+	}
+
+	body := data.body()
+	if headerLen+len(body) <= MaxCallbackData {
+		return c.sign(body), nil
+	}
+
+	if c.store == nil {
+		return "", ErrCallbackTooLong
+	}
+	key, err := newPayloadKey()
+	if err != nil {
+		return "", err
+	}
+	ref := data.Action + "#" + key
+	if headerLen+len(ref) > MaxCallbackData {
+		return "", ErrCallbackTooLong
+	}
+	if err := c.store.Save(ctx, key, body, c.ttl); err != nil {
+		return "", fmt.Errorf("failed to store callback payload: %w", err)
+	}
+
+	return c.sign(ref), nil
+}
+
+// Decode проверяет версию и подпись и восстанавливает данные кнопки
+func (c *CallbackCodec) Decode(ctx context.Context, raw string) (CallbackData, error) {
+	if len(raw) <= headerLen {
+		return CallbackData{}, ErrCallbackMalformed
+	}
+	if raw[0] != callbackVersion {
+		return CallbackData{}, ErrCallbackVersion
+	}
+
+	body := raw[headerLen:]
+	if !hmac.Equal([]byte(raw), []byte(c.sign(body))) {
+		return CallbackData{}, ErrCallbackSignature
+	}
+
+	action, key, stored := strings.Cut(body, "#")
+	if !stored {
+		return parseBody(body)
+	}
+
+	if c.store == nil {
+		return CallbackData{}, ErrCallbackExpired
+	}
+	payload, ok, err := c.store.Load(ctx, key)
+	if err != nil {
+		return CallbackData{}, fmt.Errorf("failed to load callback payload: %w", err)
+	}
+	if !ok {
+		return CallbackData{}, ErrCallbackExpired
+	}
+
+	data, err := parseBody(payload)
+	if err != nil {
+		return CallbackData{}, err
+	}
+	if data.Action != action {
+		return CallbackData{}, ErrCallbackMalformed
+	}
+	return data, nil
+}
+
+func (c *CallbackCodec) sign(body string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte{callbackVersion})
+	mac.Write([]byte(body))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:8])
+
+	return string(callbackVersion) + signature + body
+}
+
+// CallbackAction возвращает действие кнопки без проверки подписи, например для меток метрик
+func CallbackAction(raw string) string {
+	if len(raw) <= headerLen || raw[0] != callbackVersion {
+		return ""
+	}
+	body := raw[headerLen:]
+	if i := strings.IndexAny(body, ":#"); i >= 0 {
+		return body[:i]
+	}
+	return body
+}
+
+func newPayloadKey() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// MemoryPayloadStore хранит данные кнопок в памяти процесса; при нескольких репликах
+// нужно общее хранилище
+type MemoryPayloadStore struct {
+	mu       sync.Mutex
+	capacity int
+	payloads map[string]memoryPayload
+}
+
+type memoryPayload struct {
+	value     string
+	expiresAt time.Time
+}
+
+func NewMemoryPayloadStore(capacity int) *MemoryPayloadStore {
+	return &MemoryPayloadStore{
+		capacity: capacity,
+		payloads: make(map[string]memoryPayload),
+	}
+}
+
+// Save сохраняет данные; при заполнении удаляются истекшие, а затем ближайшие к истечению записи
+func (s *MemoryPayloadStore) Save(_ context.Context, key, payload string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.capacity > 0 && len(s.payloads) >= s.capacity {
+		for k, p := range s.payloads {
+			if now.After(p.expiresAt) {
+				delete(s.payloads, k)
+			}
+		}
+	}
+	if s.capacity > 0 && len(s.payloads) >= s.capacity {
+		oldest := ""
+		for k, p := range s.payloads {
+			if oldest == "" || p.expiresAt.Before(s.payloads[oldest].expiresAt) {
+				oldest = k
+			}
+		}
+		delete(s.payloads, oldest)
+	}
+
+	s.payloads[key] = memoryPayload{value: payload, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryPayloadStore) Load(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.payloads[key]
+	if !ok || time.Now().After(p.expiresAt) {
+		return "", false, nil
+	}
+	return p.value, true, nil
+}
@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallbackCodec_RoundTrip(t *testing.T) {
+	codec := NewCallbackCodec([]byte("secret"))
+	ctx := context.Background()
+
+	raw, err := codec.Encode(ctx, NewCallbackData("conv", 12.5, "USD_T", "a:b#c%"))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(raw), MaxCallbackData)
+	assert.Equal(t, "conv", CallbackAction(raw))
+
+	data, err := codec.Decode(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, "conv", data.Action)
+	assert.Equal(t, []string{"12.5", "USD_T", "a:b#c%"}, data.Args)
+
+	amount, err := data.Float(0)
+	require.NoError(t, err)
+	assert.InDelta(t, 12.5, amount, 1e-9)
+	assert.Equal(t, "", data.Arg(5))
+}
+
+func TestCallbackCodec_RejectsTampering(t *testing.T) {
+	codec := NewCallbackCodec([]byte("secret"))
+	ctx := context.Background()
+
+	raw, err := codec.Encode(ctx, NewCallbackData("bcast", "send", int64(7)))
+	require.NoError(t, err)
+
+	_, err = codec.Decode(ctx, strings.Replace(raw, ":7", ":8", 1))
+	assert.ErrorIs(t, err, ErrCallbackSignature)
+
+	_, err = NewCallbackCodec([]byte("other")).Decode(ctx, raw)
+	assert.ErrorIs(t, err, ErrCallbackSignature)
+
+	_, err = codec.Decode(ctx, "2"+raw[1:])
+	assert.ErrorIs(t, err, ErrCallbackVersion)
+
+	_, err = codec.Decode(ctx, "conv_10_USD_RUB")
+	assert.Error(t, err)
+}
+
+func TestCallbackCodec_StoresLongPayloads(t *testing.T) {
+	store := NewMemoryPayloadStore(10)
+	codec := NewCallbackCodec([]byte("secret"), WithPayloadStore(store, time.Minute))
+	ctx := context.Background()
+
+	long := strings.Repeat("x", 100)
+	raw, err := codec.Encode(ctx, NewCallbackData("note", long))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(raw), MaxCallbackData)
+	assert.Equal(t, "note", CallbackAction(raw))
+
+	data, err := codec.Decode(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, long, data.Arg(0))
+
+	expired := NewCallbackCodec([]byte("secret"))
+	_, err = expired.Decode(ctx, raw)
+	assert.ErrorIs(t, err, ErrCallbackExpired)
+
+	_, err = NewCallbackCodec([]byte("secret"), WithPayloadStore(nil, 0)).Encode(ctx, NewCallbackData("note", long))
+	assert.ErrorIs(t, err, ErrCallbackTooLong)
+}
+
+func TestMemoryPayloadStore_Capacity(t *testing.T) {
+	store := NewMemoryPayloadStore(2)
+	ctx := context.Background()
+
+	require.NoError(t, store.Save(ctx, "a", "1", time.Minute))
+	require.NoError(t, store.Save(ctx, "b", "2", time.Hour))
+	require.NoError(t, store.Save(ctx, "c", "3", time.Hour))
+
+	_, ok, _ := store.Load(ctx, "a")
+	assert.False(t, ok)
+
+	value, ok, err := store.Load(ctx, "c")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "3", value)
+}
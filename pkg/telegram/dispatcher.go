@@ -0,0 +1,91 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultPendingUpdates ограничивает очередь одного чата: остальное — флуд, который отбросит и троттлер
+const defaultPendingUpdates = 100
+
+// Dispatcher обрабатывает обновления разных чатов параллельно, а одного чата — по порядку.
+// Ожидание лимита чата или retry_after при ответе одному чату не задерживает остальные.
+type Dispatcher struct {
+	handle     func(context.Context, tgbotapi.Update)
+	maxPending int
+
+	mu    sync.Mutex
+	chats map[int64][]tgbotapi.Update
+	wg    sync.WaitGroup
+}
+
+func NewDispatcher(handle func(context.Context, tgbotapi.Update)) *Dispatcher {
+	return &Dispatcher{
+		handle:     handle,
+		maxPending: defaultPendingUpdates,
+		chats:      make(map[int64][]tgbotapi.Update),
+	}
+}
+
+// Dispatch ставит обновление в очередь его чата и запускает обработчик чата, если он не запущен.
+// Возвращает false, если очередь чата переполнена и обновление отброшено.
+func (d *Dispatcher) Dispatch(ctx context.Context, update tgbotapi.Update) bool {
+	chatID := updateChatID(update)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	pending, running := d.chats[chatID]
+	if len(pending) >= d.maxPending {
+		return false
+	}
+	d.chats[chatID] = append(pending, update)
+
+	if !running {
+		d.wg.Add(1)
+		go d.work(ctx, chatID)
+	}
+	return true
+}
+
+// Wait дожидается обработки уже принятых обновлений
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// work обрабатывает очередь чата и завершается, когда она опустела
+func (d *Dispatcher) work(ctx context.Context, chatID int64) {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		pending := d.chats[chatID]
+		if len(pending) == 0 {
+			delete(d.chats, chatID)
+			d.mu.Unlock()
+			return
+		}
+		update := pending[0]
+		d.chats[chatID] = pending[1:]
+		d.mu.Unlock()
+
+		d.handle(ctx, update)
+	}
+}
+
+// updateChatID возвращает чат обновления; обновления без чата группируются по отправителю.
+// Update.FromChat не подходит: он падает на кнопках без сообщения.
+func updateChatID(update tgbotapi.Update) int64 {
+	switch {
+	case update.Message != nil:
+		return update.Message.Chat.ID
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return update.CallbackQuery.Message.Chat.ID
+	}
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}
@@ -0,0 +1,62 @@
+package telegram
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher_RateLimitedChatDoesNotDelayOthers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := NewSender(&fakeClient{}, WithChatRate(1, 0.01))
+
+	replied := make(chan int64, 10)
+	dispatcher := NewDispatcher(func(ctx context.Context, update tgbotapi.Update) {
+		chatID := update.Message.Chat.ID
+		if _, err := sender.Send(ctx, tgbotapi.NewMessage(chatID, "ok")); err == nil {
+			replied <- chatID
+		}
+	})
+
+	// Четвертый ответ группе ждет лимита чата около 100 секунд
+	group := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: -100}}
+	for i := range 4 {
+		require.True(t, dispatcher.Dispatch(ctx, tgbotapi.Update{UpdateID: i, Message: group}))
+	}
+	for range 3 {
+		assert.Equal(t, int64(-100), <-replied)
+	}
+
+	private := &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 7}}
+	require.True(t, dispatcher.Dispatch(ctx, tgbotapi.Update{UpdateID: 5, Message: private}))
+
+	select {
+	case chatID := <-replied:
+		assert.Equal(t, int64(7), chatID)
+	case <-time.After(time.Second):
+		t.Fatal("reply to another chat waited for the group's rate limit")
+	}
+
+	cancel()
+	dispatcher.Wait()
+	assert.Empty(t, replied, "rate-limited reply is abandoned on shutdown")
+}
+
+func TestDispatcher_KeepsChatOrder(t *testing.T) {
+	var handled []int
+	dispatcher := NewDispatcher(func(_ context.Context, update tgbotapi.Update) {
+		handled = append(handled, update.UpdateID)
+	})
+
+	callback := &tgbotapi.CallbackQuery{From: &tgbotapi.User{ID: 7}}
+	for i := range 5 {
+		dispatcher.Dispatch(context.Background(), tgbotapi.Update{UpdateID: i, CallbackQuery: callback})
+	}
+	dispatcher.Wait()
+
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, handled)
+}
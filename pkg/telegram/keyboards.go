@@ -18,45 +18,6 @@ func CreateMainKeyboard() tgbotapi.ReplyKeyboardMarkup {
 	)
 }
 
-// CreateInlineKeyboard создает инлайн-клавиатуру для избранного
-func CreateInlineKeyboard(favorites []string) tgbotapi.InlineKeyboardMarkup {
-	var rows [][]tgbotapi.InlineKeyboardButton
-
-	for i := 0; i < len(favorites); i += 2 {
-		var row []tgbotapi.InlineKeyboardButton
-
-		if i < len(favorites) {
-			row = append(row, tgbotapi.NewInlineKeyboardButtonData(favorites[i], "favorite_"+favorites[i]))
-		}
-
-		if i+1 < len(favorites) {
-			row = append(row, tgbotapi.NewInlineKeyboardButtonData(favorites[i+1], "favorite_"+favorites[i+1]))
-		}
-
-		rows = append(rows, row)
-	}
-
-	return tgbotapi.NewInlineKeyboardMarkup(rows...)
-}
-
-// CreateCurrencyKeyboard создает клавиатуру для выбора валют
-func CreateCurrencyKeyboard() tgbotapi.InlineKeyboardMarkup {
-
-	currencies := []string{"USD", "EUR", "RUB", "GBP", "JPY", "CNY", "CAD", "CHF"}
-
-	var rows [][]tgbotapi.InlineKeyboardButton
-	for i := 0; i < len(currencies); i += 4 {
-		var row []tgbotapi.InlineKeyboardButton
-		for j := 0; j < 4 && i+j < len(currencies); j++ {
-			currency := currencies[i+j]
-			row = append(row, tgbotapi.NewInlineKeyboardButtonData(currency, "currency_"+currency))
-		}
-		rows = append(rows, row)
-	}
-
-	return tgbotapi.NewInlineKeyboardMarkup(rows...)
-}
-
 // RemoveKeyboard удаляет кастомную клавиатуру
 func RemoveKeyboard() tgbotapi.ReplyKeyboardRemove {
 	return tgbotapi.NewRemoveKeyboard(true)
@@ -0,0 +1,347 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/time/rate"
+)
+
+// Лимиты Telegram по умолчанию: около 30 сообщений в секунду всего,
+// одно в секунду в личный чат и 20 в минуту в группу
+const (
+	DefaultGlobalRate = 30
+	DefaultChatRate   = 1
+	DefaultGroupRate  = 20.0 / 60
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultBackoff    = 500 * time.Millisecond
+	defaultQueueSize  = 1000
+	defaultWorkers    = 4
+	// chatBurst позволяет ответить на команду несколькими сообщениями без задержки
+	chatBurst = 3
+	// chatLimiterIdle — через сколько простоя лимитер чата удаляется
+	chatLimiterIdle = 10 * time.Minute
+)
+
+// ErrQueueFull возвращается, когда очередь отправки переполнена
+var ErrQueueFull = errors.New("telegram send queue is full")
+
+// Client — часть tgbotapi.BotAPI, через которую отправляются запросы
+type Client interface {
+	Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+}
+
+// Sender отправляет запросы к Bot API в пределах глобального лимита и лимитов
+// отдельных чатов, выжидает retry_after при ответе 429 и повторяет запросы при
+// сетевых ошибках. Повтор после обрыва соединения может продублировать сообщение,
+// если Telegram успел его принять.
+type Sender struct {
+	client     Client
+	global     *rate.Limiter
+	chatRate   rate.Limit
+	groupRate  rate.Limit
+	maxRetries int
+	backoff    time.Duration
+	onRetry    func(method, reason string)
+
+	mu    sync.Mutex
+	chats map[int64]*chatLimiter
+
+	queues []chan job
+}
+
+type chatLimiter struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+type job struct {
+	ctx  context.Context
+	c    tgbotapi.Chattable
+	done func(tgbotapi.Message, error)
+}
+
+// SenderOption настраивает Sender
+type SenderOption func(*Sender)
+
+// WithGlobalRate задает общий лимит сообщений в секунду
+func WithGlobalRate(perSecond float64) SenderOption {
+	return func(s *Sender) {
+		s.global = rate.NewLimiter(rate.Limit(perSecond), max(1, int(perSecond)))
+	}
+}
+
+// WithChatRate задает лимиты сообщений в секунду для личных чатов и групп
+func WithChatRate(private, group float64) SenderOption {
+	return func(s *Sender) {
+		s.chatRate = rate.Limit(private)
+		s.groupRate = rate.Limit(group)
+	}
+}
+
+// WithRetries задает число повторов и начальную паузу между ними
+func WithRetries(maxRetries int, backoff time.Duration) SenderOption {
+	return func(s *Sender) {
+		s.maxRetries = maxRetries
+		s.backoff = backoff
+	}
+}
+
+// WithQueue задает размер очереди и число обработчиков; сообщения одного чата
+// всегда попадают к одному обработчику и доставляются по порядку
+func WithQueue(size, workers int) SenderOption {
+	return func(s *Sender) {
+		s.queues = make([]chan job, workers)
+		for i := range s.queues {
+			s.queues[i] = make(chan job, (size+workers-1)/workers)
+		}
+	}
+}
+
+// WithRetryHook вызывается перед каждым повтором, например для метрик
+func WithRetryHook(hook func(method, reason string)) SenderOption {
+	return func(s *Sender) {
+		s.onRetry = hook
+	}
+}
+
+func NewSender(client Client, opts ...SenderOption) *Sender {
+	s := &Sender{
+		client:     client,
+		global:     rate.NewLimiter(DefaultGlobalRate, DefaultGlobalRate),
+		chatRate:   DefaultChatRate,
+		groupRate:  DefaultGroupRate,
+		maxRetries: defaultMaxRetries,
+		backoff:    defaultBackoff,
+		chats:      make(map[int64]*chatLimiter),
+	}
+	WithQueue(defaultQueueSize, defaultWorkers)(s)
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Send отправляет сообщение, дожидаясь лимитов и повторяя временные ошибки
+func (s *Sender) Send(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if _, ok := c.(tgbotapi.Fileable); ok {
+		return s.sendFile(ctx, c)
+	}
+
+	var message tgbotapi.Message
+	err := s.do(ctx, c, func() error {
+		var err error
+		message, err = s.client.Send(c)
+		return err
+	})
+	return message, err
+}
+
+// sendFile отправляет файл через Request: при загрузке файлов tgbotapi не переносит
+// код ошибки из ответа в tgbotapi.Error, и без него не распознаются 403 и 5xx
+func (s *Sender) sendFile(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var message tgbotapi.Message
+	err := s.do(ctx, c, func() error {
+		resp, err := s.client.Request(c)
+		var tgErr *tgbotapi.Error
+		if errors.As(err, &tgErr) && tgErr.Code == 0 && resp != nil {
+			tgErr.Code = resp.ErrorCode
+		}
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(resp.Result, &message)
+	})
+	return message, err
+}
+
+// Request выполняет запрос без разбора ответа, например answerCallbackQuery
+func (s *Sender) Request(ctx context.Context, c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(ctx, c, func() error {
+		var err error
+		resp, err = s.client.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+// Enqueue ставит сообщение в очередь фоновой отправки; done вызывается с результатом и может быть nil.
+// Очередь обрабатывается после запуска Run.
+func (s *Sender) Enqueue(ctx context.Context, c tgbotapi.Chattable, done func(tgbotapi.Message, error)) error {
+	_, chatID := describe(c)
+	queue := s.queues[uint64(chatID)%uint64(len(s.queues))]
+
+	select {
+	case queue <- job{ctx: context.WithoutCancel(ctx), c: c, done: done}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run обрабатывает очередь до отмены контекста и периодически удаляет простаивающие лимитеры чатов
+func (s *Sender) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, queue := range s.queues {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.work(ctx, queue)
+		}()
+	}
+
+	ticker := time.NewTicker(chatLimiterIdle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			s.cleanup()
+		}
+	}
+}
+
+func (s *Sender) work(ctx context.Context, queue chan job) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-queue:
+			message, err := s.Send(j.ctx, j.c)
+			if j.done != nil {
+				j.done(message, err)
+			}
+		}
+	}
+}
+
+func (s *Sender) do(ctx context.Context, c tgbotapi.Chattable, call func() error) error {
+	method, chatID := describe(c)
+
+	for attempt := 0; ; attempt++ {
+		if err := s.wait(ctx, chatID); err != nil {
+			return err
+		}
+
+		err := call()
+		if err == nil || attempt == s.maxRetries {
+			return err
+		}
+
+		delay, reason, retry := s.retryDelay(err, attempt)
+		if !retry {
+			return err
+		}
+		if s.onRetry != nil {
+			s.onRetry(method, reason)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// wait дожидается глобального лимита и лимита чата; запросы без чата ограничиваются только глобально
+func (s *Sender) wait(ctx context.Context, chatID int64) error {
+	if chatID != 0 {
+		if err := s.chatLimiter(chatID).Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return s.global.Wait(ctx)
+}
+
+func (s *Sender) chatLimiter(chatID int64) *rate.Limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	chat, ok := s.chats[chatID]
+	if !ok {
+		limit := s.chatRate
+		if chatID < 0 {
+			limit = s.groupRate
+		}
+		chat = &chatLimiter{limiter: rate.NewLimiter(limit, chatBurst)}
+		s.chats[chatID] = chat
+	}
+	chat.lastUsed = time.Now()
+
+	return chat.limiter
+}
+
+func (s *Sender) cleanup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for chatID, chat := range s.chats {
+		if time.Since(chat.lastUsed) > chatLimiterIdle {
+			delete(s.chats, chatID)
+		}
+	}
+}
+
+// retryDelay решает, стоит ли повторять запрос: 429 повторяется через retry_after,
+// ошибки сервера и сети — с экспоненциальной паузой, остальные ответы API — нет
+func (s *Sender) retryDelay(err error, attempt int) (time.Duration, string, bool) {
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) {
+		switch {
+		case tgErr.RetryAfter > 0:
+			return time.Duration(tgErr.RetryAfter) * time.Second, "flood", true
+		case tgErr.Code >= http.StatusInternalServerError:
+			return s.backoffDelay(attempt), "server", true
+		default:
+			return 0, "", false
+		}
+	}
+	return s.backoffDelay(attempt), "network", true
+}
+
+func (s *Sender) backoffDelay(attempt int) time.Duration {
+	delay := s.backoff << attempt
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// IsBlocked сообщает, что пользователь заблокировал бота или удалил аккаунт
+func IsBlocked(err error) bool {
+	var tgErr *tgbotapi.Error
+	return errors.As(err, &tgErr) && tgErr.Code == http.StatusForbidden
+}
+
+// describe возвращает метод Bot API и чат запроса для используемых ботом типов
+func describe(c tgbotapi.Chattable) (string, int64) {
+	switch c := c.(type) {
+	case tgbotapi.MessageConfig:
+		return "sendMessage", c.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return "editMessageText", c.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return "editMessageReplyMarkup", c.ChatID
+	case tgbotapi.DocumentConfig:
+		return "sendDocument", c.ChatID
+	case tgbotapi.DeleteMessageConfig:
+		return "deleteMessage", c.ChatID
+	case tgbotapi.CallbackConfig:
+		return "answerCallbackQuery", 0
+	default:
+		return "other", 0
+	}
+}
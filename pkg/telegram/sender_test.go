@@ -0,0 +1,151 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient возвращает ошибки из errs по очереди, затем успех
+type fakeClient struct {
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (f *fakeClient) next() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeClient) Request(tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	if err := f.next(); err != nil {
+		return nil, err
+	}
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (f *fakeClient) Send(tgbotapi.Chattable) (tgbotapi.Message, error) {
+	if err := f.next(); err != nil {
+		return tgbotapi.Message{}, err
+	}
+	return tgbotapi.Message{MessageID: 1}, nil
+}
+
+func TestSender_RetriesFloodAndNetworkErrors(t *testing.T) {
+	client := &fakeClient{errs: []error{
+		&tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 1}},
+		errors.New("connection reset by peer"),
+	}}
+
+	var reasons []string
+	sender := NewSender(client,
+		WithRetries(3, time.Millisecond),
+		WithRetryHook(func(method, reason string) {
+			assert.Equal(t, "sendMessage", method)
+			reasons = append(reasons, reason)
+		}),
+	)
+
+	start := time.Now()
+	message, err := sender.Send(context.Background(), tgbotapi.NewMessage(1, "hi"))
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, message.MessageID)
+	assert.Equal(t, 3, client.calls)
+	assert.Equal(t, []string{"flood", "network"}, reasons)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "retry_after must be honoured")
+}
+
+// uploadClient отвечает на загрузку файлов как tgbotapi.UploadFiles: код ошибки есть только в ответе
+type uploadClient struct {
+	fakeClient
+	codes []int
+}
+
+func (u *uploadClient) Request(tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	u.next()
+	if len(u.codes) == 0 {
+		return &tgbotapi.APIResponse{Ok: true, Result: []byte(`{"message_id":5}`)}, nil
+	}
+	code := u.codes[0]
+	u.codes = u.codes[1:]
+	return &tgbotapi.APIResponse{ErrorCode: code}, &tgbotapi.Error{Message: "upload failed"}
+}
+
+func TestSender_ClassifiesUploadErrors(t *testing.T) {
+	document := tgbotapi.NewDocument(1, tgbotapi.FileBytes{Name: "export.csv", Bytes: []byte("a,b")})
+
+	client := &uploadClient{codes: []int{502}}
+	sender := NewSender(client, WithRetries(3, time.Millisecond))
+	message, err := sender.Send(context.Background(), document)
+	require.NoError(t, err)
+	assert.Equal(t, 5, message.MessageID)
+	assert.Equal(t, 2, client.calls, "5xx upload error is retried")
+
+	client = &uploadClient{codes: []int{403}}
+	sender = NewSender(client, WithRetries(3, time.Millisecond))
+	_, err = sender.Send(context.Background(), document)
+	assert.True(t, IsBlocked(err))
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestSender_DoesNotRetryClientErrors(t *testing.T) {
+	client := &fakeClient{errs: []error{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}}}
+	sender := NewSender(client, WithRetries(3, time.Millisecond))
+
+	_, err := sender.Send(context.Background(), tgbotapi.NewMessage(1, "hi"))
+
+	assert.True(t, IsBlocked(err))
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestSender_ChatRateLimit(t *testing.T) {
+	client := &fakeClient{}
+	sender := NewSender(client, WithChatRate(20, 20))
+
+	start := time.Now()
+	for i := 0; i < chatBurst+2; i++ {
+		_, err := sender.Send(context.Background(), tgbotapi.NewMessage(1, "hi"))
+		require.NoError(t, err)
+	}
+
+	// Сообщения сверх burst ждут токены по 50 мс
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestSender_Enqueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sender := NewSender(&fakeClient{}, WithQueue(1, 1))
+
+	done := make(chan error, 1)
+	require.NoError(t, sender.Enqueue(ctx, tgbotapi.NewMessage(1, "hi"), func(_ tgbotapi.Message, err error) {
+		done <- err
+	}))
+	assert.ErrorIs(t, sender.Enqueue(ctx, tgbotapi.NewMessage(1, "hi"), nil), ErrQueueFull)
+
+	go sender.Run(ctx)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("queued message was not sent")
+	}
+}